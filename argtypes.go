@@ -3,6 +3,7 @@ package mjingo
 import (
 	"fmt"
 	"reflect"
+	"slices"
 
 	"github.com/hnakamur/mjingo/internal/datast/hashset"
 	"github.com/hnakamur/mjingo/option"
@@ -125,6 +126,45 @@ func ValueFromObject(dy Object) Value {
 	return Value{data: dynamicValue{Dy: dy}}
 }
 
+// ValueFromString creates a value from a string.
+func ValueFromString(s string) Value { return valueFromString(s) }
+
+// ValueFromInt creates a value from an integer.
+func ValueFromInt(n int64) Value { return valueFromI64(n) }
+
+// ValueFromFloat creates a value from a floating point number.
+func ValueFromFloat(f float64) Value { return valueFromF64(f) }
+
+// ValueFromBool creates a value from a bool.
+func ValueFromBool(b bool) Value { return valueFromBool(b) }
+
+// ValueFromBytes creates a value from a byte slice.
+//
+// The slice is not copied, so it must not be modified afterwards.
+func ValueFromBytes(b []byte) Value { return valueFromBytes(b) }
+
+// ValueFromSlice creates a sequence value from a slice of values.
+//
+// The slice is not copied, so it must not be modified afterwards.
+func ValueFromSlice(values []Value) Value { return valueFromSlice(values) }
+
+// ValueFromMap creates a map value from a map with string keys.
+//
+// The entries are ordered by their keys so that the map iterates in a
+// deterministic order.
+func ValueFromMap(m map[string]Value) Value {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	vm := valueMapWithCapacity(uint(len(keys)))
+	for _, key := range keys {
+		vm.Set(keyRefFromString(key), m[key])
+	}
+	return valueFromIndexMap(vm)
+}
+
 // Kwargs is the utility to accept keyword arguments.
 //
 // Keyword arguments are represented as regular values as the last argument
@@ -204,7 +244,58 @@ func valueTryToValueSlice(val Value) ([]Value, error) {
 	if err != nil {
 		return nil, err
 	}
-	return iter.Collect(), nil
+	return iter.Collect()
+}
+
+// bindArgs binds the positional arguments and the trailing keyword arguments
+// in args to the parameter names like Python does.  The returned slice has
+// an element for each name which is None if the argument is not given.
+func bindArgs(args []Value, names ...string) ([]option.Option[Value], error) {
+	rv := make([]option.Option[Value], len(names))
+	var kwargs *valueMap
+	if len(args) > 0 {
+		if m, ok := args[len(args)-1].data.(mapValue); ok && m.Type == mapTypeKwargs {
+			kwargs = m.Map
+			args = args[:len(args)-1]
+		}
+	}
+	if len(args) > len(names) {
+		return nil, NewError(TooManyArguments, "")
+	}
+	for i, arg := range args {
+		rv[i] = option.Some(arg)
+	}
+	if kwargs == nil {
+		return rv, nil
+	}
+	for _, keyRf := range kwargs.Keys() {
+		var key string
+		if !keyRf.AsStr().UnwrapTo(&key) {
+			return nil, NewError(InvalidOperation, "non string keys passed to kwargs")
+		}
+		i := slices.Index(names, key)
+		if i == -1 {
+			return nil, NewError(TooManyArguments,
+				fmt.Sprintf("unknown keyword argument '%s'", key))
+		}
+		if rv[i].IsSome() {
+			return nil, NewError(TooManyArguments,
+				fmt.Sprintf("got multiple values for argument '%s'", key))
+		}
+		val, _ := kwargs.Get(keyRf)
+		rv[i] = option.Some(val)
+	}
+	return rv, nil
+}
+
+// argOr converts an argument bound with bindArgs with f.  It returns def if
+// the argument is missing, none or undefined.
+func argOr[T any](arg option.Option[Value], def T, f func(Value) (T, error)) (T, error) {
+	var val Value
+	if !arg.UnwrapTo(&val) || val.isNone() || val.isUndefined() {
+		return def, nil
+	}
+	return f(val)
 }
 
 // ConvertArgToGoValue convert an argument in args to a Go value of type T.
@@ -105,6 +105,12 @@ type doStmt struct {
 	call spanned[call]
 	span span
 }
+type customTagStmt struct {
+	name string
+	args []astExpr
+	body []statement
+	span span
+}
 
 type assignment struct {
 	lhs astExpr
@@ -119,6 +125,8 @@ type importName struct {
 type call struct {
 	expr astExpr
 	args []astExpr
+	// trans is true if the call was generated from a `{% trans %}` block.
+	trans bool
 }
 
 type spanned[T any] struct {
@@ -144,6 +152,7 @@ var _ = statement(includeStmt{})
 var _ = statement(macroStmt{})
 var _ = statement(callBlockStmt{})
 var _ = statement(doStmt{})
+var _ = statement(customTagStmt{})
 
 func (templateStmt) typ() stmtType    { return stmtTypeTemplate }
 func (emitExprStmt) typ() stmtType    { return stmtTypeEmitExpr }
@@ -163,6 +172,7 @@ func (includeStmt) typ() stmtType     { return stmtTypeInclude }
 func (macroStmt) typ() stmtType       { return stmtTypeMacro }
 func (callBlockStmt) typ() stmtType   { return stmtTypeCallBlock }
 func (doStmt) typ() stmtType          { return stmtTypeDo }
+func (customTagStmt) typ() stmtType   { return stmtTypeCustomTag }
 
 type stmtType int
 
@@ -185,6 +195,7 @@ const (
 	stmtTypeMacro
 	stmtTypeCallBlock
 	stmtTypeDo
+	stmtTypeCustomTag
 )
 
 func (k stmtType) String() string {
@@ -225,6 +236,8 @@ func (k stmtType) String() string {
 		return "callBlock"
 	case stmtTypeDo:
 		return "do"
+	case stmtTypeCustomTag:
+		return "customTag"
 	default:
 		panic("invalid stmtType")
 	}
@@ -46,6 +46,17 @@ func callMethod(receiver Value, state *State, name string, args []Value) (Value,
 		if c, ok := v.Dy.(CallMethoder); ok {
 			return c.CallMethod(state, name, args)
 		}
+		if mapObj, ok := valueAsMapObject(receiver); ok {
+			var val Value
+			if mapObj.GetValue(valueFromString(name)).UnwrapTo(&val) {
+				if dyVal, ok := val.data.(dynamicValue); ok {
+					if c, ok := dyVal.Dy.(Caller); ok {
+						return c.Call(state, args)
+					}
+				}
+				return notCallableValueType(val)
+			}
+		}
 	case mapValue:
 		if val, ok := v.Map.Get(keyRefFromString(name)); ok {
 			if dyVal, ok := val.data.(dynamicValue); ok {
@@ -0,0 +1,505 @@
+package mjingo
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Catalog is a [Translator] which looks up messages in a gettext message
+// catalog.
+//
+// A catalog is created from a `.po` file with [ParsePOCatalog] or from a
+// compiled `.mo` file with [ParseMOCatalog].  The plural form is chosen with
+// the `Plural-Forms` header of the catalog.  If the header is missing, the
+// germanic rule `n != 1` is used.
+type Catalog struct {
+	messages map[string][]string
+	plural   pluralFormExpr
+}
+
+var _ = Translator((*Catalog)(nil))
+
+// The context and the message ID are joined with EOT like in `.mo` files.
+const catalogContextSep = "\x04"
+
+func newCatalog() *Catalog {
+	return &Catalog{
+		messages: make(map[string][]string),
+		plural:   germanicPluralForm,
+	}
+}
+
+func catalogKey(msgctxt, msgid string) string {
+	if msgctxt == "" {
+		return msgid
+	}
+	return msgctxt + catalogContextSep + msgid
+}
+
+// Gettext implements [Translator].
+func (c *Catalog) Gettext(msgid string) string {
+	return c.PGettext("", msgid)
+}
+
+// NGettext implements [Translator].
+func (c *Catalog) NGettext(msgid, msgidPlural string, n int64) string {
+	return c.NPGettext("", msgid, msgidPlural, n)
+}
+
+// PGettext implements [Translator].
+func (c *Catalog) PGettext(msgctxt, msgid string) string {
+	if strs, ok := c.messages[catalogKey(msgctxt, msgid)]; ok && strs[0] != "" {
+		return strs[0]
+	}
+	return msgid
+}
+
+// NPGettext implements [Translator].
+func (c *Catalog) NPGettext(msgctxt, msgid, msgidPlural string, n int64) string {
+	if strs, ok := c.messages[catalogKey(msgctxt, msgid)]; ok {
+		if i := c.plural(n); i >= 0 && i < int64(len(strs)) && strs[i] != "" {
+			return strs[i]
+		}
+	}
+	if n == 1 {
+		return msgid
+	}
+	return msgidPlural
+}
+
+func (c *Catalog) add(msgctxt, msgid string, msgstrs []string) error {
+	if msgid == "" && msgctxt == "" {
+		return c.parseHeader(msgstrs[0])
+	}
+	for _, s := range msgstrs {
+		if s != "" {
+			c.messages[catalogKey(msgctxt, msgid)] = msgstrs
+			return nil
+		}
+	}
+	return nil
+}
+
+// parseHeader reads the `Plural-Forms` field from the header entry.
+func (c *Catalog) parseHeader(header string) error {
+	for _, line := range strings.Split(header, "\n") {
+		name, value, found := strings.Cut(line, ":")
+		if !found || !strings.EqualFold(strings.TrimSpace(name), "Plural-Forms") {
+			continue
+		}
+		for _, field := range strings.Split(value, ";") {
+			name, expr, found := strings.Cut(field, "=")
+			if found && strings.TrimSpace(name) == "plural" {
+				plural, err := parsePluralForm(expr)
+				if err != nil {
+					return err
+				}
+				c.plural = plural
+			}
+		}
+	}
+	return nil
+}
+
+// ParsePOCatalog reads a catalog from a gettext `.po` file.
+//
+// Fuzzy and obsolete entries and entries without translations are skipped.
+func ParsePOCatalog(r io.Reader) (*Catalog, error) {
+	c := newCatalog()
+	p := poParser{catalog: c}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		p.lineNo++
+		if err := p.parseLine(strings.TrimSpace(scanner.Text())); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := p.flush(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+type poParser struct {
+	catalog *Catalog
+	lineNo  int
+
+	msgctxt string
+	msgid   string
+	msgstrs []string
+	hasID   bool
+	fuzzy   bool
+	// last points to the string continuation lines are appended to.
+	last *string
+}
+
+func (p *poParser) parseLine(line string) error {
+	switch {
+	case line == "":
+		return p.flush()
+	case strings.HasPrefix(line, "#"):
+		if p.hasID {
+			if err := p.flush(); err != nil {
+				return err
+			}
+		}
+		if strings.HasPrefix(line, "#,") {
+			for _, flag := range strings.Split(line[2:], ",") {
+				if strings.TrimSpace(flag) == "fuzzy" {
+					p.fuzzy = true
+				}
+			}
+		}
+		// Obsolete entries (`#~`) and other comments are ignored.
+		p.last = nil
+		return nil
+	case strings.HasPrefix(line, `"`):
+		if p.last == nil {
+			return p.errorf("unexpected string")
+		}
+		s, err := p.unquote(line)
+		if err != nil {
+			return err
+		}
+		*p.last += s
+		return nil
+	}
+
+	keyword, rest, _ := strings.Cut(line, " ")
+	s, err := p.unquote(strings.TrimSpace(rest))
+	if err != nil {
+		return err
+	}
+	switch {
+	case keyword == "msgctxt":
+		if p.hasID {
+			if err := p.flush(); err != nil {
+				return err
+			}
+		}
+		p.msgctxt = s
+		p.last = &p.msgctxt
+	case keyword == "msgid":
+		if p.hasID {
+			if err := p.flush(); err != nil {
+				return err
+			}
+		}
+		p.msgid, p.hasID = s, true
+		p.last = &p.msgid
+	case keyword == "msgid_plural":
+		// The plural message ID is not needed for lookups.
+		p.last = new(string)
+	case keyword == "msgstr":
+		p.msgstrs = append(p.msgstrs[:0], s)
+		p.last = &p.msgstrs[0]
+	case strings.HasPrefix(keyword, "msgstr[") && strings.HasSuffix(keyword, "]"):
+		i, err := strconv.Atoi(keyword[len("msgstr[") : len(keyword)-1])
+		if err != nil || i != len(p.msgstrs) {
+			return p.errorf(fmt.Sprintf("invalid plural index %q", keyword))
+		}
+		p.msgstrs = append(p.msgstrs, s)
+		p.last = &p.msgstrs[i]
+	default:
+		return p.errorf(fmt.Sprintf("unknown keyword %q", keyword))
+	}
+	return nil
+}
+
+func (p *poParser) unquote(s string) (string, error) {
+	rv, err := strconv.Unquote(s)
+	if err != nil || !strings.HasPrefix(s, `"`) {
+		return "", p.errorf(fmt.Sprintf("invalid string %s", s))
+	}
+	return rv, nil
+}
+
+func (p *poParser) flush() error {
+	var err error
+	if p.hasID && len(p.msgstrs) != 0 && (!p.fuzzy || p.msgid == "") {
+		err = p.catalog.add(p.msgctxt, p.msgid, p.msgstrs)
+	}
+	*p = poParser{catalog: p.catalog, lineNo: p.lineNo}
+	return err
+}
+
+func (p *poParser) errorf(msg string) error {
+	return NewError(InvalidOperation, fmt.Sprintf("invalid po file at line %d: %s", p.lineNo, msg))
+}
+
+// ParseMOCatalog reads a catalog from a compiled gettext `.mo` file.
+//
+// Both little endian and big endian files are supported.
+func ParseMOCatalog(r io.Reader) (*Catalog, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 20 {
+		return nil, invalidMOError("file too short")
+	}
+	var order binary.ByteOrder
+	switch {
+	case binary.LittleEndian.Uint32(data) == 0x950412de:
+		order = binary.LittleEndian
+	case binary.BigEndian.Uint32(data) == 0x950412de:
+		order = binary.BigEndian
+	default:
+		return nil, invalidMOError("bad magic number")
+	}
+	if major := order.Uint32(data[4:]) >> 16; major > 1 {
+		return nil, invalidMOError(fmt.Sprintf("unsupported revision %d", major))
+	}
+	count := order.Uint32(data[8:])
+	origTable := order.Uint32(data[12:])
+	transTable := order.Uint32(data[16:])
+
+	str := func(table, i uint32) (string, error) {
+		pos := uint64(table) + uint64(i)*8
+		if pos+8 > uint64(len(data)) {
+			return "", invalidMOError("string table out of range")
+		}
+		length := uint64(order.Uint32(data[pos:]))
+		offset := uint64(order.Uint32(data[pos+4:]))
+		if offset+length > uint64(len(data)) {
+			return "", invalidMOError("string out of range")
+		}
+		return string(data[offset : offset+length]), nil
+	}
+
+	c := newCatalog()
+	for i := uint32(0); i < count; i++ {
+		orig, err := str(origTable, i)
+		if err != nil {
+			return nil, err
+		}
+		trans, err := str(transTable, i)
+		if err != nil {
+			return nil, err
+		}
+		var msgctxt string
+		if ctx, id, found := strings.Cut(orig, catalogContextSep); found {
+			msgctxt, orig = ctx, id
+		}
+		msgid, _, _ := strings.Cut(orig, "\x00")
+		if err := c.add(msgctxt, msgid, strings.Split(trans, "\x00")); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func invalidMOError(msg string) error {
+	return NewError(InvalidOperation, "invalid mo file: "+msg)
+}
+
+// pluralFormExpr returns the index of the plural form for n.
+type pluralFormExpr func(n int64) int64
+
+func germanicPluralForm(n int64) int64 {
+	if n != 1 {
+		return 1
+	}
+	return 0
+}
+
+// parsePluralForm parses the C expression of the `plural` field of the
+// `Plural-Forms` header.
+func parsePluralForm(expr string) (pluralFormExpr, error) {
+	p := pluralFormParser{s: expr}
+	rv, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpace(); p.pos < len(p.s) {
+		return nil, p.error()
+	}
+	return rv, nil
+}
+
+type pluralFormParser struct {
+	s   string
+	pos int
+}
+
+func (p *pluralFormParser) error() error {
+	return NewError(InvalidOperation, fmt.Sprintf("invalid plural forms expression %q", p.s))
+}
+
+func (p *pluralFormParser) skipSpace() {
+	for p.pos < len(p.s) && strings.IndexByte(" \t\r\n", p.s[p.pos]) != -1 {
+		p.pos++
+	}
+}
+
+// skipOp consumes op if it is next in the input.
+func (p *pluralFormParser) skipOp(op string) bool {
+	p.skipSpace()
+	if !strings.HasPrefix(p.s[p.pos:], op) {
+		return false
+	}
+	// Do not mistake the first character of `==`, `<=` or `||` for an operator.
+	if len(op) == 1 && p.pos+1 < len(p.s) {
+		next := p.s[p.pos+1]
+		if next == '=' && strings.IndexByte("=!<>", op[0]) != -1 ||
+			next == op[0] && strings.IndexByte("|&", op[0]) != -1 {
+			return false
+		}
+	}
+	p.pos += len(op)
+	return true
+}
+
+func (p *pluralFormParser) parseTernary() (pluralFormExpr, error) {
+	cond, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if !p.skipOp("?") {
+		return cond, nil
+	}
+	then, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	if !p.skipOp(":") {
+		return nil, p.error()
+	}
+	otherwise, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	return func(n int64) int64 {
+		if cond(n) != 0 {
+			return then(n)
+		}
+		return otherwise(n)
+	}, nil
+}
+
+// pluralFormOps lists the binary operators from the lowest precedence.
+var pluralFormOps = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<=", ">=", "<", ">"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *pluralFormParser) parseBinary(level int) (pluralFormExpr, error) {
+	if level == len(pluralFormOps) {
+		return p.parseUnary()
+	}
+	lhs, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+outer:
+	for {
+		for _, op := range pluralFormOps[level] {
+			if !p.skipOp(op) {
+				continue
+			}
+			rhs, err := p.parseBinary(level + 1)
+			if err != nil {
+				return nil, err
+			}
+			lhs = makePluralFormBinOp(op, lhs, rhs)
+			continue outer
+		}
+		return lhs, nil
+	}
+}
+
+func makePluralFormBinOp(op string, lhs, rhs pluralFormExpr) pluralFormExpr {
+	boolToInt := func(b bool) int64 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+	return func(n int64) int64 {
+		a, b := lhs(n), rhs(n)
+		switch op {
+		case "||":
+			return boolToInt(a != 0 || b != 0)
+		case "&&":
+			return boolToInt(a != 0 && b != 0)
+		case "==":
+			return boolToInt(a == b)
+		case "!=":
+			return boolToInt(a != b)
+		case "<=":
+			return boolToInt(a <= b)
+		case ">=":
+			return boolToInt(a >= b)
+		case "<":
+			return boolToInt(a < b)
+		case ">":
+			return boolToInt(a > b)
+		case "+":
+			return a + b
+		case "-":
+			return a - b
+		case "*":
+			return a * b
+		case "/", "%":
+			if b == 0 {
+				return 0
+			}
+			if op == "/" {
+				return a / b
+			}
+			return a % b
+		default:
+			panic("unreachable")
+		}
+	}
+}
+
+func (p *pluralFormParser) parseUnary() (pluralFormExpr, error) {
+	if p.skipOp("!") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(n int64) int64 {
+			if operand(n) == 0 {
+				return 1
+			}
+			return 0
+		}, nil
+	}
+	if p.skipOp("(") {
+		rv, err := p.parseTernary()
+		if err != nil {
+			return nil, err
+		}
+		if !p.skipOp(")") {
+			return nil, p.error()
+		}
+		return rv, nil
+	}
+	p.skipSpace()
+	if p.pos < len(p.s) && p.s[p.pos] == 'n' {
+		p.pos++
+		return func(n int64) int64 { return n }, nil
+	}
+	start := p.pos
+	for p.pos < len(p.s) && p.s[p.pos] >= '0' && p.s[p.pos] <= '9' {
+		p.pos++
+	}
+	num, err := strconv.ParseInt(p.s[start:p.pos], 10, 64)
+	if err != nil {
+		return nil, p.error()
+	}
+	return func(int64) int64 { return num }, nil
+}
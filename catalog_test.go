@@ -0,0 +1,159 @@
+package mjingo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// buildMO builds a `.mo` file from pairs of original and translated strings.
+func buildMO(order binary.ByteOrder, pairs [][2]string) []byte {
+	const headerLen = 28
+	n := len(pairs)
+	origTable := headerLen
+	transTable := origTable + n*8
+	offset := transTable + n*8
+
+	var strs bytes.Buffer
+	table := make([]uint32, 0, n*4)
+	for k := 0; k < 2; k++ {
+		for _, pair := range pairs {
+			table = append(table, uint32(len(pair[k])), uint32(offset+strs.Len()))
+			strs.WriteString(pair[k])
+			strs.WriteByte(0)
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, v := range []uint32{0x950412de, 0, uint32(n), uint32(origTable), uint32(transTable), 0, 0} {
+		_ = binary.Write(&buf, order, v)
+	}
+	for _, v := range table {
+		_ = binary.Write(&buf, order, v)
+	}
+	buf.Write(strs.Bytes())
+	return buf.Bytes()
+}
+
+func TestParseMOCatalog(t *testing.T) {
+	pairs := [][2]string{
+		{"", "Plural-Forms: nplurals=3; plural=(n==1 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);\n"},
+		{"file", "plik"},
+		{"one file\x00%(num)s files", "jeden plik\x00%(num)s pliki\x00%(num)s plików"},
+		{"menu\x04Open", "Otwórz"},
+	}
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		c, err := ParseMOCatalog(bytes.NewReader(buildMO(order, pairs)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := c.Gettext("file"), "plik"; got != want {
+			t.Errorf("Gettext: got=%q, want=%q", got, want)
+		}
+		if got, want := c.Gettext("missing"), "missing"; got != want {
+			t.Errorf("Gettext: got=%q, want=%q", got, want)
+		}
+		for n, want := range map[int64]string{1: "jeden plik", 3: "%(num)s pliki", 5: "%(num)s plików", 22: "%(num)s pliki"} {
+			if got := c.NGettext("one file", "%(num)s files", n); got != want {
+				t.Errorf("NGettext(n=%d): got=%q, want=%q", n, got, want)
+			}
+		}
+		if got, want := c.PGettext("menu", "Open"), "Otwórz"; got != want {
+			t.Errorf("PGettext: got=%q, want=%q", got, want)
+		}
+		if got, want := c.Gettext("Open"), "Open"; got != want {
+			t.Errorf("Gettext without context: got=%q, want=%q", got, want)
+		}
+	}
+
+	if _, err := ParseMOCatalog(strings.NewReader("not a mo file at all")); err == nil {
+		t.Error("expected an error for a bad magic number")
+	}
+}
+
+func TestParsePOCatalog(t *testing.T) {
+	const po = `# Translator comment
+msgid ""
+msgstr ""
+"Content-Type: text/plain; charset=UTF-8\n"
+
+#: templates/index.html:3
+msgid ""
+"multi "
+"line"
+msgstr "mehr"
+"zeilig\t!"
+#~ msgid "obsolete"
+#~ msgstr "veraltet"
+msgid "empty"
+msgstr ""
+
+msgid "apple"
+msgid_plural "apples"
+msgstr[0] "Apfel"
+msgstr[1] "Äpfel"
+`
+	c, err := ParsePOCatalog(strings.NewReader(po))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		got, want string
+	}{
+		{got: c.Gettext("multi line"), want: "mehrzeilig\t!"},
+		{got: c.Gettext("obsolete"), want: "obsolete"},
+		{got: c.Gettext("empty"), want: "empty"},
+		{got: c.NGettext("apple", "apples", 1), want: "Apfel"},
+		{got: c.NGettext("apple", "apples", 0), want: "Äpfel"},
+		{got: c.NGettext("pear", "pears", 2), want: "pears"},
+	}
+	for i, tc := range testCases {
+		if tc.got != tc.want {
+			t.Errorf("case %d: got=%q, want=%q", i, tc.got, tc.want)
+		}
+	}
+
+	for _, po := range []string{
+		"msgid \"a\"\nmsgstr[1] \"b\"\n",
+		"msgid \"a\nmsgstr \"b\"\n",
+		"\"orphan\"\n",
+		"msgfoo \"a\"\n",
+	} {
+		if _, err := ParsePOCatalog(strings.NewReader(po)); err == nil {
+			t.Errorf("expected an error for %q", po)
+		}
+	}
+}
+
+func TestParsePluralForm(t *testing.T) {
+	testCases := []struct {
+		expr string
+		want []int64 // results for n = 0, 1, 2, 5, 11, 21
+	}{
+		{expr: "0", want: []int64{0, 0, 0, 0, 0, 0}},
+		{expr: "(n != 1)", want: []int64{1, 0, 1, 1, 1, 1}},
+		{expr: "n>1", want: []int64{0, 0, 1, 1, 1, 1}},
+		{expr: "n%10==1 && n%100!=11 ? 0 : n != 0 ? 1 : 2", want: []int64{2, 0, 1, 1, 1, 0}},
+		{expr: "!(n <= 2) + n/5", want: []int64{0, 0, 0, 2, 3, 5}},
+	}
+	ns := []int64{0, 1, 2, 5, 11, 21}
+	for _, tc := range testCases {
+		f, err := parsePluralForm(tc.expr)
+		if err != nil {
+			t.Errorf("expr=%q: unexpected error: %s", tc.expr, err)
+			continue
+		}
+		for i, n := range ns {
+			if got := f(n); got != tc.want[i] {
+				t.Errorf("expr=%q, n=%d: got=%d, want=%d", tc.expr, n, got, tc.want[i])
+			}
+		}
+	}
+
+	for _, expr := range []string{"", "n ==", "(n", "n ? 1", "x", "n = 1"} {
+		if _, err := parsePluralForm(expr); err == nil {
+			t.Errorf("expr=%q: expected an error", expr)
+		}
+	}
+}
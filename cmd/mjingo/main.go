@@ -0,0 +1,241 @@
+// Command mjingo provides tools for mjingo templates.
+//
+// Usage:
+//
+//	mjingo extract [flags] path...
+//
+// The extract command extracts translatable messages from templates and
+// writes them as a gettext `.pot` file.  Directories are searched
+// recursively for files with the extensions given by -ext.
+//
+// The flags are:
+//
+//	-o file
+//		write the output to file instead of stdout
+//	-k keyword
+//		extract calls of an additional gettext-like function.  The keyword
+//		uses the syntax of xgettext and Babel, for example `_n:1,2` or
+//		`pgettext:1c,2`.  The flag can be repeated.
+//	-no-default-keywords
+//		do not extract the default gettext functions
+//	-c tag
+//		extract comments starting with tag as translator comments.  The flag
+//		can be repeated.  Defaults to `Translators:`.
+//	-ext extensions
+//		comma separated file extensions searched in directories
+//		(default ".html,.htm,.xml,.txt,.j2,.jinja,.jinja2")
+//	-block-start, -block-end, -variable-start, -variable-end,
+//	-comment-start, -comment-end delimiter
+//		override the delimiters of the template syntax
+//	-line-statement-prefix prefix, -line-comment-prefix prefix
+//		enable line statements and line comments
+//	-tag name[:endname]
+//		register a custom statement tag so that templates using it can be
+//		parsed.  endname is the name of the end tag if the tag has a body.
+//		The flag can be repeated.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/hnakamur/mjingo"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "mjingo: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+const usage = "usage: mjingo extract [flags] path..."
+
+func run(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	switch args[0] {
+	case "extract":
+		return runExtract(args[1:], stdout)
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+	}
+}
+
+type stringsFlag []string
+
+func (f *stringsFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *stringsFlag) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
+
+func runExtract(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("extract", flag.ContinueOnError)
+	output := flags.String("o", "", "write the output to `file` instead of stdout")
+	noDefaultKeywords := flags.Bool("no-default-keywords", false, "do not extract the default gettext functions")
+	exts := flags.String("ext", ".html,.htm,.xml,.txt,.j2,.jinja,.jinja2",
+		"comma separated file `extensions` searched in directories")
+	var keywords, tags, customTags stringsFlag
+	flags.Var(&keywords, "k", "additional gettext-like function `keyword` like pgettext:1c,2")
+	flags.Var(&tags, "c", "comment `tag` of translator comments (default Translators:)")
+	flags.Var(&customTags, "tag", "custom statement tag `name[:endname]`")
+	syntax := mjingo.DefaultSyntax
+	for _, f := range []struct {
+		name string
+		p    *string
+	}{
+		{"block-start", &syntax.BlockStart},
+		{"block-end", &syntax.BlockEnd},
+		{"variable-start", &syntax.VariableStart},
+		{"variable-end", &syntax.VariableEnd},
+		{"comment-start", &syntax.CommentStart},
+		{"comment-end", &syntax.CommentEnd},
+	} {
+		flags.StringVar(f.p, f.name, *f.p, "`delimiter` of the template syntax")
+	}
+	flags.StringVar(&syntax.LineStatementPrefix, "line-statement-prefix", "", "line statement `prefix`")
+	flags.StringVar(&syntax.LineCommentPrefix, "line-comment-prefix", "", "line comment `prefix`")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errors.New(usage)
+	}
+
+	opts := mjingo.ExtractOptions{
+		Keywords:          make(map[string]mjingo.ExtractKeyword),
+		NoDefaultKeywords: *noDefaultKeywords,
+		CommentTags:       tags,
+	}
+	for _, spec := range keywords {
+		name, kw, err := parseKeyword(spec)
+		if err != nil {
+			return err
+		}
+		opts.Keywords[name] = kw
+	}
+
+	files, err := findFiles(flags.Args(), strings.Split(*exts, ","))
+	if err != nil {
+		return err
+	}
+	env := mjingo.NewEnvironment()
+	if err := env.SetSyntax(syntax); err != nil {
+		return err
+	}
+	for _, spec := range customTags {
+		name, endName, _ := strings.Cut(spec, ":")
+		if name == "" {
+			return fmt.Errorf("invalid tag %q", spec)
+		}
+		// The tags are only parsed, so they do not need an implementation.
+		env.AddCustomTag(name, endName, func(*mjingo.State, []mjingo.Value, mjingo.CustomTagBody) (mjingo.Value, error) {
+			return mjingo.Undefined, nil
+		})
+	}
+	var messages []mjingo.ExtractedMessage
+	for _, file := range files {
+		source, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		msgs, err := env.ExtractMessages(filepath.ToSlash(file), string(source), opts)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, msgs...)
+	}
+
+	if *output == "" {
+		return mjingo.WritePOT(stdout, messages)
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := mjingo.WritePOT(f, messages); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// parseKeyword parses a keyword like `name`, `name:1,2` or `name:1c,2`.
+func parseKeyword(spec string) (string, mjingo.ExtractKeyword, error) {
+	name, positions, found := strings.Cut(spec, ":")
+	if !found {
+		return name, mjingo.ExtractKeyword{Singular: 1}, nil
+	}
+	var kw mjingo.ExtractKeyword
+	for _, pos := range strings.Split(positions, ",") {
+		isContext := strings.HasSuffix(pos, "c")
+		n, err := strconv.Atoi(strings.TrimSuffix(pos, "c"))
+		if err != nil || n <= 0 {
+			return "", kw, fmt.Errorf("invalid keyword %q", spec)
+		}
+		switch {
+		case isContext && kw.Context == 0:
+			kw.Context = n
+		case !isContext && kw.Singular == 0:
+			kw.Singular = n
+		case !isContext && kw.Plural == 0:
+			kw.Plural = n
+		default:
+			return "", kw, fmt.Errorf("invalid keyword %q", spec)
+		}
+	}
+	if kw.Singular == 0 {
+		return "", kw, fmt.Errorf("invalid keyword %q", spec)
+	}
+	return name, kw, nil
+}
+
+// findFiles returns the files in paths.  Directories are searched
+// recursively for files with one of exts skipping hidden entries.
+func findFiles(paths, exts []string) ([]string, error) {
+	var rv []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			rv = append(rv, path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if p != path && strings.HasPrefix(d.Name(), ".") {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.IsDir() {
+				for _, ext := range exts {
+					if ext != "" && filepath.Ext(p) == ext {
+						rv = append(rv, p)
+						break
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rv, nil
+}
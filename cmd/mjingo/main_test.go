@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunExtract(t *testing.T) {
+	dir := t.TempDir()
+	const source = `# if user
+<h1>[[ _("Hello") ]]</h1>
+# endif
+<% cache "sidebar" %>[[ gettext("Menu") ]]<% endcache %>
+<% trans %>Welcome<% endtrans %>
+`
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte(source), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "skip.go"), []byte("x"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	err := run([]string{"extract",
+		"-block-start", "<%", "-block-end", "%>",
+		"-variable-start", "[[", "-variable-end", "]]",
+		"-line-statement-prefix", "#",
+		"-tag", "cache:endcache",
+		dir,
+	}, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.ToSlash(filepath.Join(dir, "index.html"))
+	want := `# Translations template.
+#
+msgid ""
+msgstr ""
+"MIME-Version: 1.0\n"
+"Content-Type: text/plain; charset=UTF-8\n"
+"Content-Transfer-Encoding: 8bit\n"
+
+#: ` + file + `:2
+msgid "Hello"
+msgstr ""
+
+#: ` + file + `:4
+msgid "Menu"
+msgstr ""
+
+#: ` + file + `:5
+msgid "Welcome"
+msgstr ""
+`
+	if got := b.String(); got != want {
+		t.Errorf("output mismatch,\n got=%s\nwant=%s", got, want)
+	}
+
+	b.Reset()
+	if err := run([]string{"extract", "-no-default-keywords", "-block-start", "<%", "-block-end", "%>",
+		"-variable-start", "[[", "-variable-end", "]]", "-line-statement-prefix", "#",
+		"-tag", "cache:endcache", dir}, &b); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); strings.Contains(got, `msgid "Hello"`) || !strings.Contains(got, `msgid "Welcome"`) {
+		t.Errorf("unexpected output with -no-default-keywords:\n%s", got)
+	}
+
+	if err := run([]string{"extract", "-block-start", "<%", "-block-end", "%>", dir}, &b); err == nil {
+		t.Error("expected a syntax error without -tag")
+	}
+}
@@ -151,6 +151,8 @@ func (g *codeGenerator) CompileStmt(stmt statement) {
 		g.compileCallBlock(st)
 	case doStmt:
 		g.compileDo(st)
+	case customTagStmt:
+		g.compileCustomTag(st)
 	default:
 		panic("unreachable")
 	}
@@ -304,6 +306,26 @@ func (g *codeGenerator) compileDo(doTag doStmt) {
 	g.compileCall(doTag.call, option.None[macroStmt]())
 }
 
+func (g *codeGenerator) compileCustomTag(tag customTagStmt) {
+	g.pushSpan(tag.span)
+	argCount := g.compileCallArgs(tag.args, option.None[macroStmt]())
+	inst := g.add(callCustomTagInstruction{Name: tag.name, ArgCount: argCount, JumpTarget: ^uint(0)})
+	g.popSpan()
+	for _, node := range tag.body {
+		g.CompileStmt(node)
+	}
+	g.add(returnInstruction{})
+	if g.instructions.instructions[inst].Typ() == instTypeCallCustomTag {
+		g.instructions.instructions[inst] = callCustomTagInstruction{
+			Name:       tag.name,
+			ArgCount:   argCount,
+			JumpTarget: g.nextInstruction(),
+		}
+	} else {
+		panic("unreachable")
+	}
+}
+
 func (g *codeGenerator) compileIfStmt(ifCond ifCondStmt) {
 	g.setLineFromSpan(ifCond.span)
 	g.compileExpr(ifCond.expr)
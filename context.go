@@ -112,6 +112,64 @@ func (c *context) load(env *Environment, key string) option.Option[Value] {
 	return env.getGlobal(key)
 }
 
+// visibleVariables returns the variables of the template context and the
+// local variables which are visible in the current scope.  Variables of
+// inner frames shadow the ones of outer frames.  Globals are not included.
+func (c *context) visibleVariables() (ctxVars, localVars map[string]Value) {
+	ctxVars = make(map[string]Value)
+	localVars = make(map[string]Value)
+	add := func(dest map[string]Value, key string, val Value) {
+		if _, ok := localVars[key]; ok {
+			return
+		}
+		if _, ok := ctxVars[key]; ok {
+			return
+		}
+		dest[key] = val
+	}
+	for i := len(c.stack) - 1; i >= 0; i-- {
+		frame := &c.stack[i]
+		for key, val := range frame.locals {
+			add(localVars, key, val)
+		}
+		if frame.currentLoop.IsSome() {
+			if l := frame.currentLoop.AsPtr(); l.withLoopVar {
+				add(localVars, "loop", ValueFromObject(&l.object))
+			}
+		}
+		for _, key := range valueStringKeys(frame.ctx) {
+			add(ctxVars, key, frame.ctx.getAttrFast(key).UnwrapOr(Undefined))
+		}
+	}
+	return ctxVars, localVars
+}
+
+// valueStringKeys returns the string keys of a map value or a map object or
+// the fields of a struct object.
+func valueStringKeys(v Value) []string {
+	var keys []string
+	switch d := v.data.(type) {
+	case mapValue:
+		for _, keyRf := range d.Map.Keys() {
+			if key := ""; keyRf.AsStr().UnwrapTo(&key) {
+				keys = append(keys, key)
+			}
+		}
+	case dynamicValue:
+		switch d.Dy.Kind() {
+		case ObjectKindStruct:
+			keys = staticOrDynamicFields(d.Dy.(StructObject))
+		case ObjectKindMap:
+			for _, key := range d.Dy.(MapObject).Keys() {
+				if s, ok := key.data.(stringValue); ok {
+					keys = append(keys, s.Str)
+				}
+			}
+		}
+	}
+	return keys
+}
+
 func (c *context) pushFrame(f frame) error {
 	if err := c.checkDepth(); err != nil {
 		return err
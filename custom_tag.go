@@ -0,0 +1,18 @@
+package mjingo
+
+// CustomTagFunc is the type of the callback which renders a custom statement tag.
+//
+// args holds the evaluated argument expressions of the tag.  Keyword arguments
+// are passed as a trailing kwargs value which can be converted to [Kwargs]
+// with [ConvertArgToGoValue].  body renders the body of the tag and returns the
+// captured output.  It can be called any number of times or not at all.
+//
+// The returned value is written to the output the same way as the result
+// of a `{{ ... }}` expression.  This means it is auto escaped unless it is a
+// safe string.  The captured body is already escaped and is safe to return
+// as is.  Returning [Undefined] emits nothing.
+type CustomTagFunc = func(state *State, args []Value, body CustomTagBody) (Value, error)
+
+// CustomTagBody renders the body of a custom tag and returns the captured
+// output.
+type CustomTagBody = func() (Value, error)
@@ -0,0 +1,38 @@
+package mjingo_test
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hnakamur/mjingo"
+)
+
+func ExampleEnvironment_AddCustomTag() {
+	shout := func(_ *mjingo.State, _ []mjingo.Value, body mjingo.CustomTagBody) (mjingo.Value, error) {
+		v, err := body()
+		if err != nil {
+			return mjingo.Value{}, err
+		}
+		return mjingo.ValueFromSafeString(strings.ToUpper(v.String())), nil
+	}
+
+	env := mjingo.NewEnvironment()
+	env.AddCustomTag("shout", "endshout", shout)
+	const templateName = "test.txt"
+	err := env.AddTemplate(templateName, `{% shout %}hello {{ name }}{% endshout %}!`)
+	if err != nil {
+		log.Fatal(err)
+	}
+	tpl, err := env.GetTemplate(templateName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	context := mjingo.ValueFromGoValue(map[string]string{"name": "world"})
+	got, err := tpl.Render(context)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(got)
+	// Output: HELLO WORLD!
+}
@@ -0,0 +1,523 @@
+package mjingo
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hnakamur/mjingo/internal/rustfmt"
+	"github.com/hnakamur/mjingo/option"
+)
+
+// dateTimeObject is the object a time.Time is converted to by
+// [ValueFromGoValue].
+type dateTimeObject struct {
+	t time.Time
+}
+
+var _ = (Object)(dateTimeObject{})
+var _ = (StructObject)(dateTimeObject{})
+var _ = (fmt.Stringer)(dateTimeObject{})
+var _ = (rustfmt.Formatter)(dateTimeObject{})
+
+var dateTimeFields = []string{
+	"year", "month", "day", "hour", "minute", "second", "microsecond",
+	"nanosecond", "weekday", "yearday", "timestamp", "timezone", "offset",
+}
+
+func valueFromTime(t time.Time) Value { return ValueFromObject(dateTimeObject{t: t}) }
+
+func (dateTimeObject) Kind() ObjectKind { return ObjectKindStruct }
+
+func (o dateTimeObject) GetField(name string) option.Option[Value] {
+	t := o.t
+	switch name {
+	case "year":
+		return option.Some(valueFromI64(int64(t.Year())))
+	case "month":
+		return option.Some(valueFromI64(int64(t.Month())))
+	case "day":
+		return option.Some(valueFromI64(int64(t.Day())))
+	case "hour":
+		return option.Some(valueFromI64(int64(t.Hour())))
+	case "minute":
+		return option.Some(valueFromI64(int64(t.Minute())))
+	case "second":
+		return option.Some(valueFromI64(int64(t.Second())))
+	case "microsecond":
+		return option.Some(valueFromI64(int64(t.Nanosecond() / 1000)))
+	case "nanosecond":
+		return option.Some(valueFromI64(int64(t.Nanosecond())))
+	case "weekday":
+		// Monday is 0 and Sunday is 6 like Python's datetime.weekday.
+		return option.Some(valueFromI64(int64((t.Weekday() + 6) % 7)))
+	case "yearday":
+		return option.Some(valueFromI64(int64(t.YearDay())))
+	case "timestamp":
+		return option.Some(valueFromI64(t.Unix()))
+	case "timezone":
+		name, _ := t.Zone()
+		return option.Some(valueFromString(name))
+	case "offset":
+		_, offset := t.Zone()
+		return option.Some(valueFromI64(int64(offset)))
+	}
+	return option.None[Value]()
+}
+
+func (dateTimeObject) StaticFields() option.Option[[]string] { return option.Some(dateTimeFields) }
+
+func (dateTimeObject) Fields() []string { return nil }
+
+func (o dateTimeObject) String() string { return o.t.Format(time.RFC3339Nano) }
+
+// SupportsCustomVerb implements rustfmt.Formatter.
+func (dateTimeObject) SupportsCustomVerb(verb rune) bool {
+	return verb == rustfmt.DebugVerb || verb == rustfmt.DisplayVerb
+}
+
+// Format implements rustfmt.Formatter.
+func (o dateTimeObject) Format(f fmt.State, verb rune) {
+	switch verb {
+	case rustfmt.DisplayVerb, rustfmt.DebugVerb:
+		io.WriteString(f, o.String())
+	default:
+		// https://github.com/golang/go/issues/51195#issuecomment-1563538796
+		type hideMethods dateTimeObject
+		type dateTimeObject hideMethods
+		fmt.Fprintf(f, fmt.FormatString(f, verb), dateTimeObject(o))
+	}
+}
+
+// durationObject is the object a time.Duration is converted to by
+// [ValueFromGoValue].
+type durationObject struct {
+	d time.Duration
+}
+
+var _ = (Object)(durationObject{})
+var _ = (StructObject)(durationObject{})
+var _ = (fmt.Stringer)(durationObject{})
+var _ = (rustfmt.Formatter)(durationObject{})
+
+var durationFields = []string{
+	"days", "hours", "minutes", "seconds", "nanoseconds", "total_seconds",
+}
+
+func (durationObject) Kind() ObjectKind { return ObjectKindStruct }
+
+func (o durationObject) GetField(name string) option.Option[Value] {
+	d := o.d
+	switch name {
+	case "days":
+		return option.Some(valueFromI64(int64(d / (24 * time.Hour))))
+	case "hours":
+		return option.Some(valueFromI64(int64(d % (24 * time.Hour) / time.Hour)))
+	case "minutes":
+		return option.Some(valueFromI64(int64(d % time.Hour / time.Minute)))
+	case "seconds":
+		return option.Some(valueFromI64(int64(d % time.Minute / time.Second)))
+	case "nanoseconds":
+		return option.Some(valueFromI64(int64(d % time.Second)))
+	case "total_seconds":
+		return option.Some(valueFromF64(d.Seconds()))
+	}
+	return option.None[Value]()
+}
+
+func (durationObject) StaticFields() option.Option[[]string] { return option.Some(durationFields) }
+
+func (durationObject) Fields() []string { return nil }
+
+func (o durationObject) String() string { return o.d.String() }
+
+// SupportsCustomVerb implements rustfmt.Formatter.
+func (durationObject) SupportsCustomVerb(verb rune) bool {
+	return verb == rustfmt.DebugVerb || verb == rustfmt.DisplayVerb
+}
+
+// Format implements rustfmt.Formatter.
+func (o durationObject) Format(f fmt.State, verb rune) {
+	switch verb {
+	case rustfmt.DisplayVerb, rustfmt.DebugVerb:
+		io.WriteString(f, o.String())
+	default:
+		// https://github.com/golang/go/issues/51195#issuecomment-1563538796
+		type hideMethods durationObject
+		type durationObject hideMethods
+		fmt.Fprintf(f, fmt.FormatString(f, verb), durationObject(o))
+	}
+}
+
+// dateLayouts are the layouts accepted when a string is used as a date.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// valueToTime converts a date object, a unix timestamp or a string in
+// RFC 3339 or a similar format to time.Time.  Timestamps and strings
+// without an offset are in UTC.
+func valueToTime(val Value) (time.Time, error) {
+	switch v := val.data.(type) {
+	case dynamicValue:
+		if o, ok := v.Dy.(dateTimeObject); ok {
+			return o.t, nil
+		}
+	case i64Value, u64Value, i128Value, u128Value:
+		if n, err := val.tryToI64(); err == nil {
+			return time.Unix(n, 0).UTC(), nil
+		}
+	case f64Value:
+		sec := int64(v.F)
+		return time.Unix(sec, int64((v.F-float64(sec))*1e9)).UTC(), nil
+	case stringValue:
+		for _, layout := range dateLayouts {
+			if t, err := time.Parse(layout, v.Str); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, NewError(InvalidOperation, fmt.Sprintf("cannot parse %q as date", v.Str))
+	}
+	return time.Time{}, NewError(InvalidOperation, fmt.Sprintf("value of type %s is not a date", val.Kind()))
+}
+
+// namedTimeFormats maps the format names accepted by the date filters to
+// strftime formats.
+type namedTimeFormats struct {
+	short, medium, long, full, iso string
+}
+
+var (
+	dateFormats = namedTimeFormats{
+		short:  "%Y-%m-%d",
+		medium: "%b %-d %Y",
+		long:   "%B %-d %Y",
+		full:   "%A, %B %-d %Y",
+		iso:    "%Y-%m-%d",
+	}
+	timeFormats = namedTimeFormats{
+		short:  "%H:%M",
+		medium: "%H:%M:%S",
+		long:   "%H:%M:%S.%f",
+		full:   "%H:%M:%S.%f%:z",
+		iso:    "%H:%M:%S%:z",
+	}
+	dateTimeFormats = namedTimeFormats{
+		short:  "%Y-%m-%d %H:%M",
+		medium: "%b %-d %Y %H:%M",
+		long:   "%B %-d %Y %H:%M:%S",
+		full:   "%A, %B %-d %Y %H:%M:%S.%f",
+		iso:    "%Y-%m-%dT%H:%M:%S%:z",
+	}
+)
+
+// formatTime formats the date value for the date filters.  The `format`
+// argument is either a name in formats, `unix`, a strftime format if it
+// contains `%` or a Go layout otherwise.
+func formatTime(val Value, args []Value, formats *namedTimeFormats) (string, error) {
+	bound, err := bindArgs(args, "format", "tz")
+	if err != nil {
+		return "", err
+	}
+	format, err := argOr(bound[0], "medium", valueTryToGoString)
+	if err != nil {
+		return "", err
+	}
+	t, err := valueToTime(val)
+	if err != nil {
+		return "", err
+	}
+	if t, err = timeInZone(t, bound[1]); err != nil {
+		return "", err
+	}
+
+	switch format {
+	case "short":
+		format = formats.short
+	case "medium":
+		format = formats.medium
+	case "long":
+		format = formats.long
+	case "full":
+		format = formats.full
+	case "iso":
+		format = formats.iso
+	case "unix":
+		return strconv.FormatInt(t.Unix(), 10), nil
+	}
+	if strings.Contains(format, "%") {
+		return strftime(t, format)
+	}
+	return t.Format(format), nil
+}
+
+// timeInZone converts t to the timezone given as an optional IANA
+// timezone name argument.
+func timeInZone(t time.Time, tz option.Option[Value]) (time.Time, error) {
+	name, err := argOr(tz, "", valueTryToGoString)
+	if err != nil || name == "" {
+		return t, err
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return t, NewError(InvalidOperation, fmt.Sprintf("unknown timezone %q", name)).withSource(err)
+	}
+	return t.In(loc), nil
+}
+
+// strftime formats t with a strftime format.  A `-` after `%` disables
+// the padding of numbers.
+func strftime(t time.Time, format string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		if c != '%' {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		pad := true
+		if i < len(format) && format[i] == '-' {
+			pad = false
+			i++
+		}
+		if i >= len(format) {
+			return "", NewError(InvalidOperation, "incomplete format directive at end of format")
+		}
+		num := func(n, width int) {
+			if pad {
+				fmt.Fprintf(&b, "%0*d", width, n)
+			} else {
+				b.WriteString(strconv.Itoa(n))
+			}
+		}
+		switch format[i] {
+		case 'a':
+			b.WriteString(t.Weekday().String()[:3])
+		case 'A':
+			b.WriteString(t.Weekday().String())
+		case 'b', 'h':
+			b.WriteString(t.Month().String()[:3])
+		case 'B':
+			b.WriteString(t.Month().String())
+		case 'd':
+			num(t.Day(), 2)
+		case 'e':
+			fmt.Fprintf(&b, "%2d", t.Day())
+		case 'f':
+			fmt.Fprintf(&b, "%06d", t.Nanosecond()/1000)
+		case 'H':
+			num(t.Hour(), 2)
+		case 'I':
+			num((t.Hour()+11)%12+1, 2)
+		case 'j':
+			num(t.YearDay(), 3)
+		case 'm':
+			num(int(t.Month()), 2)
+		case 'M':
+			num(t.Minute(), 2)
+		case 'p':
+			b.WriteString(t.Format("PM"))
+		case 'S':
+			num(t.Second(), 2)
+		case 's':
+			b.WriteString(strconv.FormatInt(t.Unix(), 10))
+		case 'u':
+			b.WriteString(strconv.Itoa((int(t.Weekday())+6)%7 + 1))
+		case 'w':
+			b.WriteString(strconv.Itoa(int(t.Weekday())))
+		case 'y':
+			num(t.Year()%100, 2)
+		case 'Y':
+			num(t.Year(), 4)
+		case 'z':
+			b.WriteString(t.Format("-0700"))
+		case ':':
+			if i+1 >= len(format) || format[i+1] != 'z' {
+				return "", NewError(InvalidOperation, "unsupported format directive %:")
+			}
+			i++
+			b.WriteString(t.Format("-07:00"))
+		case 'Z':
+			b.WriteString(t.Format("MST"))
+		case 'F':
+			b.WriteString(t.Format("2006-01-02"))
+		case 'T':
+			b.WriteString(t.Format("15:04:05"))
+		case 'R':
+			b.WriteString(t.Format("15:04"))
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case '%':
+			b.WriteByte('%')
+		default:
+			return "", NewError(InvalidOperation, fmt.Sprintf("unsupported format directive %%%c", format[i]))
+		}
+	}
+	return b.String(), nil
+}
+
+// Formats a date and time.
+//
+// The value can be a date, a unix timestamp or a string in RFC 3339
+// format.  The `format` argument is one of `short`, `medium` (the
+// default), `long`, `full`, `iso` and `unix`, a strftime format like
+// `%Y-%m-%d` or a Go layout like `2006-01-02`.  The `tz` argument converts
+// the date to the given IANA timezone.
+//
+// ```jinja
+// {{ 1700000000|datetimeformat }}
+//
+//	-> Nov 14 2023 22:13
+//
+// {{ 1700000000|datetimeformat("%d.%m.%Y %H:%M", tz="Asia/Tokyo") }}
+//
+//	-> 15.11.2023 07:13
+//
+// ```
+func datetimeformat(val Value, args ...Value) (string, error) {
+	return formatTime(val, args, &dateTimeFormats)
+}
+
+// Formats the date part of a date.
+//
+// It takes the same arguments as `datetimeformat` with date-only named
+// formats.
+//
+// ```jinja
+// {{ 1700000000|dateformat("full") }}
+//
+//	-> Tuesday, November 14 2023
+//
+// ```
+func dateformat(val Value, args ...Value) (string, error) {
+	return formatTime(val, args, &dateFormats)
+}
+
+// Formats the time part of a date.
+//
+// It takes the same arguments as `datetimeformat` with time-only named
+// formats.
+//
+// ```jinja
+// {{ 1700000000|timeformat("short", tz="Asia/Tokyo") }}
+//
+//	-> 07:13
+//
+// ```
+func timeformat(val Value, args ...Value) (string, error) {
+	return formatTime(val, args, &timeFormats)
+}
+
+var timesinceUnits = []struct {
+	d          time.Duration
+	singular   string
+	pluralForm string
+}{
+	{365 * 24 * time.Hour, "year", "years"},
+	{30 * 24 * time.Hour, "month", "months"},
+	{7 * 24 * time.Hour, "week", "weeks"},
+	{24 * time.Hour, "day", "days"},
+	{time.Hour, "hour", "hours"},
+	{time.Minute, "minute", "minutes"},
+}
+
+// Formats the time elapsed since a date.
+//
+// The result has up to two adjacent units like `2 weeks, 3 days`.  Dates
+// less than a minute ago or in the future give `0 minutes`.  The elapsed
+// time is measured to the `now` argument which defaults to the current
+// time of the environment.  The value can also be a duration.
+//
+// ```jinja
+// {{ "2024-01-01"|timesince(now="2024-01-18T06:00:00Z") }}
+//
+//	-> 2 weeks, 3 days
+//
+// ```
+func timesince(state *State, val Value, args ...Value) (string, error) {
+	bound, err := bindArgs(args, "now")
+	if err != nil {
+		return "", err
+	}
+	d, ok := valueAsDuration(val)
+	if !ok {
+		t, err := valueToTime(val)
+		if err != nil {
+			return "", err
+		}
+		now := state.env.now()
+		var nowVal Value
+		if bound[0].UnwrapTo(&nowVal) && !nowVal.isNone() && !nowVal.isUndefined() {
+			if now, err = valueToTime(nowVal); err != nil {
+				return "", err
+			}
+		}
+		d = now.Sub(t)
+	}
+
+	var parts []string
+	for i, unit := range timesinceUnits {
+		n := d / unit.d
+		if n <= 0 {
+			continue
+		}
+		parts = append(parts, pluralizeUnit(int64(n), unit.singular, unit.pluralForm))
+		if i+1 < len(timesinceUnits) {
+			next := timesinceUnits[i+1]
+			if m := (d - n*unit.d) / next.d; m > 0 {
+				parts = append(parts, pluralizeUnit(int64(m), next.singular, next.pluralForm))
+			}
+		}
+		break
+	}
+	if len(parts) == 0 {
+		return "0 minutes", nil
+	}
+	return strings.Join(parts, ", "), nil
+}
+
+func valueAsDuration(val Value) (time.Duration, bool) {
+	if v, ok := val.data.(dynamicValue); ok {
+		if o, ok := v.Dy.(durationObject); ok {
+			return o.d, true
+		}
+	}
+	return 0, false
+}
+
+func pluralizeUnit(n int64, singular, plural string) string {
+	if n == 1 {
+		return "1 " + singular
+	}
+	return strconv.FormatInt(n, 10) + " " + plural
+}
+
+// Returns the current date and time.
+//
+// The time is taken from the clock of the environment which can be
+// replaced with [Environment.SetClock].  The `tz` argument converts it to
+// the given IANA timezone.
+//
+// ```jinja
+// {{ now().year }}
+// ```
+func nowFunc(state *State, args ...Value) (Value, error) {
+	bound, err := bindArgs(args, "tz")
+	if err != nil {
+		return Value{}, err
+	}
+	t, err := timeInZone(state.env.now(), bound[0])
+	if err != nil {
+		return Value{}, err
+	}
+	return valueFromTime(t), nil
+}
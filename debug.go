@@ -44,6 +44,95 @@ func (p varPrinter) Format(f fmt.State, verb rune) {
 	}
 }
 
+// Returns a pretty printed representation of values or the current state.
+//
+// Without arguments, the current state is printed.  This includes the name
+// of the template, the auto escaping, the variables of the template context,
+// the local variables including the loop state, and the globals, filters
+// and tests of the environment.  With arguments, the arguments are printed
+// instead.
+//
+// ```jinja
+// <pre>{{ debug() }}</pre>
+// <pre>{{ debug(user) }}</pre>
+// ```
+func debugFunc(state *State, args ...Value) string {
+	switch len(args) {
+	case 0:
+		return fmt.Sprintf(rustfmt.DebugPrettyString, stateDebug{state: state})
+	case 1:
+		return fmt.Sprintf(rustfmt.DebugPrettyString, args[0])
+	default:
+		return fmt.Sprintf(rustfmt.DebugPrettyString, valueFromSlice(args))
+	}
+}
+
+// stateDebug formats a State for the debug function.
+type stateDebug struct {
+	state *State
+}
+
+func (stateDebug) SupportsCustomVerb(verb rune) bool { return verb == rustfmt.DebugVerb }
+
+func (d stateDebug) Format(f fmt.State, verb rune) {
+	switch verb {
+	case rustfmt.DebugVerb:
+		st := d.state
+		ctxVars, localVars := st.ctx.visibleVariables()
+		currentBlock := none
+		if name := ""; st.currentBlock.UnwrapTo(&name) {
+			currentBlock = valueFromString(name)
+		}
+		env := rustfmt.NewDebugStruct("Environment").
+			Field("globals", valueFromSortedMap(st.env.globals)).
+			Field("filters", valueFromStringSlice(mapSortedKeys(st.env.filters))).
+			Field("tests", valueFromStringSlice(mapSortedKeys(st.env.tests)))
+		rustfmt.NewDebugStruct("State").
+			Field("name", valueFromString(st.Name())).
+			Field("current_block", currentBlock).
+			Field("auto_escape", autoEscapeDebugString(st.autoEscape)).
+			Field("ctx", valueFromSortedMap(ctxVars)).
+			Field("locals", valueFromSortedMap(localVars)).
+			Field("env", env).
+			Format(f, verb)
+	default:
+		// https://github.com/golang/go/issues/51195#issuecomment-1563538796
+		type hideMethods stateDebug
+		type stateDebug hideMethods
+		fmt.Fprintf(f, fmt.FormatString(f, verb), stateDebug(d))
+	}
+}
+
+func autoEscapeDebugString(autoEscape AutoEscape) string {
+	switch a := autoEscape.(type) {
+	case autoEscapeHTML:
+		return "Html"
+	case autoEscapeJSON:
+		return "Json"
+	case autoEscapeCustom:
+		return fmt.Sprintf("Custom(%q)", a.name)
+	default:
+		return "None"
+	}
+}
+
+// valueFromSortedMap creates a map value with the entries of m sorted by key.
+func valueFromSortedMap(m map[string]Value) Value {
+	rv := valueMapWithCapacity(uint(len(m)))
+	for _, key := range mapSortedKeys(m) {
+		rv.Set(keyRefFromString(key), m[key])
+	}
+	return valueFromIndexMap(rv)
+}
+
+func valueFromStringSlice(items []string) Value {
+	values := make([]Value, 0, len(items))
+	for _, item := range items {
+		values = append(values, valueFromString(item))
+	}
+	return valueFromSlice(values)
+}
+
 func (d debugInfo) render(w io.Writer, name option.Option[string], kind ErrorKind,
 	line option.Option[uint], spn option.Option[span]) error {
 	if len(d.templateSource) > 0 {
@@ -45,6 +45,7 @@ func getDefaultBuiltinFilters() map[string]filterObject {
 	addFilter(rv, "length", BoxedFilterFromFixedArity1ArgWithErrFunc(length), "count")
 	addFilter(rv, "dictsort", BoxedFilterFromFixedArity2ArgWithErrFunc(dictsort))
 	addFilter(rv, "items", BoxedFilterFromFixedArity1ArgWithErrFunc(items))
+	addFilter(rv, "xmlattr", BoxedFilterFromFixedArity2ArgWithErrFunc(xmlattr))
 	addFilter(rv, "reverse", BoxedFilterFromFixedArity1ArgWithErrFunc(reverse))
 	addFilter(rv, "trim", BoxedFilterFromFixedArity2ArgNoErrFunc(trim))
 	addFilter(rv, "join", BoxedFilterFromFixedArity2ArgWithErrFunc(join))
@@ -59,18 +60,59 @@ func getDefaultBuiltinFilters() map[string]filterObject {
 	addFilter(rv, "sort", BoxedFilterFromFixedArity3ArgWithErrFunc(sortFilter))
 	addFilter(rv, "list", BoxedFilterFromFixedArity2ArgWithErrFunc(listFilter))
 	addFilter(rv, "bool", BoxedFilterFromFixedArity1ArgNoErrFunc(boolFilter))
+	addFilter(rv, "int", BoxedFilterFromVariadic2ArgWithErrFunc(intFilter))
+	addFilter(rv, "float", BoxedFilterFromVariadic2ArgWithErrFunc(floatFilter))
+	addFilter(rv, "string", BoxedFilterFromFixedArity1ArgWithErrFunc(stringFilter))
+	addFilter(rv, "sum", BoxedFilterFromVariadic3ArgWithErrFunc(sumFilter))
 	addFilter(rv, "batch", BoxedFilterFromFixedArity4ArgWithErrFunc(batchFilter))
 	addFilter(rv, "slice", BoxedFilterFromFixedArity4ArgWithErrFunc(sliceFilter))
 	addFilter(rv, "indent", BoxedFilterFromFixedArity4ArgNoErrFunc(indentFilter))
+	addFilter(rv, "split", BoxedFilterFromVariadic2ArgWithErrFunc(splitFilter))
+	addFilter(rv, "lines", BoxedFilterFromFixedArity1ArgNoErrFunc(linesFilter))
+	addFilter(rv, "chain", BoxedFilterFromVariadic2ArgWithErrFunc(chainFilter))
+	addFilter(rv, "zip", BoxedFilterFromVariadic2ArgWithErrFunc(zipFilter))
+	addFilter(rv, "flatten", BoxedFilterFromFixedArity2ArgWithErrFunc(flattenFilter))
+	addFilter(rv, "take", BoxedFilterFromFixedArity2ArgWithErrFunc(takeFilter))
+	addFilter(rv, "skip", BoxedFilterFromFixedArity2ArgWithErrFunc(skipFilter))
+	addFilter(rv, "random", BoxedFilterFromFixedArity2ArgWithErrFunc(randomFilter))
+	addFilter(rv, "shuffle", BoxedFilterFromFixedArity2ArgWithErrFunc(shuffleFilter))
 	addFilter(rv, "select", BoxedFilterFromVariadic4ArgWithErrFunc(selectFilter))
 	addFilter(rv, "reject", BoxedFilterFromVariadic4ArgWithErrFunc(rejectFilter))
 	addFilter(rv, "selectattr", BoxedFilterFromVariadic5ArgWithErrFunc(selectAttrFilter))
 	addFilter(rv, "rejectattr", BoxedFilterFromVariadic5ArgWithErrFunc(rejectAttrFilter))
 	addFilter(rv, "map", BoxedFilterFromVariadic3ArgWithErrFunc(mapFilter))
 	addFilter(rv, "unique", BoxedFilterFromFixedArity1ArgNoErrFunc(uniqueFilter))
+	addFilter(rv, "groupby", BoxedFilterFromVariadic3ArgWithErrFunc(groupbyFilter))
 	addFilter(rv, "pprint", BoxedFilterFromFixedArity1ArgNoErrFunc(pprint))
 	addFilter(rv, "urlencode", BoxedFilterFromFixedArity1ArgWithErrFunc(urlencodeFilter))
-	addFilter(rv, "tojson", BoxedFilterFromFixedArity2ArgWithErrFunc(tojson))
+	addFilter(rv, "urldecode", BoxedFilterFromFixedArity1ArgWithErrFunc(urldecode))
+	addFilter(rv, "urlize", BoxedFilterFromVariadic3ArgWithErrFunc(urlizeFilter))
+	addFilter(rv, "filesizeformat", BoxedFilterFromFixedArity2ArgWithErrFunc(filesizeformat))
+	addFilter(rv, "quote_plus", BoxedFilterFromFixedArity2ArgWithErrFunc(quotePlus))
+	addFilter(rv, "b64encode", BoxedFilterFromVariadic2ArgWithErrFunc(b64encode))
+	addFilter(rv, "b64decode", BoxedFilterFromFixedArity1ArgWithErrFunc(b64decode))
+	addFilter(rv, "hexencode", BoxedFilterFromFixedArity1ArgWithErrFunc(hexencode))
+	addFilter(rv, "sha1", BoxedFilterFromFixedArity1ArgWithErrFunc(sha1Filter))
+	addFilter(rv, "sha256", BoxedFilterFromFixedArity1ArgWithErrFunc(sha256Filter))
+	addFilter(rv, "md5", BoxedFilterFromFixedArity1ArgWithErrFunc(md5Filter))
+	addFilter(rv, "tojson", BoxedFilterFromVariadic2ArgWithErrFunc(tojson))
+	addFilter(rv, "toyaml", BoxedFilterFromVariadic2ArgWithErrFunc(toyaml))
+	addFilter(rv, "totoml", BoxedFilterFromVariadic2ArgWithErrFunc(totoml))
+	addFilter(rv, "fromjson", BoxedFilterFromFixedArity1ArgWithErrFunc(fromjson))
+	addFilter(rv, "fromyaml", BoxedFilterFromFixedArity1ArgWithErrFunc(fromyaml))
+	addFilter(rv, "format", BoxedFilterFromVariadic2ArgWithErrFunc(formatFilter))
+	addFilter(rv, "truncate", BoxedFilterFromVariadic2ArgWithErrFunc(truncateFilter))
+	addFilter(rv, "wordwrap", BoxedFilterFromVariadic2ArgWithErrFunc(wordwrapFilter))
+	addFilter(rv, "center", BoxedFilterFromFixedArity2ArgNoErrFunc(centerFilter))
+	addFilter(rv, "wordcount", BoxedFilterFromFixedArity1ArgNoErrFunc(wordcount))
+	addFilter(rv, "striptags", BoxedFilterFromFixedArity1ArgNoErrFunc(striptags))
+	addFilter(rv, "regex_replace", BoxedFilterFromVariadic4ArgWithErrFunc(regexReplaceFilter))
+	addFilter(rv, "regex_search", BoxedFilterFromVariadic4ArgWithErrFunc(regexSearchFilter))
+	addFilter(rv, "regex_findall", BoxedFilterFromVariadic4ArgWithErrFunc(regexFindallFilter))
+	addFilter(rv, "datetimeformat", BoxedFilterFromVariadic2ArgWithErrFunc(datetimeformat))
+	addFilter(rv, "dateformat", BoxedFilterFromVariadic2ArgWithErrFunc(dateformat))
+	addFilter(rv, "timeformat", BoxedFilterFromVariadic2ArgWithErrFunc(timeformat))
+	addFilter(rv, "timesince", BoxedFilterFromVariadic3ArgWithErrFunc(timesince))
 	return rv
 }
 
@@ -87,6 +129,7 @@ func getDefaultBuiltinFiltersReflect() map[string]filterObject {
 	addFilter(rv, "length", BoxedFilterFromFuncReflect(length), "count")
 	addFilter(rv, "dictsort", BoxedFilterFromFuncReflect(dictsort))
 	addFilter(rv, "items", BoxedFilterFromFuncReflect(items))
+	addFilter(rv, "xmlattr", BoxedFilterFromFuncReflect(xmlattr))
 	addFilter(rv, "reverse", BoxedFilterFromFuncReflect(reverse))
 	addFilter(rv, "trim", BoxedFilterFromFuncReflect(trim))
 	addFilter(rv, "join", BoxedFilterFromFuncReflect(join))
@@ -101,18 +144,59 @@ func getDefaultBuiltinFiltersReflect() map[string]filterObject {
 	addFilter(rv, "sort", BoxedFilterFromFuncReflect(sortFilter))
 	addFilter(rv, "list", BoxedFilterFromFuncReflect(listFilter))
 	addFilter(rv, "bool", BoxedFilterFromFuncReflect(boolFilter))
+	addFilter(rv, "int", BoxedFilterFromFuncReflect(intFilter))
+	addFilter(rv, "float", BoxedFilterFromFuncReflect(floatFilter))
+	addFilter(rv, "string", BoxedFilterFromFuncReflect(stringFilter))
+	addFilter(rv, "sum", BoxedFilterFromFuncReflect(sumFilter))
 	addFilter(rv, "batch", BoxedFilterFromFuncReflect(batchFilter))
 	addFilter(rv, "slice", BoxedFilterFromFuncReflect(sliceFilter))
 	addFilter(rv, "indent", BoxedFilterFromFuncReflect(indentFilter))
+	addFilter(rv, "split", BoxedFilterFromFuncReflect(splitFilter))
+	addFilter(rv, "lines", BoxedFilterFromFuncReflect(linesFilter))
+	addFilter(rv, "chain", BoxedFilterFromFuncReflect(chainFilter))
+	addFilter(rv, "zip", BoxedFilterFromFuncReflect(zipFilter))
+	addFilter(rv, "flatten", BoxedFilterFromFuncReflect(flattenFilter))
+	addFilter(rv, "take", BoxedFilterFromFuncReflect(takeFilter))
+	addFilter(rv, "skip", BoxedFilterFromFuncReflect(skipFilter))
+	addFilter(rv, "random", BoxedFilterFromFuncReflect(randomFilter))
+	addFilter(rv, "shuffle", BoxedFilterFromFuncReflect(shuffleFilter))
 	addFilter(rv, "select", BoxedFilterFromFuncReflect(selectFilter))
 	addFilter(rv, "reject", BoxedFilterFromFuncReflect(rejectFilter))
 	addFilter(rv, "selectattr", BoxedFilterFromFuncReflect(selectAttrFilter))
 	addFilter(rv, "rejectattr", BoxedFilterFromFuncReflect(rejectAttrFilter))
 	addFilter(rv, "map", BoxedFilterFromFuncReflect(mapFilter))
 	addFilter(rv, "unique", BoxedFilterFromFuncReflect(uniqueFilter))
+	addFilter(rv, "groupby", BoxedFilterFromFuncReflect(groupbyFilter))
 	addFilter(rv, "pprint", BoxedFilterFromFuncReflect(pprint))
 	addFilter(rv, "urlencode", BoxedFilterFromFuncReflect(urlencodeFilter))
+	addFilter(rv, "urldecode", BoxedFilterFromFuncReflect(urldecode))
+	addFilter(rv, "urlize", BoxedFilterFromFuncReflect(urlizeFilter))
+	addFilter(rv, "filesizeformat", BoxedFilterFromFuncReflect(filesizeformat))
+	addFilter(rv, "quote_plus", BoxedFilterFromFuncReflect(quotePlus))
+	addFilter(rv, "b64encode", BoxedFilterFromFuncReflect(b64encode))
+	addFilter(rv, "b64decode", BoxedFilterFromFuncReflect(b64decode))
+	addFilter(rv, "hexencode", BoxedFilterFromFuncReflect(hexencode))
+	addFilter(rv, "sha1", BoxedFilterFromFuncReflect(sha1Filter))
+	addFilter(rv, "sha256", BoxedFilterFromFuncReflect(sha256Filter))
+	addFilter(rv, "md5", BoxedFilterFromFuncReflect(md5Filter))
 	addFilter(rv, "tojson", BoxedFilterFromFuncReflect(tojson))
+	addFilter(rv, "toyaml", BoxedFilterFromFuncReflect(toyaml))
+	addFilter(rv, "totoml", BoxedFilterFromFuncReflect(totoml))
+	addFilter(rv, "fromjson", BoxedFilterFromFuncReflect(fromjson))
+	addFilter(rv, "fromyaml", BoxedFilterFromFuncReflect(fromyaml))
+	addFilter(rv, "format", BoxedFilterFromFuncReflect(formatFilter))
+	addFilter(rv, "truncate", BoxedFilterFromFuncReflect(truncateFilter))
+	addFilter(rv, "wordwrap", BoxedFilterFromFuncReflect(wordwrapFilter))
+	addFilter(rv, "center", BoxedFilterFromFuncReflect(centerFilter))
+	addFilter(rv, "wordcount", BoxedFilterFromFuncReflect(wordcount))
+	addFilter(rv, "striptags", BoxedFilterFromFuncReflect(striptags))
+	addFilter(rv, "regex_replace", BoxedFilterFromFuncReflect(regexReplaceFilter))
+	addFilter(rv, "regex_search", BoxedFilterFromFuncReflect(regexSearchFilter))
+	addFilter(rv, "regex_findall", BoxedFilterFromFuncReflect(regexFindallFilter))
+	addFilter(rv, "datetimeformat", BoxedFilterFromFuncReflect(datetimeformat))
+	addFilter(rv, "dateformat", BoxedFilterFromFuncReflect(dateformat))
+	addFilter(rv, "timeformat", BoxedFilterFromFuncReflect(timeformat))
+	addFilter(rv, "timesince", BoxedFilterFromFuncReflect(timesince))
 	return rv
 }
 
@@ -134,8 +218,19 @@ func getDefaultBuiltinTests() map[string]testObject {
 	addTest(rv, "string", BoxedTestFromFixedArity1ArgNoErrFunc(isString))
 	addTest(rv, "sequence", BoxedTestFromFixedArity1ArgNoErrFunc(isSequence))
 	addTest(rv, "mapping", BoxedTestFromFixedArity1ArgNoErrFunc(isMapping))
+	addTest(rv, "divisibleby", BoxedTestFromFixedArity2ArgNoErrFunc(isDivisibleBy))
+	addTest(rv, "integer", BoxedTestFromFixedArity1ArgNoErrFunc(isInteger))
+	addTest(rv, "float", BoxedTestFromFixedArity1ArgNoErrFunc(isFloat))
+	addTest(rv, "boolean", BoxedTestFromFixedArity1ArgNoErrFunc(isBoolean))
+	addTest(rv, "callable", BoxedTestFromFixedArity1ArgNoErrFunc(isCallable))
+	addTest(rv, "iterable", BoxedTestFromFixedArity1ArgNoErrFunc(isIterable))
+	addTest(rv, "lower", BoxedTestFromFixedArity1ArgNoErrFunc(isLower))
+	addTest(rv, "upper", BoxedTestFromFixedArity1ArgNoErrFunc(isUpper))
+	addTest(rv, "sameas", BoxedTestFromFixedArity2ArgNoErrFunc(isSameAs))
 	addTest(rv, "startingwith", BoxedTestFromFixedArity2ArgNoErrFunc(isStartingWith))
 	addTest(rv, "endingwith", BoxedTestFromFixedArity2ArgNoErrFunc(isEndingWith))
+	addTest(rv, "matching", BoxedTestFromVariadic4ArgWithErrFunc(isMatching))
+	addTest(rv, "regex", BoxedTestFromVariadic4ArgWithErrFunc(isMatching))
 
 	// operators
 	addTest(rv, "eq", BoxedTestFromFixedArity2ArgNoErrFunc(isEq))
@@ -176,8 +271,19 @@ func getDefaultBuiltinTestsReflect() map[string]testObject {
 	addTest(rv, "string", BoxedTestFromFuncReflect(isString))
 	addTest(rv, "sequence", BoxedTestFromFuncReflect(isSequence))
 	addTest(rv, "mapping", BoxedTestFromFuncReflect(isMapping))
+	addTest(rv, "divisibleby", BoxedTestFromFuncReflect(isDivisibleBy))
+	addTest(rv, "integer", BoxedTestFromFuncReflect(isInteger))
+	addTest(rv, "float", BoxedTestFromFuncReflect(isFloat))
+	addTest(rv, "boolean", BoxedTestFromFuncReflect(isBoolean))
+	addTest(rv, "callable", BoxedTestFromFuncReflect(isCallable))
+	addTest(rv, "iterable", BoxedTestFromFuncReflect(isIterable))
+	addTest(rv, "lower", BoxedTestFromFuncReflect(isLower))
+	addTest(rv, "upper", BoxedTestFromFuncReflect(isUpper))
+	addTest(rv, "sameas", BoxedTestFromFuncReflect(isSameAs))
 	addTest(rv, "startingwith", BoxedTestFromFuncReflect(isStartingWith))
 	addTest(rv, "endingwith", BoxedTestFromFuncReflect(isEndingWith))
+	addTest(rv, "matching", BoxedTestFromFuncReflect(isMatching))
+	addTest(rv, "regex", BoxedTestFromFuncReflect(isMatching))
 
 	// operators
 	addTest(rv, "eq", BoxedTestFromFuncReflect(isEq))
@@ -212,6 +318,12 @@ func getDefaultGlobals() map[string]Value {
 	rv := make(map[string]Value)
 	addFunction(rv, "range", BoxedFuncFromFixedArity3ArgWithErrFunc(rangeFunc))
 	addFunction(rv, "dict", BoxedFuncFromFixedArity1ArgWithErrFunc(dictFunc))
+	addFunction(rv, "now", BoxedFuncFromVariadic2ArgWithErrFunc(nowFunc))
+	addFunction(rv, "cycler", BoxedFuncFromVariadic1ArgWithErrFunc(cyclerFunc))
+	addFunction(rv, "joiner", BoxedFuncFromFixedArity1ArgNoErrFunc(joinerFunc))
+	addFunction(rv, "lipsum", BoxedFuncFromVariadic1ArgWithErrFunc(lipsumFunc))
+	addFunction(rv, "debug", BoxedFuncFromVariadic2ArgNoErrFunc(debugFunc))
+	addGettextFunctions(rv, envTranslator)
 	return rv
 }
 
@@ -219,5 +331,11 @@ func getDefaultGlobalsReflect() map[string]Value {
 	rv := make(map[string]Value)
 	addFunction(rv, "range", BoxedFuncFromFuncReflect(rangeFunc))
 	addFunction(rv, "dict", BoxedFuncFromFuncReflect(dictFunc))
+	addFunction(rv, "now", BoxedFuncFromFuncReflect(nowFunc))
+	addFunction(rv, "cycler", BoxedFuncFromFuncReflect(cyclerFunc))
+	addFunction(rv, "joiner", BoxedFuncFromFuncReflect(joinerFunc))
+	addFunction(rv, "lipsum", BoxedFuncFromFuncReflect(lipsumFunc))
+	addFunction(rv, "debug", BoxedFuncFromFuncReflect(debugFunc))
+	addGettextFunctions(rv, envTranslator)
 	return rv
 }
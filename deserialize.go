@@ -1,9 +1,14 @@
 package mjingo
 
 import (
+	"bytes"
+	"encoding"
+	"errors"
 	"fmt"
 	"math"
 	"reflect"
+	"strconv"
+	"time"
 
 	"github.com/hnakamur/mjingo/option"
 )
@@ -303,14 +308,12 @@ func valueTryToJSONObject(val Value) (any, error) {
 		if v.N.IsInt64() {
 			return v.N.Int64(), nil
 		}
-		f, _ := v.N.n.Float64()
-		return f, nil
+		return v.N.Float64(), nil
 	case i128Value:
 		if v.N.IsInt64() {
 			return v.N.Int64(), nil
 		}
-		f, _ := v.N.n.Float64()
-		return f, nil
+		return v.N.Float64(), nil
 	case bytesValue:
 		return string(v.B), nil
 	case seqValue:
@@ -364,6 +367,14 @@ func valueTryToJSONObject(val Value) (any, error) {
 				rv[field] = vv
 			}
 			return rv, nil
+		case ObjectKindMap:
+			return valueTryToJSONObject(valueFromIndexMap(valueMapFromMapObject(v.Dy.(MapObject))))
+		case ObjectKindIterable:
+			items, err := collectIterableObject(v.Dy.(IterableObject))
+			if err != nil {
+				return nil, err
+			}
+			return valueTryToJSONObject(valueFromSlice(items))
 		default:
 			return nil, NewError(InvalidOperation, "cannot serialize to JSON")
 		}
@@ -371,3 +382,365 @@ func valueTryToJSONObject(val Value) (any, error) {
 		panic("unreachable")
 	}
 }
+
+var (
+	textUnmarshalerType = reflectType[encoding.TextUnmarshaler]()
+	timeType            = reflectType[time.Time]()
+	durationType        = reflectType[time.Duration]()
+)
+
+// DecodeValue decodes a value into the Go value pointed to by dst.
+//
+// This is the reverse of [ValueFromGoValue] and is useful to convert the
+// result of [Expression.Eval] or the variables of a [State] into Go types.
+// dst must be a non-nil pointer.
+//
+// Maps and struct objects can be decoded into structs and maps, and
+// sequences into slices and arrays.  Struct fields are matched by the same
+// names that [ValueFromGoValue] uses, so the [WithStructTag] option is
+// honored.  Fields without a matching key and none or undefined values are
+// left unchanged.  Values can also be decoded into [Value], [I128], [U128],
+// time.Time, time.Duration and types implementing
+// encoding.TextUnmarshaler.  Decoding into an empty interface produces
+// bool, int64, uint64, float64, string, []byte, []any and map[string]any
+// values.
+//
+// Errors have the [CannotDeserialize] kind and the detail starts with
+// the path of the value which failed to decode like `users[3].email`.
+func DecodeValue(v Value, dst any, opts ...ValueFromGoValueOption) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return NewError(CannotDeserialize, fmt.Sprintf("destination must be a non-nil pointer, got %T", dst))
+	}
+	var config valueFromGoValueConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+	d := valueDecoder{config: &config}
+	return d.decode(v, rv.Elem(), "")
+}
+
+type valueDecoder struct {
+	config *valueFromGoValueConfig
+}
+
+func (d *valueDecoder) decode(val Value, dest reflect.Value, path string) error {
+	ty := dest.Type()
+	if ty == reflectType[Value]() {
+		dest.Set(reflect.ValueOf(val))
+		return nil
+	}
+	if val.isUndefined() || val.isNone() {
+		return nil
+	}
+
+	switch ty {
+	case timeType:
+		t, err := valueToTime(val)
+		if err != nil {
+			return decodeError(path, err)
+		}
+		dest.Set(reflect.ValueOf(t))
+		return nil
+	case durationType:
+		if dur, ok := valueAsDuration(val); ok {
+			dest.SetInt(int64(dur))
+			return nil
+		}
+		if s, ok := val.data.(stringValue); ok {
+			dur, err := time.ParseDuration(s.Str)
+			if err != nil {
+				return decodeError(path, err)
+			}
+			dest.SetInt(int64(dur))
+			return nil
+		}
+		return decodeTypeError(path, val, ty)
+	case reflectType[I128]():
+		n, err := valueTryToGoI128(val)
+		if err != nil {
+			return decodeError(path, err)
+		}
+		dest.Set(reflect.ValueOf(n))
+		return nil
+	case reflectType[U128]():
+		n, err := valueTryToGoU128(val)
+		if err != nil {
+			return decodeError(path, err)
+		}
+		dest.Set(reflect.ValueOf(n))
+		return nil
+	}
+	if dest.CanAddr() && reflect.PointerTo(ty).Implements(textUnmarshalerType) {
+		if s, ok := val.data.(stringValue); ok {
+			u := dest.Addr().Interface().(encoding.TextUnmarshaler)
+			if err := u.UnmarshalText([]byte(s.Str)); err != nil {
+				return decodeError(path, err)
+			}
+			return nil
+		}
+	}
+
+	switch ty.Kind() {
+	case reflect.Pointer:
+		if dest.IsNil() {
+			dest.Set(reflect.New(ty.Elem()))
+		}
+		return d.decode(val, dest.Elem(), path)
+	case reflect.Interface:
+		if ty.NumMethod() == 0 {
+			rv, err := d.decodeAny(val, path)
+			if err != nil {
+				return err
+			}
+			if rv == nil {
+				dest.Set(reflect.Zero(ty))
+			} else {
+				dest.Set(reflect.ValueOf(rv))
+			}
+			return nil
+		}
+		if reflect.TypeOf(val).Implements(ty) {
+			dest.Set(reflect.ValueOf(val))
+			return nil
+		}
+	case reflect.Bool:
+		if b, ok := val.data.(boolValue); ok {
+			dest.SetBool(b.B)
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if val.Kind() == ValueKindNumber {
+			if n, err := val.tryToI64(); err == nil && !dest.OverflowInt(n) {
+				dest.SetInt(n)
+				return nil
+			}
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if val.Kind() == ValueKindNumber {
+			if n, err := valueTryToGoUint64(val); err == nil && !dest.OverflowUint(n) {
+				dest.SetUint(n)
+				return nil
+			}
+		}
+	case reflect.Float32, reflect.Float64:
+		if val.Kind() == ValueKindNumber {
+			f := val.asF64().Unwrap()
+			if !dest.OverflowFloat(f) {
+				dest.SetFloat(f)
+				return nil
+			}
+		}
+	case reflect.String:
+		if s, ok := val.data.(stringValue); ok {
+			dest.SetString(s.Str)
+			return nil
+		}
+	case reflect.Slice:
+		if ty.Elem().Kind() == reflect.Uint8 {
+			switch v := val.data.(type) {
+			case bytesValue:
+				dest.SetBytes(bytes.Clone(v.B))
+				return nil
+			case stringValue:
+				dest.SetBytes([]byte(v.Str))
+				return nil
+			}
+		}
+		if val.Kind() == ValueKindSeq {
+			return d.decodeSlice(val, dest, path)
+		}
+	case reflect.Array:
+		if val.Kind() == ValueKindSeq {
+			return d.decodeArray(val, dest, path)
+		}
+	case reflect.Map:
+		if val.Kind() == ValueKindMap {
+			return d.decodeMap(val, dest, path)
+		}
+	case reflect.Struct:
+		if val.Kind() == ValueKindMap {
+			return d.decodeStruct(val, dest, path)
+		}
+	}
+	return decodeTypeError(path, val, ty)
+}
+
+func (d *valueDecoder) decodeSlice(val Value, dest reflect.Value, path string) error {
+	iter, err := val.tryIter()
+	if err != nil {
+		return decodeError(path, err)
+	}
+	slice := reflect.MakeSlice(dest.Type(), int(iter.Len()), int(iter.Len()))
+	for i, item := 0, (Value{}); iter.Next().UnwrapTo(&item); i++ {
+		if i == slice.Len() {
+			slice = reflect.Append(slice, reflect.Zero(dest.Type().Elem()))
+		}
+		if err := d.decode(item, slice.Index(i), indexPath(path, i)); err != nil {
+			return err
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return decodeError(path, err)
+	}
+	dest.Set(slice)
+	return nil
+}
+
+func (d *valueDecoder) decodeArray(val Value, dest reflect.Value, path string) error {
+	iter, err := val.tryIter()
+	if err != nil {
+		return decodeError(path, err)
+	}
+	// The items are collected first since the length of an iterable
+	// object is not known up front.
+	items, err := iter.Collect()
+	if err != nil {
+		return decodeError(path, err)
+	}
+	if len(items) != dest.Len() {
+		return decodeErrorf(path, "expected sequence of length %d, got %d", dest.Len(), len(items))
+	}
+	for i, item := range items {
+		if err := d.decode(item, dest.Index(i), indexPath(path, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *valueDecoder) decodeMap(val Value, dest reflect.Value, path string) error {
+	iter, err := val.tryIter()
+	if err != nil {
+		return decodeError(path, err)
+	}
+	ty := dest.Type()
+	if dest.IsNil() {
+		dest.Set(reflect.MakeMapWithSize(ty, int(iter.Len())))
+	}
+	for key := (Value{}); iter.Next().UnwrapTo(&key); {
+		keyPath := keyPath(path, key)
+		destKey := reflect.New(ty.Key()).Elem()
+		if err := d.decode(key, destKey, keyPath); err != nil {
+			return err
+		}
+		destVal := reflect.New(ty.Elem()).Elem()
+		if err := d.decode(val.getItemOpt(key).UnwrapOr(Undefined), destVal, keyPath); err != nil {
+			return err
+		}
+		dest.SetMapIndex(destKey, destVal)
+	}
+	return nil
+}
+
+func (d *valueDecoder) decodeStruct(val Value, dest reflect.Value, path string) error {
+	ty := dest.Type()
+	for i := 0; i < ty.NumField(); i++ {
+		f := ty.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := d.config.keyNameForField(f)
+		fieldVal := val.getItemOpt(valueFromString(name)).UnwrapOr(Undefined)
+		if err := d.decode(fieldVal, dest.Field(i), fieldPath(path, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeAny decodes a value into the natural Go type for an empty
+// interface.
+func (d *valueDecoder) decodeAny(val Value, path string) (any, error) {
+	switch v := val.data.(type) {
+	case undefinedValue, noneValue:
+		return nil, nil
+	case boolValue:
+		return v.B, nil
+	case i64Value:
+		return v.N, nil
+	case u64Value:
+		if v.N <= math.MaxInt64 {
+			return int64(v.N), nil
+		}
+		return v.N, nil
+	case i128Value:
+		return valueTryToGoI128(val)
+	case u128Value:
+		return valueTryToGoU128(val)
+	case f64Value:
+		return v.F, nil
+	case stringValue:
+		return v.Str, nil
+	case bytesValue:
+		return bytes.Clone(v.B), nil
+	case dynamicValue:
+		switch o := v.Dy.(type) {
+		case dateTimeObject:
+			return o.t, nil
+		case durationObject:
+			return o.d, nil
+		}
+	}
+	switch val.Kind() {
+	case ValueKindSeq:
+		var rv []any
+		err := d.decodeSlice(val, reflect.ValueOf(&rv).Elem(), path)
+		return rv, err
+	case ValueKindMap:
+		rv := make(map[string]any)
+		iter, err := val.tryIter()
+		if err != nil {
+			return nil, decodeError(path, err)
+		}
+		for key := (Value{}); iter.Next().UnwrapTo(&key); {
+			keyPath := keyPath(path, key)
+			item, err := d.decodeAny(val.getItemOpt(key).UnwrapOr(Undefined), keyPath)
+			if err != nil {
+				return nil, err
+			}
+			rv[key.String()] = item
+		}
+		return rv, nil
+	}
+	return val, nil
+}
+
+func fieldPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func indexPath(path string, idx int) string {
+	return path + "[" + strconv.Itoa(idx) + "]"
+}
+
+func keyPath(path string, key Value) string {
+	if s, ok := key.data.(stringValue); ok {
+		return fieldPath(path, s.Str)
+	}
+	return path + "[" + key.String() + "]"
+}
+
+func decodeTypeError(path string, val Value, ty reflect.Type) error {
+	return decodeErrorf(path, "cannot decode %s into %s", val.Kind(), ty)
+}
+
+func decodeError(path string, err error) error {
+	detail := err.Error()
+	var mErr *Error
+	if errors.As(err, &mErr) {
+		detail = mErr.detail
+	}
+	return decodeErrorf(path, "%s", detail).withSource(err)
+}
+
+func decodeErrorf(path string, format string, args ...any) *Error {
+	detail := fmt.Sprintf(format, args...)
+	if path != "" {
+		detail = path + ": " + detail
+	}
+	return NewError(CannotDeserialize, detail)
+}
@@ -1,8 +1,11 @@
 package mjingo
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/hnakamur/mjingo/option"
 )
 
@@ -56,3 +59,189 @@ func BenchmarkValueAsOptionString(b *testing.B) {
 		}
 	})
 }
+
+type decodeTestLevel int
+
+func (l *decodeTestLevel) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return fmt.Errorf("unknown level %q", text)
+	}
+	return nil
+}
+
+type decodeTestUser struct {
+	Name     string          `json:"name"`
+	Email    string          `json:"email"`
+	Age      uint8           `json:"age"`
+	Tags     []string        `json:"tags"`
+	Level    decodeTestLevel `json:"level"`
+	Joined   time.Time       `json:"joined"`
+	Score    *float64        `json:"score"`
+	Extra    map[string]any  `json:"extra"`
+	Untagged int
+}
+
+func TestDecodeValue(t *testing.T) {
+	t.Run("struct", func(t *testing.T) {
+		env := NewEnvironment()
+		expr, err := env.CompileExpression(`{"users": [{"name": "alice", "email": "a@example.com", "age": 30,
+"tags": ["x", "y"], "level": "high", "joined": "2023-04-05T06:07:08Z", "score": 1.5,
+"extra": {"n": 1, "l": [true, none]}, "Untagged": 7}]}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		val, err := expr.Eval(Undefined)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got struct {
+			Users []decodeTestUser `json:"users"`
+		}
+		if err := DecodeValue(val, &got, WithStructTag("json")); err != nil {
+			t.Fatal(err)
+		}
+		score := 1.5
+		want := decodeTestUser{
+			Name:     "alice",
+			Email:    "a@example.com",
+			Age:      30,
+			Tags:     []string{"x", "y"},
+			Level:    2,
+			Joined:   time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC),
+			Score:    &score,
+			Extra:    map[string]any{"n": int64(1), "l": []any{true, nil}},
+			Untagged: 7,
+		}
+		if len(got.Users) != 1 {
+			t.Fatalf("users length mismatch, got=%d", len(got.Users))
+		}
+		if diff := cmp.Diff(want, got.Users[0]); diff != "" {
+			t.Errorf("result mismatch (-want +got):\n%s", diff)
+		}
+	})
+	t.Run("roundTrip", func(t *testing.T) {
+		type point struct {
+			X, Y int
+		}
+		want := map[string][]point{"a": {{1, 2}}, "b": {{3, 4}, {5, 6}}}
+		var got map[string][]point
+		if err := DecodeValue(ValueFromGoValue(want), &got); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("result mismatch (-want +got):\n%s", diff)
+		}
+	})
+	t.Run("int128", func(t *testing.T) {
+		var want I128
+		want.SetString("-170141183460469231731687303715884105728", 10)
+		var got struct{ N I128 }
+		if err := DecodeValue(ValueFromGoValue(map[string]I128{"N": want}), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.N.Cmp(&want) != 0 {
+			t.Errorf("result mismatch, got=%s, want=%s", got.N.String(), want.String())
+		}
+	})
+	t.Run("iterable", func(t *testing.T) {
+		val := ValueFromObject(&sliceIterableObject{items: []Value{ValueFromInt(1), ValueFromInt(2)}})
+		var got [2]int
+		if err := DecodeValue(val, &got); err != nil {
+			t.Fatal(err)
+		}
+		if want := [2]int{1, 2}; got != want {
+			t.Errorf("result mismatch, got=%v, want=%v", got, want)
+		}
+		var gotSlice []int
+		if err := DecodeValue(val, &gotSlice); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]int{1, 2}, gotSlice); diff != "" {
+			t.Errorf("result mismatch (-want +got):\n%s", diff)
+		}
+		var tooLong [3]int
+		if err := DecodeValue(val, &tooLong); err == nil {
+			t.Error("should fail for an array of another length")
+		}
+	})
+	t.Run("errors", func(t *testing.T) {
+		testCases := []struct {
+			name string
+			val  Value
+			dst  any
+			want string
+		}{
+			{
+				name: "path",
+				val: ValueFromGoValue(map[string]any{"users": []any{
+					map[string]any{"email": "a@example.com"},
+					map[string]any{"email": 3},
+				}}),
+				dst: &struct {
+					Users []struct {
+						Email string `json:"email"`
+					} `json:"users"`
+				}{},
+				want: "cannot deserialize: users[1].email: cannot decode number into string",
+			},
+			{
+				name: "overflow",
+				val:  ValueFromGoValue([]int{1, 300}),
+				dst:  &[]uint8{},
+				want: "cannot deserialize: [1]: cannot decode number into uint8",
+			},
+			{
+				name: "textUnmarshaler",
+				val:  ValueFromGoValue(map[string]string{"level": "medium"}),
+				dst:  &map[string]decodeTestLevel{},
+				want: `cannot deserialize: level: unknown level "medium"`,
+			},
+			{
+				name: "array",
+				val:  ValueFromGoValue([]int{1, 2, 3}),
+				dst:  &[2]int{},
+				want: "cannot deserialize: expected sequence of length 2, got 3",
+			},
+			{
+				name: "nonPointer",
+				val:  ValueFromGoValue(1),
+				dst:  1,
+				want: "cannot deserialize: destination must be a non-nil pointer, got int",
+			},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				err := DecodeValue(tc.val, tc.dst, WithStructTag("json"))
+				if err == nil {
+					t.Fatal("should fail")
+				}
+				if got := err.Error(); got != tc.want {
+					t.Errorf("error mismatch,\n got=%s,\nwant=%s", got, tc.want)
+				}
+			})
+		}
+	})
+}
+
+// sliceIterableObject is an iterable object over a fixed slice of items.
+type sliceIterableObject struct {
+	items []Value
+}
+
+func (*sliceIterableObject) Kind() ObjectKind { return ObjectKindIterable }
+
+func (o *sliceIterableObject) Iterate() func() option.Option[Value] {
+	i := 0
+	return func() option.Option[Value] {
+		if i >= len(o.items) {
+			return option.None[Value]()
+		}
+		i++
+		return option.Some(o.items[i-1])
+	}
+}
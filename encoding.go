@@ -0,0 +1,592 @@
+package mjingo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// dataEntry is an entry of a dataMap.
+type dataEntry struct {
+	key   string
+	value any
+}
+
+// dataMap is a map of plain data which keeps the order of the entries.
+type dataMap []dataEntry
+
+// valueToData converts val to plain data for the serialization filters.
+//
+// The result consists of nil, bool, int64, float64, string, []any and
+// dataMap values.  Dates and durations are converted to strings.  If
+// sortKeys is true, the entries of maps are sorted by key.
+func valueToData(val Value, sortKeys bool) (any, error) {
+	switch v := val.data.(type) {
+	case seqValue:
+		return valuesToData(v.Items, sortKeys)
+	case mapValue:
+		rv := make(dataMap, 0, v.Map.Len())
+		for i := uint(0); i < v.Map.Len(); i++ {
+			e, _ := v.Map.EntryAt(i)
+			var key string
+			if !e.Key.AsStr().UnwrapTo(&key) {
+				return nil, NewError(InvalidOperation, "cannot serialize map with non-string key")
+			}
+			item, err := valueToData(e.Value, sortKeys)
+			if err != nil {
+				return nil, err
+			}
+			rv = append(rv, dataEntry{key: key, value: item})
+		}
+		return sortDataMap(rv, sortKeys), nil
+	case dynamicValue:
+		switch o := v.Dy.(type) {
+		case dateTimeObject:
+			return o.String(), nil
+		case durationObject:
+			return o.String(), nil
+		}
+		switch v.Dy.Kind() {
+		case ObjectKindSeq:
+			seq := v.Dy.(SeqObject)
+			items := make([]Value, 0, seq.ItemCount())
+			for i := uint(0); i < seq.ItemCount(); i++ {
+				items = append(items, seq.GetItem(i).Unwrap())
+			}
+			return valuesToData(items, sortKeys)
+		case ObjectKindStruct:
+			obj := v.Dy.(StructObject)
+			fields := staticOrDynamicFields(obj)
+			rv := make(dataMap, 0, len(fields))
+			for _, field := range fields {
+				item, err := valueToData(obj.GetField(field).Unwrap(), sortKeys)
+				if err != nil {
+					return nil, err
+				}
+				rv = append(rv, dataEntry{key: field, value: item})
+			}
+			return sortDataMap(rv, sortKeys), nil
+		case ObjectKindMap:
+			return valueToData(valueFromIndexMap(valueMapFromMapObject(v.Dy.(MapObject))), sortKeys)
+		case ObjectKindIterable:
+			items, err := collectIterableObject(v.Dy.(IterableObject))
+			if err != nil {
+				return nil, err
+			}
+			return valuesToData(items, sortKeys)
+		}
+	}
+	return valueTryToJSONObject(val)
+}
+
+func valuesToData(items []Value, sortKeys bool) ([]any, error) {
+	rv := make([]any, 0, len(items))
+	for _, item := range items {
+		v, err := valueToData(item, sortKeys)
+		if err != nil {
+			return nil, err
+		}
+		rv = append(rv, v)
+	}
+	return rv, nil
+}
+
+func sortDataMap(m dataMap, sortKeys bool) dataMap {
+	if sortKeys {
+		slices.SortStableFunc(m, func(a, b dataEntry) int { return strings.Compare(a.key, b.key) })
+	}
+	return m
+}
+
+// writeJSON writes v as JSON.  If indent is not empty, the output is
+// pretty printed with indent for each level.
+func writeJSON(b *strings.Builder, v any, indent string, level int) error {
+	newline := func(level int) {
+		if indent != "" {
+			b.WriteByte('\n')
+			b.WriteString(strings.Repeat(indent, level))
+		}
+	}
+	switch v := v.(type) {
+	case dataMap:
+		if len(v) == 0 {
+			b.WriteString("{}")
+			return nil
+		}
+		b.WriteByte('{')
+		for i, e := range v {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			newline(level + 1)
+			writeQuotedString(b, e.key)
+			b.WriteByte(':')
+			if indent != "" {
+				b.WriteByte(' ')
+			}
+			if err := writeJSON(b, e.value, indent, level+1); err != nil {
+				return err
+			}
+		}
+		newline(level)
+		b.WriteByte('}')
+	case []any:
+		if len(v) == 0 {
+			b.WriteString("[]")
+			return nil
+		}
+		b.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			newline(level + 1)
+			if err := writeJSON(b, item, indent, level+1); err != nil {
+				return err
+			}
+		}
+		newline(level)
+		b.WriteByte(']')
+	case string:
+		writeQuotedString(b, v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		b.Write(data)
+	}
+	return nil
+}
+
+// marshalJSON serializes val as JSON which is safe to embed in HTML.
+func marshalJSON(val Value, indent string, sortKeys bool) (string, error) {
+	data, err := valueToData(val, sortKeys)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := writeJSON(&b, data, indent, 0); err != nil {
+		return "", NewError(InvalidOperation, "cannot serialize to JSON").withSource(err)
+	}
+	return b.String(), nil
+}
+
+// writeQuotedString writes s as a double quoted string which is valid in
+// JSON, YAML and TOML.  The characters `<`, `>`, `&` and `'` are escaped
+// so that the result is safe to embed in HTML.
+func writeQuotedString(b *strings.Builder, s string) {
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '<', '>', '&', '\'', '\u2028', '\u2029':
+			fmt.Fprintf(b, `\u%04x`, r)
+		default:
+			if r < 0x20 || r == 0x7f || r == utf8.RuneError {
+				fmt.Fprintf(b, `\u%04x`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+}
+
+// yamlPlainUnsafeRe matches strings which cannot be written as plain
+// YAML scalars.
+var yamlPlainUnsafeRe = regexp.MustCompile(`^[-?:,\[\]{}#&*!|>'"%@` + "`" + `\s]|\s$|:$|: | #|[<>&'\p{Cc}\x{2028}\x{2029}\x{FFFD}]`)
+
+// yamlWriter writes plain data as block style YAML.
+type yamlWriter struct {
+	b      strings.Builder
+	indent int
+}
+
+func (w *yamlWriter) pad(col int) {
+	w.b.WriteString(strings.Repeat(" ", col))
+}
+
+// writeMap writes the entries of m at column col.  If cont is true, the
+// first entry continues the current line.
+func (w *yamlWriter) writeMap(m dataMap, col int, cont bool) error {
+	for i, e := range m {
+		if i > 0 || !cont {
+			w.pad(col)
+		}
+		w.writeString(e.key, -1)
+		w.b.WriteByte(':')
+		if err := w.writeValue(e.value, col); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSeq writes the items of s at column col.  If cont is true, the
+// first item continues the current line.
+func (w *yamlWriter) writeSeq(s []any, col int, cont bool) error {
+	for i, item := range s {
+		if i > 0 || !cont {
+			w.pad(col)
+		}
+		w.b.WriteString("- ")
+		var err error
+		switch item := item.(type) {
+		case dataMap:
+			if len(item) != 0 {
+				err = w.writeMap(item, col+2, true)
+				break
+			}
+			err = w.writeScalar(item, col+2)
+		case []any:
+			if len(item) != 0 {
+				err = w.writeSeq(item, col+2, true)
+				break
+			}
+			err = w.writeScalar(item, col+2)
+		default:
+			err = w.writeScalar(item, col+2)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeValue writes the value of a map entry at column col.
+func (w *yamlWriter) writeValue(v any, col int) error {
+	switch v := v.(type) {
+	case dataMap:
+		if len(v) != 0 {
+			w.b.WriteByte('\n')
+			return w.writeMap(v, col+w.indent, false)
+		}
+	case []any:
+		if len(v) != 0 {
+			w.b.WriteByte('\n')
+			return w.writeSeq(v, col+w.indent, false)
+		}
+	}
+	w.b.WriteByte(' ')
+	return w.writeScalar(v, col+w.indent)
+}
+
+// writeScalar writes a scalar or an empty collection followed by a
+// newline.  The lines of block scalars are indented to col.
+func (w *yamlWriter) writeScalar(v any, col int) error {
+	switch v := v.(type) {
+	case nil:
+		w.b.WriteString("null")
+	case bool:
+		w.b.WriteString(strconv.FormatBool(v))
+	case int64:
+		w.b.WriteString(strconv.FormatInt(v, 10))
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			w.b.WriteString(".nan")
+		case math.IsInf(v, 1):
+			w.b.WriteString(".inf")
+		case math.IsInf(v, -1):
+			w.b.WriteString("-.inf")
+		default:
+			w.b.WriteString(formatFloatWithPoint(v))
+		}
+	case string:
+		w.writeString(v, col)
+	case dataMap:
+		w.b.WriteString("{}")
+	case []any:
+		w.b.WriteString("[]")
+	default:
+		return fmt.Errorf("unsupported type %T", v)
+	}
+	w.b.WriteByte('\n')
+	return nil
+}
+
+// writeString writes s as a plain, literal block or double quoted scalar.
+// Literal blocks are only used if col is not negative.
+func (w *yamlWriter) writeString(s string, col int) {
+	if s != "" && !yamlPlainUnsafeRe.MatchString(s) && !strings.HasPrefix(s, "...") {
+		if _, isStr := resolveYAMLPlainScalar(s).data.(stringValue); isStr {
+			w.b.WriteString(s)
+			return
+		}
+	}
+	if col >= 0 && isYAMLLiteralBlockable(s) {
+		body := strings.TrimSuffix(s, "\n")
+		switch {
+		case !strings.HasSuffix(s, "\n"):
+			w.b.WriteString("|-")
+		case strings.HasSuffix(body, "\n"):
+			w.b.WriteString("|+")
+		default:
+			w.b.WriteString("|")
+		}
+		for _, line := range strings.Split(body, "\n") {
+			w.b.WriteByte('\n')
+			if line != "" {
+				w.pad(col)
+				w.b.WriteString(line)
+			}
+		}
+		return
+	}
+	writeQuotedString(&w.b, s)
+}
+
+// isYAMLLiteralBlockable reports whether s is a multi-line string which
+// can be written as a literal block scalar.
+func isYAMLLiteralBlockable(s string) bool {
+	if !strings.Contains(strings.TrimRight(s, "\n"), "\n") ||
+		strings.TrimLeft(s, "\n") != s || strings.HasPrefix(s, " ") {
+		return false
+	}
+	for _, r := range s {
+		if r != '\n' && (r < 0x20 || r == 0x7f || strings.ContainsRune("<>&'\u2028\u2029\ufffd", r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// formatFloatWithPoint formats f so that it is read back as a float.
+func formatFloatWithPoint(f float64) string {
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+// tomlBareKeyRe matches keys which can be written without quotes.
+var tomlBareKeyRe = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// tomlWriter writes plain data as TOML.
+type tomlWriter struct {
+	b strings.Builder
+}
+
+func isTOMLTable(v any) bool {
+	m, ok := v.(dataMap)
+	return ok && len(m) != 0
+}
+
+func isTOMLArrayOfTables(v any) bool {
+	s, ok := v.([]any)
+	if !ok || len(s) == 0 {
+		return false
+	}
+	for _, item := range s {
+		if _, ok := item.(dataMap); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// writeTable writes the entries of the table at path.  Keys with plain
+// values are written before sub-tables as required by TOML.
+func (w *tomlWriter) writeTable(m dataMap, path []string) error {
+	for _, e := range m {
+		if isTOMLTable(e.value) || isTOMLArrayOfTables(e.value) {
+			continue
+		}
+		w.writeKey(e.key)
+		w.b.WriteString(" = ")
+		if err := w.writeValue(e.value); err != nil {
+			return fmt.Errorf("%s: %w", strings.Join(append(path, e.key), "."), err)
+		}
+		w.b.WriteByte('\n')
+	}
+	for _, e := range m {
+		subPath := append(slices.Clip(path), e.key)
+		switch {
+		case isTOMLTable(e.value):
+			w.writeHeader("[", subPath, "]")
+			if err := w.writeTable(e.value.(dataMap), subPath); err != nil {
+				return err
+			}
+		case isTOMLArrayOfTables(e.value):
+			for _, item := range e.value.([]any) {
+				w.writeHeader("[[", subPath, "]]")
+				if err := w.writeTable(item.(dataMap), subPath); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (w *tomlWriter) writeHeader(open string, path []string, close string) {
+	if w.b.Len() != 0 {
+		w.b.WriteByte('\n')
+	}
+	w.b.WriteString(open)
+	for i, key := range path {
+		if i > 0 {
+			w.b.WriteByte('.')
+		}
+		w.writeKey(key)
+	}
+	w.b.WriteString(close)
+	w.b.WriteByte('\n')
+}
+
+func (w *tomlWriter) writeKey(key string) {
+	if tomlBareKeyRe.MatchString(key) {
+		w.b.WriteString(key)
+	} else {
+		writeQuotedString(&w.b, key)
+	}
+}
+
+// writeValue writes v as an inline value.
+func (w *tomlWriter) writeValue(v any) error {
+	switch v := v.(type) {
+	case nil:
+		return errors.New("none cannot be represented in TOML")
+	case bool:
+		w.b.WriteString(strconv.FormatBool(v))
+	case int64:
+		w.b.WriteString(strconv.FormatInt(v, 10))
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			w.b.WriteString("nan")
+		case math.IsInf(v, 1):
+			w.b.WriteString("inf")
+		case math.IsInf(v, -1):
+			w.b.WriteString("-inf")
+		default:
+			w.b.WriteString(formatFloatWithPoint(v))
+		}
+	case string:
+		writeQuotedString(&w.b, v)
+	case []any:
+		w.b.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				w.b.WriteString(", ")
+			}
+			if err := w.writeValue(item); err != nil {
+				return err
+			}
+		}
+		w.b.WriteByte(']')
+	case dataMap:
+		if len(v) == 0 {
+			w.b.WriteString("{}")
+			return nil
+		}
+		w.b.WriteString("{ ")
+		for i, e := range v {
+			if i > 0 {
+				w.b.WriteString(", ")
+			}
+			w.writeKey(e.key)
+			w.b.WriteString(" = ")
+			if err := w.writeValue(e.value); err != nil {
+				return err
+			}
+		}
+		w.b.WriteString(" }")
+	default:
+		return fmt.Errorf("unsupported type %T", v)
+	}
+	return nil
+}
+
+// decodeJSON decodes a JSON value keeping the order of object keys.
+func decodeJSON(dec *json.Decoder) (Value, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return Value{}, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		if t == '[' {
+			var items []Value
+			for dec.More() {
+				item, err := decodeJSON(dec)
+				if err != nil {
+					return Value{}, err
+				}
+				items = append(items, item)
+			}
+			if _, err := dec.Token(); err != nil {
+				return Value{}, err
+			}
+			return valueFromSlice(items), nil
+		}
+		m := newValueMap()
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return Value{}, err
+			}
+			item, err := decodeJSON(dec)
+			if err != nil {
+				return Value{}, err
+			}
+			m.Set(keyRefFromString(keyTok.(string)), item)
+		}
+		if _, err := dec.Token(); err != nil {
+			return Value{}, err
+		}
+		return valueFromIndexMap(m), nil
+	case json.Number:
+		if n, err := t.Int64(); err == nil {
+			return valueFromI64(n), nil
+		}
+		if n, err := strconv.ParseUint(t.String(), 10, 64); err == nil {
+			return valueFromU64(n), nil
+		}
+		f, err := t.Float64()
+		if err != nil {
+			return Value{}, err
+		}
+		return valueFromF64(f), nil
+	case string:
+		return valueFromString(t), nil
+	case bool:
+		return valueFromBool(t), nil
+	default:
+		return none, nil
+	}
+}
+
+// unmarshalJSON parses s as a single JSON value.
+func unmarshalJSON(s string) (Value, error) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	val, err := decodeJSON(dec)
+	if err == nil {
+		if _, err = dec.Token(); err == io.EOF {
+			return val, nil
+		} else if err == nil {
+			err = errors.New("unexpected data after top-level value")
+		}
+	}
+	return Value{}, NewError(InvalidOperation, "cannot parse JSON").withSource(err)
+}
@@ -1,6 +1,8 @@
 package mjingo
 
 import (
+	"time"
+
 	"github.com/hnakamur/mjingo/option"
 )
 
@@ -27,6 +29,11 @@ type Environment struct {
 	defaultAutoEscape AutoEscapeFunc
 	undefinedBehavior UndefinedBehavior
 	formatter         formatterFunc
+	customTags        map[string]CustomTagFunc
+	translator        Translator
+	regexCache        *regexCache
+	clock             func() time.Time
+	rand              *lockedRand
 	debug             bool
 }
 
@@ -50,6 +57,8 @@ func NewEnvironment() *Environment {
 		globals:           getDefaultGlobals(),
 		defaultAutoEscape: DefaultAutoEscapeCallback,
 		formatter:         escapeFormatter,
+		regexCache:        newRegexCache(DefaultRegexCacheSize),
+		rand:              newLockedRandFromTime(),
 	}
 }
 
@@ -65,6 +74,8 @@ func NewEnvironmentEmpty() *Environment {
 		globals:           make(map[string]Value),
 		defaultAutoEscape: noAutoEscape,
 		formatter:         escapeFormatter,
+		regexCache:        newRegexCache(DefaultRegexCacheSize),
+		rand:              newLockedRandFromTime(),
 	}
 }
 
@@ -104,6 +115,24 @@ func (e *Environment) SetKeepTrailingNewline(yes bool) {
 	e.templates.KeepTrailingNewline = yes
 }
 
+// SetSyntax sets the delimiters and the line prefixes for templates.
+//
+// The syntax applies to templates added after this call, so it should be
+// set before templates are added.  An [Error] with the [InvalidDelimiter]
+// kind is returned if the syntax is invalid.
+func (e *Environment) SetSyntax(syntax Syntax) error {
+	if err := syntax.validate(); err != nil {
+		return err
+	}
+	e.templates.SyntaxConfig.Syntax = syntax
+	return nil
+}
+
+// Syntax returns the current syntax configuration.
+func (e *Environment) Syntax() Syntax {
+	return e.templates.SyntaxConfig.Syntax
+}
+
 // KeepTrailingNewline returns the value of the trailing newline preservation flag.
 func (e *Environment) KeepTrailingNewline() bool {
 	return e.templates.KeepTrailingNewline
@@ -325,6 +354,91 @@ func (e *Environment) RemoveGlobal(name string) {
 	delete(e.globals, name)
 }
 
+// SetTranslator sets the translator used by the gettext functions and
+// the `{% trans %}` statement.
+//
+// When no translator is set, messages are not translated.  See
+// [TranslatorGlobals] for using different translators per render.
+func (e *Environment) SetTranslator(t Translator) {
+	e.translator = t
+}
+
+// SetRegexCacheSize sets the maximum number of compiled regular expressions
+// kept by the environment for the regex filters and tests.
+//
+// The least recently used patterns are evicted once the limit is reached so
+// that templates using many distinct patterns cannot grow memory without
+// bound.  A size of zero or less disables caching.  The default is
+// [DefaultRegexCacheSize].
+func (e *Environment) SetRegexCacheSize(size int) {
+	e.regexCache.setMaxSize(size)
+}
+
+// SetClock sets the function returning the current time used by the
+// `now` function and the `timesince` filter.
+//
+// This is useful for deterministic tests.  Passing nil restores the
+// default which is [time.Now].
+func (e *Environment) SetClock(clock func() time.Time) {
+	e.clock = clock
+}
+
+// SetRandomSeed seeds the random number generator used by the `random` and
+// `shuffle` filters.
+//
+// By default the generator is seeded with the current time.  Seeding it
+// with a fixed value makes the results reproducible, which is useful for
+// tests.
+func (e *Environment) SetRandomSeed(seed int64) {
+	e.rand.seed(seed)
+}
+
+func (e *Environment) now() time.Time {
+	if e.clock == nil {
+		return time.Now()
+	}
+	return e.clock()
+}
+
+// AddCustomTag registers a custom statement tag.
+//
+// The tag is used as `{% name args... %}body{% endName %}` in templates
+// where the arguments are comma separated expressions which can also be
+// keyword arguments.  If endName is empty, the tag has no body.  When the
+// tag is rendered, f is called with the evaluated arguments and a function
+// to render the body.
+//
+// Built-in statements cannot be overridden.  Since custom tags are resolved
+// when templates are parsed, they have to be registered before templates are
+// added to the environment.
+func (e *Environment) AddCustomTag(name, endName string, f CustomTagFunc) {
+	if e.customTags == nil {
+		e.customTags = make(map[string]CustomTagFunc)
+	}
+	e.customTags[name] = f
+
+	// copy the map so that syntax configs shared with already compiled
+	// templates are not modified.
+	tags := make(map[string]string, len(e.templates.SyntaxConfig.customTags)+1)
+	for n, t := range e.templates.SyntaxConfig.customTags {
+		tags[n] = t
+	}
+	tags[name] = endName
+	e.templates.SyntaxConfig.customTags = tags
+}
+
+// RemoveCustomTag removes a custom statement tag by name.
+func (e *Environment) RemoveCustomTag(name string) {
+	delete(e.customTags, name)
+	tags := make(map[string]string, len(e.templates.SyntaxConfig.customTags))
+	for n, t := range e.templates.SyntaxConfig.customTags {
+		if n != name {
+			tags[n] = t
+		}
+	}
+	e.templates.SyntaxConfig.customTags = tags
+}
+
 func (e *Environment) format(v Value, state *State, out *output) error {
 	if v.isUndefined() && e.undefinedBehavior == UndefinedBehaviorStrict {
 		return NewError(UndefinedError, "")
@@ -357,3 +471,10 @@ func (e *Environment) getTest(name string) option.Option[BoxedTest] {
 	}
 	return option.None[BoxedTest]()
 }
+
+func (e *Environment) getCustomTag(name string) option.Option[CustomTagFunc] {
+	if f, ok := e.customTags[name]; ok {
+		return option.Some(f)
+	}
+	return option.None[CustomTagFunc]()
+}
@@ -2,10 +2,15 @@ package mjingo_test
 
 import (
 	"errors"
+	"fmt"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/hnakamur/mjingo"
+	"github.com/hnakamur/mjingo/option"
 )
 
 func TestSingleTemplate(t *testing.T) {
@@ -202,6 +207,9 @@ func TestSingleTemplate(t *testing.T) {
 			{name: "scOr1", source: `{{ false or false }}`, context: nil, want: "false"},
 			{name: "scOr2", source: `{{ false or true }}`, context: nil, want: "true"},
 			{name: "scOr3", source: `{{ true or false }}`, context: nil, want: "true"},
+			{name: "strFormatTuple", source: `{{ "%s has %d items" % (name, 2) }}`, context: map[string]string{"name": "cart"}, want: "cart has 2 items"},
+			{name: "strFormatSingle", source: `{{ "%.1f%%" % 12.345 }}`, context: nil, want: "12.3%"},
+			{name: "strFormatMap", source: `{{ "%(a)s-%(b)03d" % {"a": "x", "b": 7} }}`, context: nil, want: "x-007"},
 		})
 	})
 	t.Run("statement", func(t *testing.T) {
@@ -533,6 +541,128 @@ func TestSingleTemplate(t *testing.T) {
 			{name: "unique", source: `{% autoescape 'none' %}{{ ['foo', 'bar', 'foobar', 'foobar']|unique }}{% endautoescape %}`, context: nil, want: `["foo", "bar", "foobar"]`},
 			{name: "mapCase1", source: `{% autoescape 'none' %}{{ [{"name": "John", "id": 1}, {"name": "Paul", "id": 2}]|map(attribute="name")|join(', ') }}{% endautoescape %}`, context: nil, want: `John, Paul`},
 			{name: "mapCase2", source: `{% autoescape 'none' %}{{ [-1, -2, 3, 4, -5]|map("abs")|join(", ") }}{% endautoescape %}`, context: nil, want: `1, 2, 3, 4, 5`},
+			{name: "formatPositional", source: `{{ "%s has %d items"|format("cart", 3) }}`, context: nil, want: "cart has 3 items"},
+			{name: "formatFloatPrec", source: `{{ "%.2f"|format(3.14159) }}`, context: nil, want: "3.14"},
+			{name: "formatWidth", source: `{{ "[%5s|%-5s|%05d]"|format("ab", "cd", 42) }}`, context: nil, want: "[   ab|cd   |00042]"},
+			{name: "formatHex", source: `{{ "%x %X %#x %o"|format(255, 255, 255, 8) }}`, context: nil, want: "ff FF 0xff 10"},
+			{name: "formatRepr", source: `{% autoescape 'none' %}{{ "%r"|format("a") }}{% endautoescape %}`, context: nil, want: `"a"`},
+			{name: "formatKwargs", source: `{{ "%(user)s is %(age)d"|format(user="john", age=42) }}`, context: nil, want: "john is 42"},
+			{name: "formatPercent", source: `{{ "%d%%"|format(50) }}`, context: nil, want: "50%"},
+			{name: "int", source: `{{ "42"|int + 1 }} {{ " -7 "|int }} {{ 3.9|int }} {{ -3.9|int }} {{ true|int }} {{ "1_000"|int }}`, context: nil, want: "43 -7 3 -3 1 1000"},
+			{name: "intBase", source: `{{ "0x1A"|int(base=16) }} {{ "ff"|int(0, 16) }} {{ "0b101"|int(base=0) }} {{ "010"|int(base=0) }}`, context: nil, want: "26 255 5 10"},
+			{name: "intFromFloatString", source: `{{ "3.9"|int }} {{ "1e3"|int }}`, context: nil, want: "3 1000"},
+			{name: "intDefault", source: `{{ "abc"|int }} {{ "abc"|int(-1) }} {{ none|int(default=7) }} {{ [1]|int }}`, context: nil, want: "0 -1 7 0"},
+			{name: "intLarge", source: `{{ "170141183460469231731687303715884105727"|int }} {{ "340282366920938463463374607431768211455"|int }} {{ 1e20|int }}`, context: nil, want: "170141183460469231731687303715884105727 340282366920938463463374607431768211455 100000000000000000000"},
+			{name: "float", source: `{{ "42.5"|float + 1 }} {{ 2|float }} {{ "abc"|float }} {{ "abc"|float(-1.5) }} {{ " 1e3 "|float }}`, context: nil, want: "43.5 2.0 0.0 -1.5 1000.0"},
+			{name: "string", source: `{{ (42|string ~ "!") }} {{ [1, "a"]|string }} {{ (1|string)|length }}`, context: nil, want: `42! [1, &quot;a&quot;] 1`},
+			{name: "stringKeepsSafe", source: `{{ "<b>"|safe|string }}`, context: nil, want: "<b>"},
+			{name: "sum", source: `{{ [1, 2, 3]|sum }} {{ [1, 2.5]|sum }} {{ [1, 2]|sum(start=10) }}`, context: nil, want: "6 3.5 13"},
+			{name: "sumAttribute", source: `{{ items|sum(attribute="price") }} {{ items|sum(attribute="info.qty") }}`, context: map[string]any{"items": []map[string]any{{"price": 3, "info": map[string]int{"qty": 1}}, {"price": 4, "info": map[string]int{"qty": 2}}}}, want: "7 3"},
+			{name: "sumLarge", source: `{{ [9223372036854775807, 1]|sum }}`, context: nil, want: "9223372036854775808"},
+			{
+				name:   "groupby",
+				source: `{% for city, items in users|groupby("city") %}{{ city }}: {{ items|map(attribute="name")|join(",") }};{% endfor %}`,
+				context: map[string]any{"users": []map[string]any{
+					{"name": "a", "city": "Tokyo"}, {"name": "b", "city": "Berlin"}, {"name": "c", "city": "tokyo"},
+				}},
+				want: "Berlin: b;Tokyo: a,c;",
+			},
+			{
+				name:   "groupbyCaseSensitiveAttrs",
+				source: `{% for g in users|groupby("city", case_sensitive=true) %}{{ g.grouper }}={{ g.list|length }};{% endfor %}`,
+				context: map[string]any{"users": []map[string]any{
+					{"name": "a", "city": "Tokyo"}, {"name": "b", "city": "Berlin"}, {"name": "c", "city": "tokyo"},
+				}},
+				want: "Berlin=1;Tokyo=1;tokyo=1;",
+			},
+			{
+				name:   "groupbyPathAndDefault",
+				source: `{% for g, items in rows|groupby("info.status", default="none") %}{{ g }}={{ items|length }};{% endfor %}`,
+				context: map[string]any{"rows": []map[string]any{
+					{"info": map[string]any{"status": "open"}}, {"info": map[string]any{}}, {"info": map[string]any{"status": "done"}}, {"x": 1},
+				}},
+				want: "done=1;none=2;open=1;",
+			},
+			{
+				name:    "groupbyIndex",
+				source:  `{% for g, items in pairs|groupby(0) %}{{ g }}:{{ items|map(attribute=1)|join }};{% endfor %}`,
+				context: map[string]any{"pairs": [][]any{{2, "a"}, {1, "b"}, {2, "c"}}},
+				want:    "1:b;2:ac;",
+			},
+			{name: "truncate", source: `{{ "foo bar baz qux"|truncate(9) }}`, context: nil, want: "foo..."},
+			{name: "truncateKillwords", source: `{{ "foo bar baz qux"|truncate(9, true) }}`, context: nil, want: "foo ba..."},
+			{name: "truncateKwargs", source: `{{ "foo bar baz qux"|truncate(11, end=" [..]", leeway=0) }}`, context: nil, want: "foo [..]"},
+			{name: "truncateLeeway", source: `{{ "foo bar baz qux"|truncate(11) }}|{{ "short"|truncate }}`, context: nil, want: "foo bar baz qux|short"},
+			{name: "truncateUnicode", source: `{{ "äöü äöü äöü"|truncate(6, true, "…", 0) }}`, context: nil, want: "äöü ä…"},
+			{name: "wordwrap", source: `{{ "a very long sentence"|wordwrap(8) }}`, context: nil, want: "a very\nlong\nsentence"},
+			{name: "wordwrapParagraphs", source: `{{ "aaa bbb ccc\n\nddd"|wordwrap(7, wrapstring="|") }}`, context: nil, want: "aaa bbb|ccc||ddd"},
+			{name: "wordwrapLongWords", source: `{{ "abcdefgh ij"|wordwrap(3) }}|{{ "abcdefgh ij"|wordwrap(3, false) }}`, context: nil, want: "abc\ndef\ngh\nij|abcdefgh\nij"},
+			{name: "center", source: `[{{ "foo"|center(9) }}][{{ "foo"|center(2) }}][{{ "ab"|center(5) }}]`, context: nil, want: "[   foo   ][foo][  ab ]"},
+			{name: "wordcount", source: `{{ "Hello, wörld! foo_bar 42"|wordcount }}`, context: nil, want: "4"},
+			{name: "striptags", source: `{{ "<p>Hello <!-- x --><b>World</b>\n &amp; <i>co</i></p>"|striptags }}`, context: nil, want: "Hello World &amp; co"},
+			{name: "striptagsUnescape", source: `{% autoescape false %}{{ "a &lt;b&gt; &quot;c&quot;"|striptags }}{% endautoescape %}`, context: nil, want: `a <b> "c"`},
+			{name: "regexReplace", source: `{{ "2024-01-31"|regex_replace("(\\d+)-(\\d+)-(\\d+)", "$3.$2.$1") }}`, context: nil, want: "31.01.2024"},
+			{name: "regexReplaceNamed", source: `{{ "john smith"|regex_replace("(?P<first>\\w+) (?P<last>\\w+)", "${last}, ${first}") }}`, context: nil, want: "smith, john"},
+			{name: "regexReplaceCount", source: `{{ "a-b-c"|regex_replace("-", "+", count=1) }}`, context: nil, want: "a+b-c"},
+			{name: "regexReplaceDefault", source: `{{ "a1b2"|regex_replace("\\d") }}`, context: nil, want: "ab"},
+			{name: "regexReplaceIgnoreCase", source: `{{ "Foo foo"|regex_replace("foo", "x", ignorecase=true) }}`, context: nil, want: "x x"},
+			{name: "regexSearch", source: `{{ "fix: handle PROJ-123 timeout"|regex_search("[A-Z]+-\\d+") }}`, context: nil, want: "PROJ-123"},
+			{name: "regexSearchNoMatch", source: `{{ "nothing"|regex_search("\\d+") is none }}`, context: nil, want: "true"},
+			{name: "regexSearchMultiline", source: `{{ "a\nb"|regex_search("^b$", multiline=true) }}`, context: nil, want: "b"},
+			{name: "regexFindall", source: `{{ "a1 b22 c333"|regex_findall("\\d+")|join(",") }}`, context: nil, want: "1,22,333"},
+			{name: "regexFindallGroup", source: `{{ "a=1, b=2"|regex_findall("(\\w)=\\d")|join(",") }}`, context: nil, want: "a,b"},
+			{name: "regexFindallGroups", source: `{% for k, v in "a=1, b=2"|regex_findall("(\\w)=(\\d)") %}{{ k }}:{{ v }};{% endfor %}`, context: nil, want: "a:1;b:2;"},
+			{name: "datetimeformat", source: `{{ 1700000000|datetimeformat }}`, context: nil, want: "Nov 14 2023 22:13"},
+			{name: "datetimeformatStrftimeTZ", source: `{{ 1700000000|datetimeformat("%d.%m.%Y %H:%M %Z", tz="Asia/Tokyo") }}`, context: nil, want: "15.11.2023 07:13 JST"},
+			{name: "datetimeformatGoLayout", source: `{{ t|datetimeformat("Mon, 02 Jan 2006 15:04:05 -0700") }}`, context: map[string]any{"t": time.Date(2024, 2, 9, 8, 5, 3, 0, time.FixedZone("", -5*3600))}, want: "Fri, 09 Feb 2024 08:05:03 -0500"},
+			{name: "datetimeformatIso", source: `{{ "2024-02-09 08:05:03"|datetimeformat("iso") }}`, context: nil, want: "2024-02-09T08:05:03+00:00"},
+			{name: "datetimeformatUnix", source: `{{ "2024-02-09T08:05:03+09:00"|datetimeformat("unix") }}`, context: nil, want: "1707433503"},
+			{name: "dateformat", source: `{{ t|dateformat("full") }} {{ t|dateformat("%a %-d %b %y, day %j") }}`, context: map[string]any{"t": time.Date(2024, 2, 9, 8, 5, 3, 0, time.UTC)}, want: "Friday, February 9 2024 Fri 9 Feb 24, day 040"},
+			{name: "timeformat", source: `{{ t|timeformat }} {{ t|timeformat("long") }} {{ t|timeformat("%I:%M %p") }}`, context: map[string]any{"t": time.Date(2024, 2, 9, 20, 5, 3, 120000000, time.UTC)}, want: "20:05:03 20:05:03.120000 08:05 PM"},
+			{name: "timesince", source: `{{ "2024-01-01"|timesince(now="2024-01-18T06:00:00Z") }}`, context: nil, want: "2 weeks, 3 days"},
+			{name: "timesinceSingular", source: `{{ "2023-01-01"|timesince(now="2024-02-05") }}`, context: nil, want: "1 year, 1 month"},
+			{name: "timesinceFuture", source: `{{ "2024-01-02"|timesince(now="2024-01-01") }}`, context: nil, want: "0 minutes"},
+			{name: "timesinceDuration", source: `{{ d|timesince }}`, context: map[string]any{"d": 90 * time.Minute}, want: "1 hour, 30 minutes"},
+			{name: "dateAttributes", source: `{{ t.year }}-{{ t.month }}-{{ t.day }} {{ t.weekday }} {{ t.yearday }} {{ t.timezone }} {{ t.offset }}`, context: map[string]any{"t": time.Date(2024, 2, 9, 8, 5, 3, 0, time.FixedZone("EST", -5*3600))}, want: "2024-2-9 4 40 EST -18000"},
+			{name: "dateDisplay", source: `{{ t }}`, context: map[string]any{"t": time.Date(2024, 2, 9, 8, 5, 3, 0, time.UTC)}, want: "2024-02-09T08:05:03Z"},
+			{name: "durationAttributes", source: `{{ d.days }} {{ d.hours }} {{ d.minutes }} {{ d.seconds }} {{ d.total_seconds }} {{ d }}`, context: map[string]any{"d": 26*time.Hour + 3*time.Minute + 4*time.Second}, want: "1 2 3 4 93784.0 26h3m4s"},
+			{name: "urlencode", source: `{{ "a b/ä"|urlencode }}`, context: nil, want: "a%20b&#x2f;%C3%A4"},
+			{name: "urlencodeMap", source: `{{ {"q": "my search", "lang": "fr"}|urlencode }}`, context: nil, want: "q=my%20search&amp;lang=fr"},
+			{name: "quotePlus", source: `{{ "a b/c&d~ü"|quote_plus }} {{ "a/b"|quote_plus("/") }}`, context: nil, want: "a+b%2Fc%26d~%C3%BC a&#x2f;b"},
+			{name: "urldecode", source: `{% autoescape false %}{{ "a+b%2Fc%26d%C3%BC"|urldecode }}{% endautoescape %}`, context: nil, want: "a b/c&dü"},
+			{name: "b64encode", source: `{{ "s3cr3t"|b64encode }} {{ b|b64encode }} {{ b|b64encode(urlsafe=true) }}`, context: map[string]any{"b": []byte{0xfb, 0xff}}, want: "czNjcjN0 +&#x2f;8= -_8="},
+			{name: "b64decode", source: `{{ "czNjcjN0"|b64decode }} {{ "czNjcjM"|b64decode }} {{ "-_8="|b64decode|hexencode }}`, context: nil, want: "s3cr3t s3cr3 fbff"},
+			{name: "hexencode", source: `{{ "hi!"|hexencode }} {{ b|hexencode }}`, context: map[string]any{"b": []byte{0, 0xab}}, want: "686921 00ab"},
+			{name: "digests", source: `{{ "abc"|sha1 }} {{ "abc"|sha256 }} {{ b|md5 }}`, context: map[string]any{"b": []byte("abc")}, want: "a9993e364706816aba3e25717850c26c9cd0d89d ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad 900150983cd24fb0d6963f7d28e17f72"},
+			{name: "bytesContext", source: `{{ b|length }} {% for x in b %}{{ x }}{% endfor %} {{ b|list }} {{ b|first }} {{ b|last }} {{ b[0] }} {{ b[-1] }}`, context: map[string]any{"b": []byte("ab")}, want: "2 9798 [97, 98] 97 98 97 98"},
+			{name: "xmlattr", source: `<input{{ '{"type": "checkbox", "name": "agree", "checked": true, "disabled": false, "title": null}'|fromjson|xmlattr }}>`, context: nil, want: `<input type="checkbox" name="agree" checked>`},
+			{name: "xmlattrEscape", source: `<a{{ {"href": url}|xmlattr }}{{ {"class": "<b>"|safe}|xmlattr }}{{ {"data-n": 1}|xmlattr(false) }}>`, context: map[string]any{"url": `/q?a=1&b="2"`}, want: `<a href="&#x2f;q?a=1&amp;b=&quot;2&quot;" class="<b>"data-n="1">`},
+			{name: "xmlattrEmpty", source: `<br{{ {"id": undefined}|xmlattr }}>`, context: nil, want: `<br>`},
+			{name: "urlize", source: `{{ "see www.example.com/docs, or mail info@example.com"|urlize }}`, context: nil, want: `see <a href="https://www.example.com/docs" rel="noopener">www.example.com/docs</a>, or mail <a href="mailto:info@example.com">info@example.com</a>`},
+			{name: "urlizeEscape", source: `{{ text|urlize }}`, context: map[string]any{"text": `<b> (http://example.com/a?x=1&y=2) "q"`}, want: `&lt;b&gt; (<a href="http://example.com/a?x=1&amp;y=2" rel="noopener">http://example.com/a?x=1&amp;y=2</a>) &quot;q&quot;`},
+			{name: "urlizeBalance", source: `{{ "(https://en.wikipedia.org/wiki/Go_(language))."|urlize(rel="external", nofollow=true, target="_blank") }}`, context: nil, want: `(<a href="https://en.wikipedia.org/wiki/Go_(language)" rel="external nofollow noopener" target="_blank">https://en.wikipedia.org/wiki/Go_(language)</a>).`},
+			{name: "urlizeTrim", source: `{{ "https://example.com/a/long/path mailto:a@b.org example.org"|urlize(10) }}`, context: nil, want: `<a href="https://example.com/a/long/path" rel="noopener">https://ex...</a> <a href="mailto:a@b.org">a@b.org</a> <a href="https://example.org" rel="noopener">example.or...</a>`},
+			{name: "urlizeSafe", source: `{{ "<em>hi</em> www.example.com"|safe|urlize }}`, context: nil, want: `<em>hi</em> <a href="https://www.example.com" rel="noopener">www.example.com</a>`},
+			{name: "urlizeNoAutoEscape", source: `{% autoescape false %}{{ "a<b www.example.com"|urlize }}{% endautoescape %}`, context: nil, want: `a&lt;b <a href="https://www.example.com" rel="noopener">www.example.com</a>`},
+			{name: "filesizeformat", source: `{{ 1|filesizeformat }} {{ 999|filesizeformat }} {{ 1234567|filesizeformat }} {{ 1234567|filesizeformat(true) }} {{ "2048"|filesizeformat(true) }} {{ 1.5e30|filesizeformat }}`, context: nil, want: `1 Byte 999 Bytes 1.2 MB 1.2 MiB 2.0 KiB 1500000.0 YB`},
+			{name: "split", source: `{{ "  a b\tc  "|split }} {{ "  a b c  "|split(none, 1) }} {{ "a,b,,c"|split(",") }} {{ "a,b,c"|split(",", 1) }} {{ "a-b-c"|split(maxsplit=1, sep="-") }} {{ ""|split|length }}`, context: nil, want: `[&quot;a&quot;, &quot;b&quot;, &quot;c&quot;] [&quot;a&quot;, &quot;b c  &quot;] [&quot;a&quot;, &quot;b&quot;, &quot;&quot;, &quot;c&quot;] [&quot;a&quot;, &quot;b,c&quot;] [&quot;a&quot;, &quot;b-c&quot;] 0`},
+			{name: "lines", source: `{{ "a\nb\r\nc\rd\n\ne\n"|lines|join("|") }}`, context: nil, want: `a|b|c|d||e`},
+			{name: "chain", source: `{% set c = [1, 2]|chain(x, [4]) %}{{ c|length }} {{ c[2] }} {{ c[-1] }} {{ c|join(",") }} {{ c }} {{ [1]|chain("ab", {"k": 1})|join(",") }}`, context: map[string]any{"x": []int{3}}, want: `4 3 4 1,2,3,4 [1, 2, 3, 4] 1,a,b,k`},
+			{name: "zip", source: `{% for a, b in ["x", "y", "z"]|zip([1, 2]) %}{{ a }}{{ b }} {% endfor %}{{ "ab"|zip([1, 2, 3])|list }} {{ [1]|zip([2], [3]) }}`, context: nil, want: `x1 y2 [[&quot;a&quot;, 1], [&quot;b&quot;, 2]] [[1, 2, 3]]`},
+			{name: "flatten", source: `{{ [1, [2, [3, [4]]], "ab", []]|flatten }} {{ [1, [2, [3, [4]]]]|flatten(1) }} {{ [[1]]|flatten(0) }}`, context: nil, want: `[1, 2, 3, 4, &quot;ab&quot;] [1, 2, [3, [4]]] [[1]]`},
+			{name: "takeSkip", source: `{{ [1, 2, 3, 4]|take(2) }} {{ [1, 2]|take(5) }} {{ [1, 2, 3, 4]|skip(3) }} {{ [1]|skip(2) }} {{ "abc"|take(2) }} {{ "abc"|skip(1)|join }} {{ [1, 2, 3]|skip(1)|take(1)|first }}`, context: nil, want: `[1, 2] [1, 2] [4] [] [&quot;a&quot;, &quot;b&quot;] bc 2`},
+			{name: "random", source: `{{ [42]|random }} {{ "a"|random }} {{ []|random is undefined }} {{ [1, 2, 3]|random in [1, 2, 3] }}`, context: nil, want: `42 a true true`},
+			{name: "shuffle", source: `{{ [1, 2, 3, 4]|shuffle|sort }} {{ []|shuffle }}`, context: nil, want: `[1, 2, 3, 4] []`},
+			{name: "tojsonKeepOrder", source: `{{ '{"b": 1, "a": [2, "<x>"]}'|fromjson|tojson(sort_keys=false) }}`, context: nil, want: `{"b":1,"a":[2,"\u003cx\u003e"]}`},
+			{name: "tojsonIndent", source: `{{ {"b": 1, "a": [2]}|tojson(indent=4) }}`, context: nil, want: "{\n    \"a\": [\n        2\n    ],\n    \"b\": 1\n}"},
+			{name: "tojsonPrettyPositional", source: `{{ [1]|tojson(true) }}`, context: nil, want: "[\n  1\n]"},
+			{name: "toyaml", source: `{{ '{"name": "web", "ports": [80, 443], "env": {"DEBUG": "true", "EMPTY": ""}, "tags": [], "items": [{"a": 1, "b": null}, [1.0, "x: y"]]}'|fromjson|toyaml }}`, context: nil, want: "name: web\nports:\n  - 80\n  - 443\nenv:\n  DEBUG: \"true\"\n  EMPTY: \"\"\ntags: []\nitems:\n  - a: 1\n    b: null\n  - - 1.0\n    - \"x: y\""},
+			{name: "toyamlBlockScalar", source: `{{ {"script": "echo a\necho b\n", "note": "a\nb"}|toyaml(indent=4, sort_keys=true) }}`, context: nil, want: "note: |-\n    a\n    b\nscript: |\n    echo a\n    echo b"},
+			{name: "toyamlEscapesHTML", source: `{{ ["<b>", "it's"]|toyaml }}`, context: nil, want: `- "\u003cb\u003e"` + "\n" + `- "it\u0027s"`},
+			{name: "totoml", source: `{{ '{"title": "demo", "server": {"port": 8080, "tls": {"enabled": true}}, "hosts": ["a", "b"], "users": [{"name": "x"}, {"name": "y"}], "my key": 1.5}'|fromjson|totoml }}`, context: nil, want: "title = \"demo\"\nhosts = [\"a\", \"b\"]\n\"my key\" = 1.5\n\n[server]\nport = 8080\n\n[server.tls]\nenabled = true\n\n[[users]]\nname = \"x\"\n\n[[users]]\nname = \"y\""},
+			{name: "fromjson", source: `{% set v = '{"b": [1, 2.5, null], "a": {"c": true}}'|fromjson %}{{ v|list|join(",") }} {{ v.b[1] }} {{ v.b[2] is none }} {{ v.a.c }}`, context: nil, want: "b,a 2.5 true true"},
+			{name: "fromyaml", source: `{% set v = "z: 1\na:\n  - x\n  - {k: v}\n"|fromyaml %}{{ v|list|join(",") }} {{ v.a[0] }} {{ v.a[1].k }}`, context: nil, want: "z,a x v"},
+			{name: "yamlRoundTrip", source: `{{ (data|toyaml|fromyaml) == data }}`, context: map[string]any{"data": map[string]any{"s": "a: b # c", "n": []any{1, -2.5, "0x10", "null", true, "multi\nline\n"}, "m": map[string]any{"quote": `"'`, "empty": []any{}}}}, want: "true"},
 		})
 	})
 	t.Run("test", func(t *testing.T) {
@@ -587,10 +717,26 @@ func TestSingleTemplate(t *testing.T) {
 			{name: "isSequenceFalse", source: `{{ 42 is sequence }}`, context: nil, want: "false"},
 			{name: "isMappingTrue", source: `{{ {"foo": "bar"} is mapping }}`, context: nil, want: "true"},
 			{name: "isMappingFalse", source: `{{ [1, 2, 3] is mapping }}`, context: nil, want: "false"},
+			{name: "isDivisibleByTrue", source: `{{ 21 is divisibleby(7) }} {{ 7.5 is divisibleby(2.5) }} {{ -9 is divisibleby(3) }}`, context: nil, want: "true true true"},
+			{name: "isDivisibleByFalse", source: `{{ 22 is divisibleby(7) }} {{ 1 is divisibleby(0) }} {{ "6" is divisibleby(3) }}`, context: nil, want: "false false false"},
+			{name: "isInteger", source: `{{ 42 is integer }} {{ 170141183460469231731687303715884105727 is integer }} {{ 42.0 is integer }} {{ true is integer }} {{ "1" is integer }}`, context: nil, want: "true true false false false"},
+			{name: "isFloat", source: `{{ 42.0 is float }} {{ 42 is float }} {{ x is float }}`, context: map[string]any{"x": 1.5}, want: "true false true"},
+			{name: "isBoolean", source: `{{ true is boolean }} {{ false is boolean }} {{ 1 is boolean }} {{ none is boolean }}`, context: nil, want: "true true false false"},
+			{name: "isCallable", source: `{% macro m() %}{% endmacro %}{{ range is callable }} {{ m is callable }} {{ 42 is callable }} {{ "range" is callable }}`, context: nil, want: "true true false false"},
+			{name: "isIterable", source: `{{ [1] is iterable }} {{ {"a": 1} is iterable }} {{ "ab" is iterable }} {{ range(2) is iterable }} {{ 42 is iterable }} {{ none is iterable }}`, context: nil, want: "true true true true false true"},
+			{name: "isLower", source: `{{ "foo bar" is lower }} {{ "Foo" is lower }} {{ "123" is lower }} {{ "ß1" is lower }}`, context: nil, want: "true false false true"},
+			{name: "isUpper", source: `{{ "FOO BAR" is upper }} {{ "Foo" is upper }} {{ "123" is upper }}`, context: nil, want: "true false false"},
+			{name: "isSameAs", source: `{% set a = [1] %}{% set m = {"k": 1} %}{{ a is sameas(a) }} {{ a is sameas([1]) }} {{ m is sameas(m) }} {{ m is sameas({"k": 1}) }} {{ none is sameas(none) }} {{ 1 is sameas(1) }} {{ 1 is sameas(1.0) }} {{ true is sameas(1) }}`, context: nil, want: "true false true false true true false false"},
+			{name: "isSameAsObject", source: `{% set c = cycler(1) %}{{ range is sameas(range) }} {{ range is sameas(dict) }} {{ c is sameas(c) }} {{ cycler(1) is sameas(cycler(1)) }}`, context: nil, want: "true false true false"},
+			{name: "isMappingAndString", source: `{{ ["a", {"b": 1}, 1]|select("mapping")|list|length }} {{ ["a", {"b": 1}, 1]|reject("string")|list|length }}`, context: nil, want: "1 2"},
 			{name: "isStartingWithTrue", source: `{{ "foobar" is startingwith("foo") }}`, context: nil, want: "true"},
 			{name: "isStartingWithFalse", source: `{{ "foobar" is startingwith("bar") }}`, context: nil, want: "false"},
 			{name: "isEndingWithTrue", source: `{{ "foobar" is endingwith("bar") }}`, context: nil, want: "true"},
 			{name: "isEndingWithFalse", source: `{{ "foobar" is endingwith("foo") }}`, context: nil, want: "false"},
+			{name: "isMatchingTrue", source: `{{ "PROJ-123" is matching("^[A-Z]+-\\d+$") }}`, context: nil, want: "true"},
+			{name: "isMatchingFalse", source: `{{ "proj-123" is matching("^[A-Z]+-\\d+$") }}`, context: nil, want: "false"},
+			{name: "isMatchingIgnoreCase", source: `{{ "proj-123" is matching("^[A-Z]+-\\d+$", ignorecase=true) }}`, context: nil, want: "true"},
+			{name: "isRegex", source: `{{ ["a1", "b", "c2"]|select("regex", "\\d")|join(",") }}`, context: nil, want: "a1,c2"},
 			{name: "eqTrue", source: `{{ 41 is eq(41) }}`, context: nil, want: "true"},
 			{name: "eqFalse", source: `{{ 41 is eq(42) }}`, context: nil, want: "false"},
 			{name: "equaltoTrue", source: `{{ 41 is equalto(41) }}`, context: nil, want: "true"},
@@ -635,6 +781,10 @@ func TestSingleTemplate(t *testing.T) {
 	})
 	t.Run("function", func(t *testing.T) {
 		runTests(t, []testCase{
+			{name: "cycler", source: `{% set c = cycler("odd", "even") %}{% for x in [1, 2, 3] %}{{ c.next() }} {% endfor %}{{ c.current }} {% for x in [1] %}{{ c.next() }} {% endfor %}{{ c.reset() is none }} {{ c.current }}`, context: nil, want: "odd even odd even even true odd"},
+			{name: "joiner", source: `{% set pipe = joiner("|") %}{% if true %}{{ pipe() }}a{% endif %}{% if false %}{{ pipe() }}b{% endif %}{% if true %}{{ pipe() }}c{% endif %}{% set comma = joiner() %}{% for x in [1, 2] %}{{ comma() }}{{ x }}{% endfor %}`, context: nil, want: "a|c1, 2"},
+			{name: "lipsumHTML", source: `{% set p = lipsum(2, min=5, max=10) %}{{ p is safe }} {{ p|lines|length }} {{ p|lines|select("startingwith", "<p>")|list|length }} {{ p == lipsum(2, true, 5, 10) }}`, context: nil, want: "true 2 2 true"},
+			{name: "lipsumText", source: `{% set p = lipsum(3, html=false, min=3, max=4) %}{{ p is safe }} {{ p|split("\n\n")|length }} {{ p|split|length }} {{ p|split("\n\n")|map("last")|join }}`, context: nil, want: "false 3 9 ..."},
 			{name: "rangeJustUpper", source: "{{ range(3) }}", context: nil, want: "[0, 1, 2]"},
 			{name: "rangeLowerUpper", source: "{{ range(2, 4) }}", context: nil, want: "[2, 3]"},
 			{name: "rangeLowerUpperStep", source: "{{ range(2, 9, 3) }}", context: nil, want: "[2, 5, 8]"},
@@ -685,6 +835,49 @@ func TestErrorSingleTemplate(t *testing.T) {
 			{name: "rangeTooManyArgErr", source: "{{ range(1, 2, 3, 4) }}", context: nil, want: "too many arguments"},
 		})
 	})
+	t.Run("function", func(t *testing.T) {
+		runTests(t, []testCase{
+			{name: "cyclerNoItems", source: `{{ cycler() }}`, context: nil, want: "missing argument"},
+			{name: "cyclerUnknownMethod", source: `{{ cycler(1).foo() }}`, context: nil, want: "unknown method"},
+			{name: "joinerArgs", source: `{{ joiner()(1) }}`, context: nil, want: "too many arguments"},
+			{name: "lipsumMinMax", source: `{{ lipsum(min=5, max=5) }}`, context: nil, want: "invalid operation"},
+			{name: "lipsumTooLong", source: `{{ lipsum(100000) }}`, context: nil, want: "invalid operation"},
+		})
+	})
+	t.Run("filter", func(t *testing.T) {
+		runTests(t, []testCase{
+			{name: "truncateUnknownKwarg", source: `{{ "a"|truncate(foo=1) }}`, context: nil, want: "too many arguments"},
+			{name: "truncateDuplicateArg", source: `{{ "a"|truncate(3, length=1) }}`, context: nil, want: "too many arguments"},
+			{name: "truncateTooShort", source: `{{ "abcdefghij"|truncate(2, leeway=0) }}`, context: nil, want: "invalid operation"},
+			{name: "intOutOfRange", source: `{{ "999999999999999999999999999999999999999999"|int }}`, context: nil, want: "invalid operation"},
+			{name: "intInvalidBase", source: `{{ "1"|int(base=1) }}`, context: nil, want: "invalid operation"},
+			{name: "sumOverflow", source: `{{ [170141183460469231731687303715884105727, 1]|sum }}`, context: nil, want: "invalid operation"},
+			{name: "sumNotIterable", source: `{{ 1|sum }}`, context: nil, want: "invalid operation"},
+			{name: "groupbyNoAttribute", source: `{{ [1]|groupby }}`, context: nil, want: "missing argument"},
+			{name: "wordwrapZeroWidth", source: `{{ "a"|wordwrap(0) }}`, context: nil, want: "invalid operation"},
+			{name: "regexInvalidPattern", source: `{{ "a"|regex_search("(") }}`, context: nil, want: "invalid operation"},
+			{name: "regexReplaceNegativeCount", source: `{{ "a"|regex_replace("a", "b", count=-1) }}`, context: nil, want: "invalid operation"},
+			{name: "regexUnknownKwarg", source: `{{ "a" is matching("a", foo=true) }}`, context: nil, want: "too many arguments"},
+			{name: "datetimeformatNotDate", source: `{{ [1]|datetimeformat }}`, context: nil, want: "invalid operation"},
+			{name: "datetimeformatBadString", source: `{{ "yesterday"|datetimeformat }}`, context: nil, want: "invalid operation"},
+			{name: "datetimeformatBadDirective", source: `{{ 0|datetimeformat("%Q") }}`, context: nil, want: "invalid operation"},
+			{name: "datetimeformatUnknownTimezone", source: `{{ 0|datetimeformat(tz="Nowhere/Atlantis") }}`, context: nil, want: "invalid operation"},
+			{name: "b64decodeInvalid", source: `{{ "!!"|b64decode }}`, context: nil, want: "invalid operation"},
+			{name: "sha256NotString", source: `{{ 42|sha256 }}`, context: nil, want: "invalid operation"},
+			{name: "urldecodeInvalid", source: `{{ "%zz"|urldecode }}`, context: nil, want: "invalid operation"},
+			{name: "xmlattrInvalidName", source: `{{ {"a b": 1}|xmlattr }}`, context: nil, want: "invalid operation"},
+			{name: "xmlattrNotMap", source: `{{ [1]|xmlattr }}`, context: nil, want: "invalid operation"},
+			{name: "filesizeformatInvalid", source: `{{ "abc"|filesizeformat }}`, context: nil, want: "invalid operation"},
+			{name: "filesizeformatNotNumber", source: `{{ [1]|filesizeformat }}`, context: nil, want: "invalid operation"},
+			{name: "urlizeUnknownArg", source: `{{ "a"|urlize(foo=1) }}`, context: nil, want: "too many arguments"},
+			{name: "splitEmptySep", source: `{{ "abc"|split("") }}`, context: nil, want: "invalid operation"},
+			{name: "chainNotIterable", source: `{{ [1]|chain(1) }}`, context: nil, want: "invalid operation"},
+			{name: "fromjsonInvalid", source: `{{ '{"a": 1} x'|fromjson }}`, context: nil, want: "invalid operation"},
+			{name: "fromyamlInvalid", source: `{{ "a: 1\n  b: 2"|fromyaml }}`, context: nil, want: "invalid operation"},
+			{name: "totomlNotMap", source: `{{ [1]|totoml }}`, context: nil, want: "invalid operation"},
+			{name: "totomlNone", source: `{{ {"a": none}|totoml }}`, context: nil, want: "invalid operation"},
+		})
+	})
 }
 
 func TestMultiTemplates(t *testing.T) {
@@ -990,3 +1183,831 @@ func TestEnvironment_AddFilter_withStateArg(t *testing.T) {
 		t.Errorf("result mismatch, got=%q,\nwant=%q", got, want)
 	}
 }
+
+func TestEnvironment_AddCustomTag(t *testing.T) {
+	spaceless := func(_ *mjingo.State, _ []mjingo.Value, body mjingo.CustomTagBody) (mjingo.Value, error) {
+		v, err := body()
+		if err != nil {
+			return mjingo.Value{}, err
+		}
+		return mjingo.ValueFromSafeString(strings.Join(strings.Fields(v.String()), " ")), nil
+	}
+	repeat := func(state *mjingo.State, args []mjingo.Value, body mjingo.CustomTagBody) (mjingo.Value, error) {
+		n, args, err := mjingo.ConvertArgToGoValue[int](state, args)
+		if err != nil {
+			return mjingo.Value{}, err
+		}
+		sep, _, err := mjingo.ConvertArgToGoValue[mjingo.Kwargs](state, args)
+		if err != nil {
+			return mjingo.Value{}, err
+		}
+		sepStr := ""
+		if v := (mjingo.Value{}); sep.GetValue("sep").UnwrapTo(&v) {
+			sepStr = v.String()
+		}
+		if err := sep.AssertAllUsed(); err != nil {
+			return mjingo.Value{}, err
+		}
+		var b strings.Builder
+		for i := 0; i < n; i++ {
+			if i > 0 {
+				b.WriteString(sepStr)
+			}
+			v, err := body()
+			if err != nil {
+				return mjingo.Value{}, err
+			}
+			b.WriteString(v.String())
+		}
+		return mjingo.ValueFromSafeString(b.String()), nil
+	}
+	greet := func(_ *mjingo.State, args []mjingo.Value, _ mjingo.CustomTagBody) (mjingo.Value, error) {
+		return mjingo.ValueFromGoValue("Hello " + args[0].String()), nil
+	}
+
+	testCases := []struct {
+		name    string
+		source  string
+		context any
+		want    string
+	}{
+		{name: "spaceless", source: "{% spaceless %}  <p>\n  {{ v }}  </p>\n{% endspaceless %}", context: map[string]string{"v": "a&b"}, want: "<p> a&amp;b </p>"},
+		{name: "argsAndKwargs", source: `{% repeat n + 1, sep="," %}{{ x }}{% endrepeat %}`, context: map[string]int{"n": 2, "x": 7}, want: "7,7,7"},
+		{name: "bodyNotRendered", source: `[{% repeat 0 %}{{ undefined_fn() }}{% endrepeat %}]`, context: nil, want: "[]"},
+		{name: "nested", source: `{% repeat 2 %}{% spaceless %} a  b {% endspaceless %}{% endrepeat %}`, context: nil, want: "a ba b"},
+		{name: "noBody", source: `{% greet "<World>" %}!`, context: nil, want: "Hello &lt;World&gt;!"},
+		{name: "inLoop", source: `{% for i in [1, 2] %}{% repeat i %}{{ i }}{% endrepeat %}{% endfor %}`, context: nil, want: "122"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := mjingo.NewEnvironment()
+			env.AddCustomTag("spaceless", "endspaceless", spaceless)
+			env.AddCustomTag("repeat", "endrepeat", repeat)
+			env.AddCustomTag("greet", "", greet)
+			got, err := env.RenderNamedStr("test.html", tc.source, mjingo.ValueFromGoValue(tc.context))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("result mismatch, source=%s,\n got=%q,\nwant=%q", tc.source, got, tc.want)
+			}
+		})
+	}
+
+	t.Run("unknown", func(t *testing.T) {
+		env := mjingo.NewEnvironment()
+		env.AddCustomTag("spaceless", "endspaceless", spaceless)
+		env.RemoveCustomTag("spaceless")
+		if _, err := env.RenderStr(`{% spaceless %}{% endspaceless %}`, mjingo.Undefined); err == nil {
+			t.Error("expected an error for a removed custom tag")
+		}
+	})
+}
+
+func TestEnvironment_SetSyntax(t *testing.T) {
+	lineSyntax := mjingo.DefaultSyntax
+	lineSyntax.LineStatementPrefix = "#"
+	lineSyntax.LineCommentPrefix = "##"
+
+	customSyntax := mjingo.Syntax{
+		BlockStart:    "<%",
+		BlockEnd:      "%>",
+		VariableStart: "${",
+		VariableEnd:   "}",
+		CommentStart:  "<#",
+		CommentEnd:    "#>",
+	}
+
+	testCases := []struct {
+		name    string
+		syntax  mjingo.Syntax
+		source  string
+		context any
+		want    string
+	}{
+		{
+			name:    "lineStatement",
+			syntax:  lineSyntax,
+			source:  "<ul>\n# for item in items\n  <li>{{ item }}</li>\n# endfor\n</ul>",
+			context: map[string][]string{"items": {"a", "b"}},
+			want:    "<ul>\n  <li>a</li>\n  <li>b</li>\n</ul>",
+		},
+		{
+			name:    "lineStatementIndentedWithColon",
+			syntax:  lineSyntax,
+			source:  "  # if x:\nyes\n  # else:\nno\n  # endif",
+			context: map[string]bool{"x": false},
+			want:    "no\n",
+		},
+		{
+			name:    "lineComment",
+			syntax:  lineSyntax,
+			source:  "a\n## this is a comment\nb {# block comment #}\n  ## indented comment\nc",
+			context: nil,
+			want:    "a\nb \nc",
+		},
+		{
+			name:    "prefixNotAtLineStart",
+			syntax:  lineSyntax,
+			source:  "Issue #{{ n }} ## not a comment",
+			context: map[string]int{"n": 1},
+			want:    "Issue #1 ## not a comment",
+		},
+		{
+			name:    "mixedWithBlocks",
+			syntax:  lineSyntax,
+			source:  "{% set x = 2 %}\n# for i in range(x)\n{{ i }}\n# endfor",
+			context: nil,
+			want:    "\n0\n1\n",
+		},
+		{
+			name:    "customDelimiters",
+			syntax:  customSyntax,
+			source:  "<% for i in [1, 2] %>${ i }<#comment#><% endfor %> {{ x }}",
+			context: nil,
+			want:    "12 {{ x }}",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := mjingo.NewEnvironment()
+			if err := env.SetSyntax(tc.syntax); err != nil {
+				t.Fatal(err)
+			}
+			got, err := env.RenderStr(tc.source, mjingo.ValueFromGoValue(tc.context))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("result mismatch, source=%q,\n got=%q,\nwant=%q", tc.source, got, tc.want)
+			}
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		env := mjingo.NewEnvironment()
+		syntax := mjingo.DefaultSyntax
+		syntax.BlockStart = syntax.VariableStart
+		if err := env.SetSyntax(syntax); err == nil {
+			t.Error("expected an error for duplicated start delimiters")
+		}
+	})
+}
+
+func TestEnvironment_SetTranslator(t *testing.T) {
+	const po = `msgid ""
+msgstr ""
+"Plural-Forms: nplurals=2; plural=(n != 1);\n"
+
+msgid "Hello %(user)s!"
+msgstr "Hallo %(user)s!"
+
+msgid "%(num)s apple"
+msgid_plural "%(num)s apples"
+msgstr[0] "%(num)s Apfel"
+msgstr[1] "%(num)s Äpfel"
+
+msgctxt "month"
+msgid "May"
+msgstr "Mai"
+
+#, fuzzy
+msgid "Goodbye"
+msgstr "Tschüss"
+`
+	catalog, err := mjingo.ParsePOCatalog(strings.NewReader(po))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name    string
+		source  string
+		context any
+		want    string
+	}{
+		{
+			name:    "trans",
+			source:  "{% trans %}Hello {{ user }}!{% endtrans %}",
+			context: map[string]string{"user": "<Bob>"},
+			want:    "Hallo &lt;Bob&gt;!",
+		},
+		{
+			name:    "transWithVars",
+			source:  "{% trans user=name|upper %}Hello {{ user }}!{% endtrans %}",
+			context: map[string]string{"name": "bob"},
+			want:    "Hallo BOB!",
+		},
+		{
+			name:    "transPlural",
+			source:  "{% for n in [1, 3] %}{% trans num=n %}{{ num }} apple{% pluralize %}{{ num }} apples{% endtrans %}|{% endfor %}",
+			context: nil,
+			want:    "1 Apfel|3 Äpfel|",
+		},
+		{
+			name:    "transPluralizeName",
+			source:  "{% trans num=n, x=1 %}{{ num }} apple{% pluralize num %}{{ num }} apples{% endtrans %}",
+			context: map[string]int{"n": 2},
+			want:    "2 Äpfel",
+		},
+		{
+			name:    "transContext",
+			source:  `{% trans "month" %}May{% endtrans %} {% trans %}May{% endtrans %}`,
+			context: nil,
+			want:    "Mai May",
+		},
+		{
+			name:    "transTrimmed",
+			source:  "{% trans trimmed %}\n  Hello\n    {{ user }}!\n{% endtrans %}",
+			context: map[string]string{"user": "Bob"},
+			want:    "Hallo Bob!",
+		},
+		{
+			name:    "transPercent",
+			source:  "{% trans %}100% sure{% endtrans %}",
+			context: nil,
+			want:    "100% sure",
+		},
+		{
+			name:    "transUntranslated",
+			source:  "{% trans %}Goodbye{% endtrans %}",
+			context: nil,
+			want:    "Goodbye",
+		},
+		{
+			name:    "gettextFunctions",
+			source:  `{{ _("Hello %(user)s!", user="<b>") }} {{ ngettext("%(num)s apple", "%(num)s apples", 5) }} {{ pgettext("month", "May") }}`,
+			context: nil,
+			want:    "Hallo &lt;b&gt;! 5 Äpfel Mai",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := mjingo.NewEnvironment()
+			env.SetTranslator(catalog)
+			if err := env.AddTemplate("test.html", tc.source); err != nil {
+				t.Fatal(err)
+			}
+			tpl, err := env.GetTemplate("test.html")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := tpl.Render(mjingo.ValueFromGoValue(tc.context))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("result mismatch, source=%q,\n got=%q,\nwant=%q", tc.source, got, tc.want)
+			}
+		})
+	}
+
+	t.Run("noTranslator", func(t *testing.T) {
+		env := mjingo.NewEnvironment()
+		got, err := env.RenderStr("{% trans count=2 %}{{ count }} item{% pluralize %}{{ count }} items{% endtrans %}", mjingo.Undefined)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "2 items"; got != want {
+			t.Errorf("result mismatch, got=%q, want=%q", got, want)
+		}
+	})
+
+	t.Run("syntaxError", func(t *testing.T) {
+		for _, source := range []string{
+			"{% trans %}{% if x %}{% endif %}{% endtrans %}",
+			"{% trans %}{{ x.y }}{% endtrans %}",
+			"{% trans %}a{% pluralize %}b{% endtrans %}",
+			"{% trans x=1 %}a{% pluralize y %}b{% endtrans %}",
+			"{% trans x, x %}{% endtrans %}",
+			"{% trans %}a",
+		} {
+			env := mjingo.NewEnvironment()
+			if err := env.AddTemplate("test.html", source); err == nil {
+				t.Errorf("expected a syntax error for %q", source)
+			}
+		}
+	})
+}
+
+func TestEnvironment_SetClock(t *testing.T) {
+	env := mjingo.NewEnvironment()
+	env.SetClock(func() time.Time { return time.Date(2024, 2, 9, 8, 5, 3, 0, time.UTC) })
+	got, err := env.RenderStr(`{{ now().year }} {{ now()|datetimeformat("short") }} {{ now(tz="Asia/Tokyo")|timeformat("short") }} {{ t|timesince }}`,
+		mjingo.ValueFromGoValue(map[string]any{"t": time.Date(2024, 2, 8, 5, 0, 0, 0, time.UTC)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "2024 2024-02-09 08:05 17:05 1 day, 3 hours"; got != want {
+		t.Errorf("result mismatch, got=%q, want=%q", got, want)
+	}
+}
+
+func TestAutoEscapeJSON(t *testing.T) {
+	env := mjingo.NewEnvironment()
+	if err := env.AddTemplate("config.yaml", "name: {{ name }}\nports: {{ ports }}\nextra: {{ extra }}"); err != nil {
+		t.Fatal(err)
+	}
+	tpl, err := env.GetTemplate("config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := tpl.Render(mjingo.ValueFromGoValue(map[string]any{
+		"name":  "it's <web>",
+		"ports": []any{80, 443},
+		"extra": mjingo.ValueFromSafeString("raw"),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `name: "it\u0027s \u003cweb\u003e"` + "\nports: [80,443]\nextra: raw"; got != want {
+		t.Errorf("result mismatch, got=%q, want=%q", got, want)
+	}
+}
+
+func TestEnvironment_SetRandomSeed(t *testing.T) {
+	render := func(seed int64) string {
+		env := mjingo.NewEnvironment()
+		env.SetRandomSeed(seed)
+		got, err := env.RenderStr(`{{ range(20)|shuffle|join(",") }} {{ range(100)|random }}`, mjingo.Undefined)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+	if got1, got2 := render(1), render(1); got1 != got2 {
+		t.Errorf("results differ for the same seed, got1=%q, got2=%q", got1, got2)
+	}
+	if got1, got2 := render(1), render(2); got1 == got2 {
+		t.Errorf("results are the same for different seeds, got=%q", got1)
+	}
+}
+
+func TestState_KnownVariables(t *testing.T) {
+	env := mjingo.NewEnvironmentEmpty()
+	env.AddGlobal("site", mjingo.ValueFromGoValue("example"))
+	var got []string
+	var lookedUp []string
+	env.AddFunction("inspect", func(state *mjingo.State, _ []mjingo.Value) (mjingo.Value, error) {
+		got = state.KnownVariables()
+		for _, name := range []string{"x", "item", "user", "site", "missing"} {
+			if val := state.Lookup(name); val.IsSome() {
+				lookedUp = append(lookedUp, name+"="+val.Unwrap().String())
+			}
+		}
+		return mjingo.Undefined, nil
+	})
+	if err := env.AddTemplate("t.txt", `{% set x = 1 %}{% for item in [2] %}{{ inspect() }}{% endfor %}`); err != nil {
+		t.Fatal(err)
+	}
+	tpl, err := env.GetTemplate("t.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tpl.Render(mjingo.ValueFromGoValue(map[string]any{"user": "alice"})); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"inspect", "item", "loop", "site", "user", "x"}; !slices.Equal(got, want) {
+		t.Errorf("known variables mismatch, got=%v, want=%v", got, want)
+	}
+	if want := []string{"x=1", "item=2", "user=alice", "site=example"}; !slices.Equal(lookedUp, want) {
+		t.Errorf("lookup result mismatch, got=%v, want=%v", lookedUp, want)
+	}
+}
+
+func TestDebugFunction(t *testing.T) {
+	env := mjingo.NewEnvironment()
+	if err := env.AddTemplate("t.txt", `{% set x = [1] %}{% for item in items %}{{ debug() }}{% endfor %}`); err != nil {
+		t.Fatal(err)
+	}
+	tpl, err := env.GetTemplate("t.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := tpl.Render(mjingo.ValueFromGoValue(map[string]any{"items": []string{"a"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"State {\n    name: \"t.txt\",\n    current_block: none,\n    auto_escape: None,\n",
+		"    ctx: {\n        \"items\": [\n            \"a\",\n        ],\n    },\n",
+		"    locals: {\n        \"item\": \"a\",\n        \"loop\": Loop {\n            index0: 0,\n",
+		"        \"x\": [\n            1,\n        ],\n    },\n",
+		"        \"range\": <function range>,\n",
+		"        filters: [\n            \"abs\",\n",
+		"        tests: [\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("debug output does not contain %q, got:\n%s", want, got)
+		}
+	}
+
+	got, err = env.RenderStr(`{{ debug(1) }} {{ debug([1], "a") }}`, mjingo.Undefined)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "1 [\n    [\n        1,\n    ],\n    \"a\",\n]"; got != want {
+		t.Errorf("result mismatch, got=%q, want=%q", got, want)
+	}
+}
+
+type marshalerTestID [4]byte
+
+func (id marshalerTestID) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%x", id[:])), nil
+}
+
+type marshalerTestMoney struct {
+	cents    int64
+	currency string
+}
+
+func (m *marshalerTestMoney) String() string {
+	return fmt.Sprintf("%d.%02d %s", m.cents/100, m.cents%100, m.currency)
+}
+
+type marshalerTestPoint struct {
+	x, y int
+}
+
+func (p marshalerTestPoint) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`{"x": %d, "y": %d}`, p.x, p.y)), nil
+}
+
+type marshalerTestTemperature float64
+
+func TestValueFromGoValue_Marshalers(t *testing.T) {
+	context := map[string]any{
+		"id":    marshalerTestID{0xde, 0xad, 0xbe, 0xef},
+		"price": marshalerTestMoney{cents: 1234, currency: "USD"},
+		"point": &marshalerTestPoint{x: 1, y: 2},
+		"temp":  marshalerTestTemperature(21.5),
+	}
+	const source = `{{ id }}|{{ price }}|{{ point.x }},{{ point.y }}|{{ temp }}`
+	env := mjingo.NewEnvironment()
+
+	got, err := env.RenderStr(source, mjingo.ValueFromGoValue(context,
+		mjingo.WithMarshalers(),
+		mjingo.WithConverter(reflect.TypeOf(marshalerTestTemperature(0)), func(v any) mjingo.Value {
+			return mjingo.ValueFromString(fmt.Sprintf("%.1f°C", float64(v.(marshalerTestTemperature))))
+		})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "deadbeef|12.34 USD|1,2|21.5°C"; got != want {
+		t.Errorf("result mismatch, got=%q, want=%q", got, want)
+	}
+
+	got, err = env.RenderStr(`{{ id|length }}|{{ point.x }}`, mjingo.ValueFromGoValue(context))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "4|"; got != want {
+		t.Errorf("result mismatch without marshalers, got=%q, want=%q", got, want)
+	}
+}
+
+type methodTestUser struct {
+	First   string
+	Last    string
+	Manager *methodTestUser
+	calls   int
+}
+
+func (u methodTestUser) FullName() string { return u.First + " " + u.Last }
+
+func (u methodTestUser) Greet(greeting string, names ...string) string {
+	return greeting + " " + strings.Join(names, ", ") + " from " + u.First
+}
+
+func (u *methodTestUser) Count() int {
+	u.calls++
+	return u.calls
+}
+
+func (u methodTestUser) Check(ok bool) (string, error) {
+	if !ok {
+		return "", errors.New("check failed")
+	}
+	return "ok", nil
+}
+
+func (u methodTestUser) Secret() string { return "s3cr3t" }
+
+func (u methodTestUser) Nil() *methodTestUser { return nil }
+
+func TestValueFromGoValue_Methods(t *testing.T) {
+	env := mjingo.NewEnvironment()
+	user := &methodTestUser{First: "John", Last: "Doe"}
+	render := func(source string, opts ...mjingo.ValueFromGoValueOption) (string, error) {
+		if len(opts) == 0 {
+			opts = []mjingo.ValueFromGoValueOption{mjingo.WithMethods()}
+		}
+		return env.RenderStr(source, mjingo.ValueFromGoValue(map[string]any{"user": user}, opts...))
+	}
+
+	got, err := render(`{{ user.FullName() }}|{{ user.Greet("Hi", "Ann", "Bob") }}|{{ user.Count() }}{{ user.Count() }}|{{ user.Check(true) }}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "John Doe|Hi Ann, Bob from John|12|ok"; got != want {
+		t.Errorf("result mismatch, got=%q, want=%q", got, want)
+	}
+
+	got, err = env.RenderStr(`{{ user.FullName() }}`, mjingo.ValueFromGoValue(map[string]any{"user": *user}, mjingo.WithMethods()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "John Doe"; got != want {
+		t.Errorf("result mismatch for non-pointer, got=%q, want=%q", got, want)
+	}
+
+	got, err = render(`{{ user.Nil() is none }}|{{ user.Nil() }}|{{ user.Manager is none }}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "true|none|true"; got != want {
+		t.Errorf("result mismatch for nil pointers, got=%q, want=%q", got, want)
+	}
+
+	errTestCases := []struct {
+		name   string
+		source string
+		user   any
+		opts   []mjingo.ValueFromGoValueOption
+		want   string
+	}{
+		{
+			name:   "notEnabled",
+			source: `{{ user.FullName() }}`,
+			opts:   []mjingo.ValueFromGoValueOption{}, // no WithMethods
+
+			want: "unknown method: mjingo_test.methodTestUser has no method named FullName",
+		},
+		{
+			name:   "pointerReceiverOnValue",
+			source: `{{ user.Count() }}`,
+			user:   *user,
+			want:   "unknown method: mjingo_test.methodTestUser has no method named Count",
+		},
+		{
+			name:   "error",
+			source: `{{ user.Check(false) }}`,
+			want:   "check failed",
+		},
+		{
+			name:   "badArgument",
+			source: `{{ user.Check("yes") }}`,
+			want:   "invalid operation: cannot convert string to bool",
+		},
+		{
+			name:   "denied",
+			source: `{{ user.Secret() }}`,
+			opts:   []mjingo.ValueFromGoValueOption{mjingo.WithMethods(), mjingo.WithDeniedMethods("Secret")},
+			want:   "unknown method: mjingo_test.methodTestUser has no method named Secret",
+		},
+		{
+			name:   "notAllowed",
+			source: `{{ user.Secret() }}`,
+			opts:   []mjingo.ValueFromGoValueOption{mjingo.WithMethods(), mjingo.WithAllowedMethods("FullName")},
+			want:   "unknown method: mjingo_test.methodTestUser has no method named Secret",
+		},
+	}
+	for _, tc := range errTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := map[string]any{"user": tc.user}
+			if tc.user == nil {
+				ctx["user"] = user
+			}
+			opts := tc.opts
+			if opts == nil {
+				opts = []mjingo.ValueFromGoValueOption{mjingo.WithMethods()}
+			}
+			_, err := env.RenderStr(tc.source, mjingo.ValueFromGoValue(ctx, opts...))
+			if err == nil {
+				t.Fatal("should fail")
+			}
+			if got := err.Error(); !strings.HasPrefix(got, tc.want) {
+				t.Errorf("error mismatch,\n got=%s,\nwant=%s", got, tc.want)
+			}
+		})
+	}
+
+	got, err = render(`{{ user.FullName() }}`, mjingo.WithAllowedMethods("FullName"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "John Doe"; got != want {
+		t.Errorf("result mismatch with allowed methods, got=%q, want=%q", got, want)
+	}
+}
+
+// squaresMapObject maps the integers from 1 to n to their squares.
+type squaresMapObject struct {
+	n int64
+}
+
+func (*squaresMapObject) Kind() mjingo.ObjectKind { return mjingo.ObjectKindMap }
+
+func (m *squaresMapObject) GetValue(key mjingo.Value) option.Option[mjingo.Value] {
+	var n int64
+	if !key.AsInt64().UnwrapTo(&n) || n < 1 || n > m.n {
+		return option.None[mjingo.Value]()
+	}
+	return option.Some(mjingo.ValueFromInt(n * n))
+}
+
+func (m *squaresMapObject) Keys() []mjingo.Value {
+	keys := make([]mjingo.Value, 0, m.n)
+	for i := m.n; i >= 1; i-- {
+		keys = append(keys, mjingo.ValueFromInt(i))
+	}
+	return keys
+}
+
+func (m *squaresMapObject) Len() uint { return uint(m.n) }
+
+// upperMapObject maps the keys to their upper case.
+type upperMapObject struct {
+	keys []string
+}
+
+func (*upperMapObject) Kind() mjingo.ObjectKind { return mjingo.ObjectKindMap }
+
+func (m *upperMapObject) GetValue(key mjingo.Value) option.Option[mjingo.Value] {
+	var s string
+	if !key.AsString().UnwrapTo(&s) || !slices.Contains(m.keys, s) {
+		return option.None[mjingo.Value]()
+	}
+	return option.Some(mjingo.ValueFromString(strings.ToUpper(s)))
+}
+
+func (m *upperMapObject) Keys() []mjingo.Value {
+	keys := make([]mjingo.Value, 0, len(m.keys))
+	for _, key := range m.keys {
+		keys = append(keys, mjingo.ValueFromString(key))
+	}
+	return keys
+}
+
+func (m *upperMapObject) Len() uint { return uint(len(m.keys)) }
+
+func TestMapObject(t *testing.T) {
+	context := mjingo.ValueFromMap(map[string]mjingo.Value{
+		"squares": mjingo.ValueFromObject(&squaresMapObject{n: 3}),
+		"upper":   mjingo.ValueFromObject(&upperMapObject{keys: []string{"b", "a"}}),
+	})
+	testCases := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{name: "item", source: `{{ squares[2] }}|{{ squares[4] is undefined }}`, want: "4|true"},
+		{name: "attr", source: `{{ upper.a }}|{{ upper.c is undefined }}`, want: "A|true"},
+		{name: "iter", source: `{% for k in squares %}{{ k }}={{ squares[k] }} {% endfor %}`, want: "3=9 2=4 1=1 "},
+		{name: "items", source: `{% for k, v in upper|items %}{{ k }}{{ v }}{% endfor %}`, want: "bBaA"},
+		{name: "dictsort", source: `{{ squares|dictsort }}`, want: "[[1, 1], [2, 4], [3, 9]]"},
+		{name: "length", source: `{{ squares|length }}|{{ squares is mapping }}|{{ squares is true }}`, want: "3|true|false"},
+		{name: "in", source: `{{ 2 in squares }}|{{ 5 in squares }}|{{ "a" in upper }}`, want: "true|false|true"},
+		{name: "tojson", source: `{{ upper|tojson }}`, want: `{"a":"A","b":"B"}`},
+		{name: "print", source: `{{ squares }}`, want: "{3: 9, 2: 4, 1: 1}"},
+		{name: "dict", source: `{{ dict(upper) }}`, want: `{"b": "B", "a": "A"}`},
+		{name: "equal", source: `{{ upper == {"a": "A", "b": "B"} }}`, want: "true"},
+	}
+	env := mjingo.NewEnvironment()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := env.RenderStr(tc.source, context)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("result mismatch, got=%q, want=%q", got, tc.want)
+			}
+		})
+	}
+}
+
+// countingIterableObject yields the integers from 1 to n and counts how
+// many items were pulled.
+type countingIterableObject struct {
+	n      int64
+	pulled int
+}
+
+func (*countingIterableObject) Kind() mjingo.ObjectKind { return mjingo.ObjectKindIterable }
+
+func (o *countingIterableObject) Iterate() func() option.Option[mjingo.Value] {
+	var i int64
+	return func() option.Option[mjingo.Value] {
+		if i >= o.n {
+			return option.None[mjingo.Value]()
+		}
+		i++
+		o.pulled++
+		return option.Some(mjingo.ValueFromInt(i))
+	}
+}
+
+func TestIterableObject(t *testing.T) {
+	testCases := []struct {
+		name       string
+		source     string
+		want       string
+		wantPulled int
+	}{
+		{
+			name:       "for",
+			source:     `{% for x in it %}{{ loop.index }}:{{ x }}{{ "!" if loop.last }}{{ loop.length is undefined }}{{ loop.revindex is undefined }} {% endfor %}`,
+			want:       "1:1truetrue 2:2truetrue 3:3!truetrue ",
+			wantPulled: 3,
+		},
+		{name: "first", source: `{{ it|first }}`, want: "1", wantPulled: 1},
+		{name: "last", source: `{{ it|last }}`, want: "3", wantPulled: 3},
+		{name: "join", source: `{{ it|join(",") }}`, want: "1,2,3", wantPulled: 3},
+		{name: "map", source: `{{ it|map("string")|join("-") }}`, want: "1-2-3", wantPulled: 3},
+		{name: "select", source: `{{ it|select("odd")|list }}`, want: "[1, 3]", wantPulled: 3},
+		{name: "in", source: `{{ 2 in it }}|{{ 5 in it }}|{{ 5 not in it }}`, want: "true|false|true", wantPulled: 8},
+		{name: "reverse", source: `{{ it|reverse }}|{{ it|sort(reverse=true) }}`, want: "[3, 2, 1]|[3, 2, 1]", wantPulled: 6},
+		{name: "mapFirst", source: `{{ it|map("abs")|first }}`, want: "1", wantPulled: 1},
+		{name: "selectFirst", source: `{{ it|select("odd")|first }}`, want: "1", wantPulled: 1},
+		{name: "rejectFirst", source: `{{ it|reject("odd")|first }}`, want: "2", wantPulled: 2},
+		{name: "mapAttributeFirst", source: `{{ it|map(attribute="x", default=0)|first }}`, want: "0", wantPulled: 1},
+		{name: "print", source: `{{ it }}|{{ it|tojson }}`, want: "[1, 2, 3]|[1,2,3]", wantPulled: 6},
+		{name: "tests", source: `{{ it is sequence }}|{{ it is iterable }}|{{ "yes" if it }}`, want: "true|true|yes"},
+	}
+	env := mjingo.NewEnvironment()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			obj := &countingIterableObject{n: 3}
+			got, err := env.RenderStr(tc.source, mjingo.ValueFromMap(map[string]mjingo.Value{
+				"it": mjingo.ValueFromObject(obj),
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("result mismatch, got=%q, want=%q", got, tc.want)
+			}
+			if obj.pulled != tc.wantPulled {
+				t.Errorf("pulled item count mismatch, got=%d, want=%d", obj.pulled, tc.wantPulled)
+			}
+		})
+	}
+	t.Run("lazyError", func(t *testing.T) {
+		for _, source := range []string{
+			`{{ it|select("divisibleby")|first }}`,
+			`{% for x in it|map("datetimeformat", "%Q") %}{{ x }}{% endfor %}`,
+			`{{ it|map("datetimeformat", "%Q")|join(",") }}`,
+			`{{ it|map("datetimeformat", "%Q")|list }}`,
+			`{{ it|map("datetimeformat", "%Q")|reverse }}`,
+			`{{ 1 in it|map("datetimeformat", "%Q") }}`,
+			`{{ it|reject("divisibleby")|last }}`,
+			`{{ it|reject("divisibleby")|sort }}`,
+			`{{ it|map("datetimeformat", "%Q")|map("upper")|list }}`,
+			`{{ it|map("datetimeformat", "%Q") }}`,
+			`{{ it|map("datetimeformat", "%Q")|string }}`,
+			`{{ it|map("datetimeformat", "%Q")|tojson }}`,
+		} {
+			_, err := env.RenderStr(source, mjingo.ValueFromMap(map[string]mjingo.Value{
+				"it": mjingo.ValueFromObject(&countingIterableObject{n: 3}),
+			}))
+			if err == nil {
+				t.Errorf("should fail for %s", source)
+			}
+		}
+	})
+}
+
+// stringerStructObject is a struct object which also implements fmt.Stringer.
+type stringerStructObject struct{}
+
+func (stringerStructObject) Kind() mjingo.ObjectKind { return mjingo.ObjectKindStruct }
+
+func (stringerStructObject) StaticFields() option.Option[[]string] {
+	return option.Some([]string{"a"})
+}
+
+func (stringerStructObject) Fields() []string { return nil }
+
+func (stringerStructObject) GetField(name string) option.Option[mjingo.Value] {
+	if name == "a" {
+		return option.Some(mjingo.ValueFromInt(1))
+	}
+	return option.None[mjingo.Value]()
+}
+
+func (stringerStructObject) String() string { return "stringer" }
+
+func TestStructObjectIgnoresStringer(t *testing.T) {
+	env := mjingo.NewEnvironment()
+	got, err := env.RenderStr(`{{ obj }}|{{ obj|string }}`, mjingo.ValueFromMap(map[string]mjingo.Value{
+		"obj": mjingo.ValueFromObject(stringerStructObject{}),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{a: 1}|{a: 1}`; got != want {
+		t.Errorf("result mismatch, got=%q, want=%q", got, want)
+	}
+}
@@ -74,8 +74,8 @@ const (
 	UndefinedError ErrorKind = 13
 	// BadSerialization represents not able to serialize this
 	BadSerialization ErrorKind = 14
-	// Not able to deserialize this
-	cannotDeserialize ErrorKind = 15
+	// CannotDeserialize represents a value could not be decoded into a Go value.
+	CannotDeserialize ErrorKind = 15
 	// BadInclude represents an error happened in an include.
 	BadInclude ErrorKind = 16
 	// EvalBlock represents an error happened in a super block.
@@ -130,7 +130,7 @@ func (k ErrorKind) String() string {
 		return "cannot unpack"
 	case writeFailure:
 		return "failed to write output"
-	case cannotDeserialize:
+	case CannotDeserialize:
 		return "cannot deserialize"
 	case outOfFuel:
 		return "engine ran out of fuel"
@@ -181,7 +181,7 @@ func (k ErrorKind) debugString() string {
 		return "CannotUnpack"
 	case writeFailure:
 		return "WriteFailure"
-	case cannotDeserialize:
+	case CannotDeserialize:
 		return "CannotDeserialize"
 	case outOfFuel:
 		return "OutOfFuel"
@@ -0,0 +1,415 @@
+package mjingo
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/hnakamur/mjingo/option"
+)
+
+// ExtractedMessage is a translatable message found in a template by
+// [Environment.ExtractMessages].
+type ExtractedMessage struct {
+	// Context is the message context or empty if the message has none.
+	Context string
+	// ID is the message ID.
+	ID string
+	// IDPlural is the plural message ID or empty if the message has no
+	// plural form.
+	IDPlural string
+	// Filename is the name of the template.
+	Filename string
+	// Line is the line number of the message starting with 1.
+	Line int
+	// Comments holds the lines of the translator comments for the message.
+	Comments []string
+}
+
+// ExtractKeyword describes the arguments of a gettext-like function.
+//
+// The fields are argument positions starting with 1.  Zero means that the
+// function has no such argument.
+type ExtractKeyword struct {
+	Context  int
+	Singular int
+	Plural   int
+}
+
+// DefaultExtractKeywords are the gettext functions which are extracted by
+// [Environment.ExtractMessages].  `{% trans %}` blocks are compiled into calls
+// of these functions.
+var DefaultExtractKeywords = map[string]ExtractKeyword{
+	"_":         {Singular: 1},
+	"gettext":   {Singular: 1},
+	"ngettext":  {Singular: 1, Plural: 2},
+	"pgettext":  {Context: 1, Singular: 2},
+	"npgettext": {Context: 1, Singular: 2, Plural: 3},
+}
+
+// ExtractOptions is the configuration of [Environment.ExtractMessages].
+type ExtractOptions struct {
+	// Keywords are the gettext-like functions to extract in addition to
+	// DefaultExtractKeywords.
+	Keywords map[string]ExtractKeyword
+	// NoDefaultKeywords disables DefaultExtractKeywords.  `{% trans %}`
+	// blocks are extracted regardless.
+	NoDefaultKeywords bool
+	// CommentTags are the prefixes of the comments which are extracted as
+	// translator comments.  A comment is attached to a message if it ends on
+	// the line of the message or on the line before.  If nil,
+	// `Translators:` is used.
+	CommentTags []string
+}
+
+// ExtractMessages parses a template source and returns the translatable
+// messages in it.
+//
+// Messages are the calls of the gettext-like functions configured in opts
+// whose message arguments are string literals and `{% trans %}` blocks.
+// The template is parsed with the syntax and the custom tags of the
+// environment.  Use [WritePOT] to write the messages as a `.pot` file.
+func (e *Environment) ExtractMessages(name, source string, opts ExtractOptions) ([]ExtractedMessage, error) {
+	syntax := e.syntaxConfig()
+	stmt, err := parseWithSyntax(source, name, *syntax, true)
+	if err != nil {
+		return nil, err
+	}
+
+	keywords := make(map[string]ExtractKeyword)
+	if !opts.NoDefaultKeywords {
+		for k, v := range DefaultExtractKeywords {
+			keywords[k] = v
+		}
+	}
+	for k, v := range opts.Keywords {
+		keywords[k] = v
+	}
+	tags := opts.CommentTags
+	if tags == nil {
+		tags = []string{"Translators:"}
+	}
+	comments, err := collectTranslatorComments(source, syntax, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	x := messageExtractor{filename: name, keywords: keywords, comments: comments}
+	x.walkStmt(stmt)
+	return x.messages, nil
+}
+
+type translatorComment struct {
+	lines []string
+	span  span
+}
+
+// collectTranslatorComments lexes the source and returns the comments which
+// start with one of tags.  The parser never sees comments so they are
+// collected separately.
+func collectTranslatorComments(source string, syntax *syntaxConfig, tags []string) ([]translatorComment, error) {
+	var rv []translatorComment
+	iter := newTokenizeIterator(source, false, syntax)
+	iter.onComment = func(text string, spn *span) {
+		text = strings.TrimSpace(text)
+		for _, tag := range tags {
+			if strings.HasPrefix(text, tag) {
+				lines := strings.Split(text, "\n")
+				for i := range lines {
+					lines[i] = strings.TrimSpace(lines[i])
+				}
+				rv = append(rv, translatorComment{lines: lines, span: *spn})
+				return
+			}
+		}
+	}
+	for {
+		tkn, _, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		if tkn == nil {
+			return rv, nil
+		}
+	}
+}
+
+type messageExtractor struct {
+	filename string
+	keywords map[string]ExtractKeyword
+	comments []translatorComment
+	messages []ExtractedMessage
+}
+
+func (x *messageExtractor) walkStmts(stmts []statement) {
+	for _, stmt := range stmts {
+		x.walkStmt(stmt)
+	}
+}
+
+func (x *messageExtractor) walkStmt(stmt statement) {
+	switch st := stmt.(type) {
+	case templateStmt:
+		x.walkStmts(st.children)
+	case emitExprStmt:
+		x.visitExpr(st.expr)
+	case emitRawStmt: // do nothing
+	case forLoopStmt:
+		x.visitExpr(st.iter)
+		x.visitExprOpt(st.filterExpr)
+		x.walkStmts(st.body)
+		x.walkStmts(st.elseBody)
+	case ifCondStmt:
+		x.visitExpr(st.expr)
+		x.walkStmts(st.trueBody)
+		x.walkStmts(st.falseBody)
+	case withBlockStmt:
+		for _, assign := range st.assignments {
+			x.visitExpr(assign.rhs)
+		}
+		x.walkStmts(st.body)
+	case setStmt:
+		x.visitExpr(st.expr)
+	case setBlockStmt:
+		x.visitExprOpt(st.filter)
+		x.walkStmts(st.body)
+	case autoEscapeStmt:
+		x.visitExpr(st.enabled)
+		x.walkStmts(st.body)
+	case filterBlockStmt:
+		x.visitExpr(st.filter)
+		x.walkStmts(st.body)
+	case blockStmt:
+		x.walkStmts(st.body)
+	case importStmt:
+		x.visitExpr(st.expr)
+	case fromImportStmt:
+		x.visitExpr(st.expr)
+	case extendsStmt:
+		x.visitExpr(st.name)
+	case includeStmt:
+		x.visitExpr(st.name)
+	case macroStmt:
+		x.visitExprs(st.defaults)
+		x.walkStmts(st.body)
+	case callBlockStmt:
+		x.visitCall(st.call)
+		x.walkStmt(st.macroDecl)
+	case doStmt:
+		x.visitCall(st.call)
+	case customTagStmt:
+		x.visitExprs(st.args)
+		x.walkStmts(st.body)
+	}
+}
+
+func (x *messageExtractor) visitExprs(exprs []astExpr) {
+	for _, expr := range exprs {
+		x.visitExpr(expr)
+	}
+}
+
+func (x *messageExtractor) visitExprOpt(expr option.Option[astExpr]) {
+	if expr.IsSome() {
+		x.visitExpr(expr.Unwrap())
+	}
+}
+
+func (x *messageExtractor) visitExpr(expr astExpr) {
+	switch exp := expr.(type) {
+	case varExpr, constExpr: // do nothing
+	case unaryOpExpr:
+		x.visitExpr(exp.expr)
+	case binOpExpr:
+		x.visitExpr(exp.left)
+		x.visitExpr(exp.right)
+	case ifExpr:
+		x.visitExpr(exp.testExpr)
+		x.visitExpr(exp.trueExpr)
+		x.visitExprOpt(exp.falseExpr)
+	case filterExpr:
+		x.visitExprOpt(exp.expr)
+		x.visitExprs(exp.args)
+	case testExpr:
+		x.visitExpr(exp.expr)
+		x.visitExprs(exp.args)
+	case getAttrExpr:
+		x.visitExpr(exp.expr)
+	case getItemExpr:
+		x.visitExpr(exp.expr)
+		x.visitExpr(exp.subscriptExpr)
+	case sliceExpr:
+		x.visitExpr(exp.expr)
+		x.visitExprOpt(exp.start)
+		x.visitExprOpt(exp.stop)
+		x.visitExprOpt(exp.step)
+	case callExpr:
+		x.visitCall(exp.call)
+	case listExpr:
+		x.visitExprs(exp.items)
+	case mapExpr:
+		x.visitExprs(exp.keys)
+		x.visitExprs(exp.values)
+	case kwargsExpr:
+		for _, pair := range exp.pairs {
+			x.visitExpr(pair.arg)
+		}
+	}
+}
+
+func (x *messageExtractor) visitCall(c spanned[call]) {
+	if v, ok := c.data.expr.(varExpr); ok {
+		kw, ok := x.keywords[v.id]
+		if c.data.trans {
+			// trans blocks are extracted regardless of the keywords.
+			kw, ok = DefaultExtractKeywords[v.id], true
+		}
+		if ok {
+			x.addMessage(kw, c.data.args, c.span)
+		}
+	}
+	x.visitExpr(c.data.expr)
+	x.visitExprs(c.data.args)
+}
+
+// addMessage adds a message if all arguments for kw are string literals.
+func (x *messageExtractor) addMessage(kw ExtractKeyword, args []astExpr, spn span) {
+	arg := func(pos int) (string, bool) {
+		if pos == 0 {
+			return "", true
+		}
+		if pos > len(args) {
+			return "", false
+		}
+		if c, ok := args[pos-1].(constExpr); ok {
+			if s, ok := c.val.data.(stringValue); ok {
+				return s.Str, true
+			}
+		}
+		return "", false
+	}
+	msgctxt, ok1 := arg(kw.Context)
+	msgid, ok2 := arg(kw.Singular)
+	msgidPlural, ok3 := arg(kw.Plural)
+	if !ok1 || !ok2 || !ok3 || kw.Singular == 0 {
+		return
+	}
+	x.messages = append(x.messages, ExtractedMessage{
+		Context:  msgctxt,
+		ID:       msgid,
+		IDPlural: msgidPlural,
+		Filename: x.filename,
+		Line:     int(spn.StartLine),
+		Comments: x.commentsFor(spn),
+	})
+}
+
+// commentsFor returns the lines of the translator comments which end on the
+// line of the message or the line before.  Comments on consecutive lines
+// are combined.
+func (x *messageExtractor) commentsFor(spn span) []string {
+	i := len(x.comments)
+	for i > 0 && x.comments[i-1].span.StartOffset > spn.StartOffset {
+		i--
+	}
+	line := spn.StartLine
+	start := i
+	for start > 0 && x.comments[start-1].span.EndLine+1 >= line {
+		start--
+		line = x.comments[start].span.StartLine
+	}
+	var rv []string
+	for _, c := range x.comments[start:i] {
+		rv = append(rv, c.lines...)
+	}
+	return rv
+}
+
+var pythonFormatRe = regexp.MustCompile(`%(\([^)]*\))?[-+ #0]*(\d+|\*)?(\.(\d+|\*))?[hlL]?[diouxXeEfFgGcrs]`)
+
+// WritePOT writes messages as a gettext `.pot` template.
+//
+// Messages with the same context and ID are merged into a single entry
+// which lists all the locations and translator comments.
+func WritePOT(w io.Writer, messages []ExtractedMessage) error {
+	type entry struct {
+		msg       ExtractedMessage
+		locations []string
+		comments  []string
+	}
+	var entries []*entry
+	byKey := make(map[string]*entry)
+	for _, msg := range messages {
+		key := catalogKey(msg.Context, msg.ID)
+		e, ok := byKey[key]
+		if !ok {
+			e = &entry{msg: msg}
+			byKey[key] = e
+			entries = append(entries, e)
+		}
+		if e.msg.IDPlural == "" {
+			e.msg.IDPlural = msg.IDPlural
+		}
+		e.locations = append(e.locations, fmt.Sprintf("%s:%d", msg.Filename, msg.Line))
+		for _, c := range msg.Comments {
+			if !slices.Contains(e.comments, c) {
+				e.comments = append(e.comments, c)
+			}
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("# Translations template.\n" +
+		"#\n" +
+		"msgid \"\"\n" +
+		"msgstr \"\"\n" +
+		"\"MIME-Version: 1.0\\n\"\n" +
+		"\"Content-Type: text/plain; charset=UTF-8\\n\"\n" +
+		"\"Content-Transfer-Encoding: 8bit\\n\"\n")
+	for _, e := range entries {
+		b.WriteByte('\n')
+		for _, c := range e.comments {
+			b.WriteString("#. " + c + "\n")
+		}
+		b.WriteString("#: " + strings.Join(e.locations, " ") + "\n")
+		if pythonFormatRe.MatchString(e.msg.ID) || pythonFormatRe.MatchString(e.msg.IDPlural) {
+			b.WriteString("#, python-format\n")
+		}
+		if e.msg.Context != "" {
+			writePOString(&b, "msgctxt", e.msg.Context)
+		}
+		writePOString(&b, "msgid", e.msg.ID)
+		if e.msg.IDPlural != "" {
+			writePOString(&b, "msgid_plural", e.msg.IDPlural)
+			b.WriteString("msgstr[0] \"\"\nmsgstr[1] \"\"\n")
+		} else {
+			b.WriteString("msgstr \"\"\n")
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var poStringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
+
+// writePOString writes a keyword and a quoted string.  Strings with
+// newlines are split into multiple lines after each newline.
+func writePOString(b *strings.Builder, keyword, s string) {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > 1 {
+		b.WriteString(keyword + " \"\"\n")
+	} else {
+		b.WriteString(keyword + " ")
+	}
+	for _, line := range lines {
+		b.WriteString("\"" + poStringEscaper.Replace(line) + "\"\n")
+	}
+	if len(lines) == 0 {
+		b.WriteString("\"\"\n")
+	}
+}
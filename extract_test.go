@@ -0,0 +1,102 @@
+package mjingo_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hnakamur/mjingo"
+)
+
+func TestEnvironment_ExtractMessages(t *testing.T) {
+	const source = `{# Translators: shown
+   as the title #}
+<h1>{{ _("Hello %(name)s!", name=user) }}</h1>
+{% trans count=items|length %}{{ count }} item{% pluralize %}{{ count }} items{% endtrans %}
+{% for x in xs %}{{ x|default(pgettext("menu", "Open")) }}{{ _(x) }}{% endfor %}
+{# unrelated #}
+{% macro m(label=gettext("OK")) %}{% endmacro %}
+{{ _n("apple", "apples", 2) }}{# Translators: not attached #}`
+
+	env := mjingo.NewEnvironment()
+	got, err := env.ExtractMessages("index.html", source, mjingo.ExtractOptions{
+		Keywords: map[string]mjingo.ExtractKeyword{"_n": {Singular: 1, Plural: 2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []mjingo.ExtractedMessage{
+		{ID: "Hello %(name)s!", Filename: "index.html", Line: 3, Comments: []string{"Translators: shown", "as the title"}},
+		{ID: "%(count)s item", IDPlural: "%(count)s items", Filename: "index.html", Line: 4},
+		{Context: "menu", ID: "Open", Filename: "index.html", Line: 5},
+		{ID: "OK", Filename: "index.html", Line: 7},
+		{ID: "apple", IDPlural: "apples", Filename: "index.html", Line: 8},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("messages mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err = env.ExtractMessages("index.html", source, mjingo.ExtractOptions{NoDefaultKeywords: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []mjingo.ExtractedMessage{
+		{ID: "%(count)s item", IDPlural: "%(count)s items", Filename: "index.html", Line: 4},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("messages mismatch with NoDefaultKeywords (-want +got):\n%s", diff)
+	}
+
+	if _, err := env.ExtractMessages("bad.html", "{% trans %}", mjingo.ExtractOptions{}); err == nil {
+		t.Error("expected a syntax error")
+	}
+}
+
+func TestWritePOT(t *testing.T) {
+	messages := []mjingo.ExtractedMessage{
+		{ID: "Hello %(name)s!", Filename: "a.html", Line: 1, Comments: []string{"Translators: greeting"}},
+		{Context: "menu", ID: "Open\n\"file\"", Filename: "a.html", Line: 2},
+		{ID: "%(num)s item", IDPlural: "%(num)s items", Filename: "b.html", Line: 3},
+		{ID: "Hello %(name)s!", Filename: "b.html", Line: 4, Comments: []string{"Translators: greeting"}},
+	}
+	var b strings.Builder
+	if err := mjingo.WritePOT(&b, messages); err != nil {
+		t.Fatal(err)
+	}
+	want := `# Translations template.
+#
+msgid ""
+msgstr ""
+"MIME-Version: 1.0\n"
+"Content-Type: text/plain; charset=UTF-8\n"
+"Content-Transfer-Encoding: 8bit\n"
+
+#. Translators: greeting
+#: a.html:1 b.html:4
+#, python-format
+msgid "Hello %(name)s!"
+msgstr ""
+
+#: a.html:2
+msgctxt "menu"
+msgid ""
+"Open\n"
+"\"file\""
+msgstr ""
+
+#: b.html:3
+#, python-format
+msgid "%(num)s item"
+msgid_plural "%(num)s items"
+msgstr[0] ""
+msgstr[1] ""
+`
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Errorf("output mismatch (-want +got):\n%s", diff)
+	}
+
+	// The template can be read back as a catalog.
+	if _, err := mjingo.ParsePOCatalog(strings.NewReader(b.String())); err != nil {
+		t.Errorf("cannot parse written template: %s", err)
+	}
+}
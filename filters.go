@@ -1,11 +1,22 @@
 package mjingo
 
 import (
-	"encoding/json"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"hash"
+	"html"
 	"math"
+	"math/big"
+	"net/url"
 	"reflect"
+	"regexp"
 	"slices"
+	"strconv"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -775,7 +786,10 @@ func sortFilter(state *State, val Value, kwargs Kwargs) (Value, error) {
 	if err != nil {
 		return Value{}, NewError(InvalidOperation, "cannot convert value to list").withSource(err)
 	}
-	items := iter.Collect()
+	items, err := iter.Collect()
+	if err != nil {
+		return Value{}, err
+	}
 	caseSensitive := false
 	if optCaseSensitive := kwargs.GetValue("case_sensitive"); optCaseSensitive.IsSome() {
 		if cs, ok := optCaseSensitive.Unwrap().data.(boolValue); ok && cs.B {
@@ -871,6 +885,77 @@ func items(v Value) (Value, error) {
 	return valueFromSlice(items), nil
 }
 
+// Creates an HTML/XML attribute string from the items of a map.
+//
+// All values that are `none` or undefined are skipped, `true` renders a
+// boolean attribute with just the name and `false` omits the attribute.
+// Other values are HTML escaped unless they are already safe.  Unless
+// `autospace` is set to false a space is added in front of the result if
+// it is not empty.  An error is returned if an attribute name is empty or
+// contains whitespace, control characters or any of `"'>/=`.
+//
+// ```jinja
+// <input{{ {"type": "checkbox", "name": "agree", "checked": true, "title": none}|xmlattr }}>
+//
+//	-> <input type="checkbox" name="agree" checked>
+//
+// ```
+func xmlattr(val Value, autospace option.Option[bool]) (Value, error) {
+	if val.Kind() != ValueKindMap {
+		return Value{}, NewError(InvalidOperation, "xmlattr filter expects a map")
+	}
+	var b strings.Builder
+	iter, err := val.tryIter()
+	if err != nil {
+		return Value{}, err
+	}
+	for key := (Value{}); iter.Next().UnwrapTo(&key); {
+		v, err := getItem(val, key)
+		if err != nil || v.isUndefined() || v.isNone() {
+			continue
+		}
+		var name string
+		if !valueAsOptionString(key).UnwrapTo(&name) {
+			name = key.String()
+		}
+		if !isValidXMLAttrName(name) {
+			return Value{}, NewError(InvalidOperation,
+				fmt.Sprintf("invalid character in attribute name: %q", name))
+		}
+		if bv, ok := v.data.(boolValue); ok && !bv.B {
+			continue
+		}
+		if b.Len() != 0 || autospace.UnwrapOr(true) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(name)
+		switch {
+		case v.Kind() == ValueKindBool:
+		case v.isSafe():
+			fmt.Fprintf(&b, `="%s"`, v.String())
+		default:
+			var str string
+			if !valueAsOptionString(v).UnwrapTo(&str) {
+				str = v.String()
+			}
+			fmt.Fprintf(&b, `="%s"`, htmlEscapeString(str))
+		}
+	}
+	return ValueFromSafeString(b.String()), nil
+}
+
+func isValidXMLAttrName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if unicode.IsSpace(r) || unicode.IsControl(r) || strings.ContainsRune(`"'>/=`, r) {
+			return false
+		}
+	}
+	return true
+}
+
 // Joins a sequence by a character
 func join(val Value, joiner option.Option[string]) (string, error) {
 	if val.isUndefined() || val.isNone() {
@@ -890,28 +975,32 @@ func join(val Value, joiner option.Option[string]) (string, error) {
 		}
 		return b.String(), nil
 	}
-	if optValSeq := val.asSeq(); optValSeq.IsSome() {
-		valSeq := optValSeq.Unwrap()
+	if _, ok := valueAsIterableObject(val); ok || val.asSeq().IsSome() {
+		iter, err := val.tryIter()
+		if err != nil {
+			return "", err
+		}
 		var b strings.Builder
-		n := valSeq.ItemCount()
-		for i := uint(0); i < n; i++ {
+		for item := (Value{}); iter.Next().UnwrapTo(&item); {
 			if b.Len() != 0 {
 				b.WriteString(joinerStr)
 			}
-			item := valSeq.GetItem(i).Unwrap()
 			if itemStr := ""; valueAsOptionString(item).UnwrapTo(&itemStr) {
 				b.WriteString(itemStr)
 			} else {
 				fmt.Fprintf(&b, rustfmt.DisplayString, item)
 			}
 		}
+		if err := iter.Err(); err != nil {
+			return "", err
+		}
 		return b.String(), nil
 	}
 	return "", NewError(InvalidOperation,
 		fmt.Sprintf("cannot join value of type %s", val.Kind()))
 }
 
-// Reverses a list or string
+// Reverses a list, an iterable or a string
 //
 // ```jinja
 // {% for user in users|reverse %}
@@ -943,6 +1032,14 @@ func reverse(val Value) (Value, error) {
 		}
 		return valueFromSlice(items), nil
 	}
+	if obj, ok := valueAsIterableObject(val); ok {
+		items, err := collectIterableObject(obj)
+		if err != nil {
+			return Value{}, err
+		}
+		slices.Reverse(items)
+		return valueFromSlice(items), nil
+	}
 	return Value{}, NewError(InvalidOperation,
 		fmt.Sprintf("cannot reverse value of type %s", val.Kind()))
 }
@@ -1037,6 +1134,16 @@ func first(val Value) (Value, error) {
 		valSeq := optValSeq.Unwrap()
 		return valSeq.GetItem(0).UnwrapOr(Undefined), nil
 	}
+	if v, ok := val.data.(bytesValue); ok {
+		return v.getItemOpt(valueFromI64(0)).UnwrapOr(Undefined), nil
+	}
+	if obj, ok := valueAsIterableObject(val); ok {
+		rv := obj.Iterate()().UnwrapOr(Undefined)
+		if err := invalidValueErr(rv); err != nil {
+			return Value{}, err
+		}
+		return rv, nil
+	}
 	return Value{}, NewError(InvalidOperation, "cannot get first item from value")
 }
 
@@ -1058,6 +1165,20 @@ func last(val Value) (Value, error) {
 		}
 		return valSeq.GetItem(n - 1).UnwrapOr(Undefined), nil
 	}
+	if v, ok := val.data.(bytesValue); ok {
+		return v.getItemOpt(valueFromI64(-1)).UnwrapOr(Undefined), nil
+	}
+	if obj, ok := valueAsIterableObject(val); ok {
+		rv := Undefined
+		next := obj.Iterate()
+		for item := (Value{}); next().UnwrapTo(&item); {
+			if err := invalidValueErr(item); err != nil {
+				return Value{}, err
+			}
+			rv = item
+		}
+		return rv, nil
+	}
 	return Value{}, NewError(InvalidOperation, "cannot get last item from value")
 }
 
@@ -1066,7 +1187,11 @@ func minFilter(state *State, val Value) (Value, error) {
 	if err != nil {
 		return Value{}, NewError(InvalidDelimiter, "cannot convert value to list").withSource(err)
 	}
-	return iter.Min().UnwrapOr(Undefined), nil
+	rv := iter.Min()
+	if err := iter.Err(); err != nil {
+		return Value{}, err
+	}
+	return rv.UnwrapOr(Undefined), nil
 }
 
 func maxFilter(state *State, val Value) (Value, error) {
@@ -1074,7 +1199,11 @@ func maxFilter(state *State, val Value) (Value, error) {
 	if err != nil {
 		return Value{}, NewError(InvalidDelimiter, "cannot convert value to list").withSource(err)
 	}
-	return iter.Max().UnwrapOr(Undefined), nil
+	rv := iter.Max()
+	if err := iter.Err(); err != nil {
+		return Value{}, err
+	}
+	return rv.UnwrapOr(Undefined), nil
 }
 
 func listFilter(state *State, val Value) (Value, error) {
@@ -1082,7 +1211,11 @@ func listFilter(state *State, val Value) (Value, error) {
 	if err != nil {
 		return Value{}, NewError(InvalidDelimiter, "cannot convert value to list").withSource(err)
 	}
-	return valueFromSlice(iter.Collect()), nil
+	items, err := iter.Collect()
+	if err != nil {
+		return Value{}, err
+	}
+	return valueFromSlice(items), nil
 }
 
 // Converts the value into a boolean
@@ -1093,6 +1226,376 @@ func boolFilter(val Value) bool {
 	return val.isTrue()
 }
 
+// Converts the value into an integer.
+//
+// Strings are parsed in the given base (default 10).  A base of 0 detects
+// the base from the `0b`, `0o` or `0x` prefix.  Strings which are not
+// integers are parsed as floats which are truncated like floats are.
+// If the conversion does not succeed, `default` (default 0) is returned.
+// Integers which do not fit into 128 bits cause an error.
+//
+// ```jinja
+// {{ "42"|int + 1 }}
+//
+//	-> 43
+//
+// {{ "0x1A"|int(base=16) }} {{ "3.9"|int }} {{ "abc"|int(-1) }}
+//
+//	-> 26 3 -1
+//
+// ```
+func intFilter(val Value, args ...Value) (Value, error) {
+	bound, err := bindArgs(args, "default", "base")
+	if err != nil {
+		return Value{}, err
+	}
+	def := bound[0].UnwrapOr(valueFromI64(0))
+	base, err := argOr(bound[1], 10, valueTryToGoInt)
+	if err != nil {
+		return Value{}, err
+	}
+	if base != 0 && (base < 2 || base > 36) {
+		return Value{}, NewError(InvalidOperation, "int() base must be >= 2 and <= 36, or 0")
+	}
+
+	switch v := val.data.(type) {
+	case boolValue:
+		if v.B {
+			return valueFromI64(1), nil
+		}
+		return valueFromI64(0), nil
+	case i64Value, u64Value, i128Value, u128Value:
+		return val, nil
+	case f64Value:
+		return intFromFloat(v.F, def)
+	case stringValue:
+		if rv, ok, err := parseInt(v.Str, base); err != nil || ok {
+			return rv, err
+		}
+		if f, ok := parseFloat(v.Str); ok {
+			return intFromFloat(f, def)
+		}
+	}
+	return def, nil
+}
+
+// intFromFloat truncates f to an integer.  def is returned for infinities
+// and NaN.
+func intFromFloat(f float64, def Value) (Value, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return def, nil
+	}
+	f = math.Trunc(f)
+	if f >= math.MinInt64 && f < math.MaxInt64 {
+		return valueFromI64(int64(f)), nil
+	}
+	n, _ := big.NewFloat(f).Int(nil)
+	if rv, err := I128TryFromBigInt(n); err == nil {
+		return i128AsValue(rv), nil
+	}
+	if rv, err := U128TryFromBigInt(n); err == nil {
+		return valueFromU128(*rv), nil
+	}
+	return Value{}, NewError(InvalidOperation, fmt.Sprintf("cannot convert %g to an integer", f))
+}
+
+// parseInt parses an integer like Python's int function.  ok is false if s
+// is not an integer literal in base.
+func parseInt(s string, base int) (rv Value, ok bool, err error) {
+	s = strings.TrimSpace(s)
+	neg := false
+	if s != "" && (s[0] == '+' || s[0] == '-') {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+	if len(s) > 2 && s[0] == '0' {
+		prefixBase := 0
+		switch s[1] {
+		case 'b', 'B':
+			prefixBase = 2
+		case 'o', 'O':
+			prefixBase = 8
+		case 'x', 'X':
+			prefixBase = 16
+		}
+		if prefixBase != 0 && (base == 0 || base == prefixBase) {
+			s, base = strings.TrimPrefix(s[2:], "_"), prefixBase
+		}
+	}
+	if base == 0 {
+		// Python does not allow leading zeros for decimal numbers.
+		if len(strings.TrimLeft(s, "0_")) != 0 && s[0] == '0' {
+			return Value{}, false, nil
+		}
+		base = 10
+	}
+	digits, ok := removeDigitSeparators(s)
+	if !ok || digits == "" {
+		return Value{}, false, nil
+	}
+	for _, c := range strings.ToLower(digits) {
+		d := int(c - '0')
+		if c >= 'a' {
+			d = int(c-'a') + 10
+		}
+		if c < '0' || (c > '9' && c < 'a') || d >= base {
+			return Value{}, false, nil
+		}
+	}
+	if neg {
+		digits = "-" + digits
+	}
+	var i I128
+	if _, ok := i.SetString(digits, base); ok {
+		return i128AsValue(&i), true, nil
+	}
+	var u U128
+	if _, ok := u.SetString(digits, base); ok {
+		return valueFromU128(u), true, nil
+	}
+	return Value{}, true, NewError(InvalidOperation, fmt.Sprintf("integer %s is out of range", digits))
+}
+
+// removeDigitSeparators removes single underscores between digits.
+func removeDigitSeparators(s string) (string, bool) {
+	if !strings.Contains(s, "_") {
+		return s, true
+	}
+	if strings.HasPrefix(s, "_") || strings.HasSuffix(s, "_") || strings.Contains(s, "__") {
+		return "", false
+	}
+	return strings.ReplaceAll(s, "_", ""), true
+}
+
+// parseFloat parses a float like Python's float function.
+func parseFloat(s string) (float64, bool) {
+	s, ok := removeDigitSeparators(strings.TrimSpace(s))
+	if !ok || strings.HasPrefix(strings.TrimLeft(s, "+-"), "0x") {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil && !errors.Is(err, strconv.ErrRange) {
+		return 0, false
+	}
+	return f, true
+}
+
+// Converts the value into a float.
+//
+// If the conversion does not succeed, `default` (default 0.0) is returned.
+//
+// ```jinja
+// {{ "42.5"|float + 1 }} {{ "abc"|float(-1.5) }}
+//
+//	-> 43.5 -1.5
+//
+// ```
+func floatFilter(val Value, args ...Value) (Value, error) {
+	bound, err := bindArgs(args, "default")
+	if err != nil {
+		return Value{}, err
+	}
+	def := bound[0].UnwrapOr(valueFromF64(0))
+	switch v := val.data.(type) {
+	case stringValue:
+		if f, ok := parseFloat(v.Str); ok {
+			return valueFromF64(f), nil
+		}
+	case boolValue, i64Value, u64Value, i128Value, u128Value, f64Value:
+		return valueFromF64(val.asF64().Unwrap()), nil
+	}
+	return def, nil
+}
+
+// Converts the value into a string.
+//
+// Strings including safe strings are returned as is.
+//
+// ```jinja
+// {{ 42|string ~ "!" }}
+//
+//	-> 42!
+//
+// ```
+func stringFilter(val Value) (Value, error) {
+	if _, ok := val.data.(stringValue); ok {
+		return val, nil
+	}
+	val, err := collectIterableValue(val)
+	if err != nil {
+		return Value{}, err
+	}
+	return valueFromString(val.String()), nil
+}
+
+// Sums up all the values in a sequence.
+//
+// The `attribute` parameter sums up an attribute of the items instead which
+// can use dots to access nested attributes.  The sum starts with `start`
+// which defaults to 0.  Integers and floats are added like with the `+`
+// operator.
+//
+// ```jinja
+// Total: {{ items|sum(attribute="price") }}
+// ```
+func sumFilter(state *State, val Value, args ...Value) (Value, error) {
+	bound, err := bindArgs(args, "attribute", "start")
+	if err != nil {
+		return Value{}, err
+	}
+	rv := bound[1].UnwrapOr(valueFromI64(0))
+	iter, err := state.UndefinedBehavior().tryIter(val)
+	if err != nil {
+		return Value{}, NewError(InvalidOperation, "cannot convert value to list").withSource(err)
+	}
+	attr := bound[0].UnwrapOr(none)
+	for item := (Value{}); iter.Next().UnwrapTo(&item); {
+		if !attr.isNone() {
+			if path := ""; valueAsOptionString(attr).UnwrapTo(&path) {
+				item, err = getPath(item, path)
+			} else {
+				item, err = getItem(item, attr)
+			}
+			if err != nil {
+				return Value{}, err
+			}
+		}
+		if rv, err = opAdd(rv, item); err != nil {
+			return Value{}, err
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return Value{}, err
+	}
+	return rv, nil
+}
+
+// Group a sequence of objects by an attribute.
+//
+// The attribute can use dots to access nested attributes, like `"address.city"`.
+// The values are sorted first so only one group is returned for each unique
+// value.  Each group is a tuple of the grouper and the list of items which
+// can be unpacked in a for loop.  The grouper and the list are also available
+// as the `grouper` and `list` attributes.
+//
+// Items where the attribute is missing are grouped under `default` which is
+// undefined if not given.  Strings are compared case insensitively unless
+// `case_sensitive` is true.  When comparing case insensitively the grouper
+// is the value of the first item in the group.
+//
+// ```jinja
+// {% for city, items in users|groupby("city") %}
+// {{ city }}: {{ items|map(attribute="name")|join(", ") }}
+// {% endfor %}
+// ```
+func groupbyFilter(state *State, val Value, args ...Value) (Value, error) {
+	bound, err := bindArgs(args, "attribute", "default", "case_sensitive")
+	if err != nil {
+		return Value{}, err
+	}
+	var attr Value
+	if !bound[0].UnwrapTo(&attr) {
+		return Value{}, NewError(MissingArgument, "groupby requires an attribute")
+	}
+	def := bound[1].UnwrapOr(Undefined)
+	caseSensitive, err := argOr(bound[2], false, valueTryToGoBool)
+	if err != nil {
+		return Value{}, err
+	}
+	cmp := valueCmp
+	if !caseSensitive {
+		cmp = compareValuesCaseInsensitive
+	}
+
+	iter, err := state.UndefinedBehavior().tryIter(val)
+	if err != nil {
+		return Value{}, NewError(InvalidOperation, "cannot convert value to list").withSource(err)
+	}
+	type keyedItem struct {
+		key  Value
+		item Value
+	}
+	var items []keyedItem
+	for item := (Value{}); iter.Next().UnwrapTo(&item); {
+		var key Value
+		if path := ""; valueAsOptionString(attr).UnwrapTo(&path) {
+			key, err = getPath(item, path)
+		} else {
+			key, err = getItem(item, attr)
+		}
+		if err != nil {
+			if def.isUndefined() {
+				return Value{}, err
+			}
+			key = def.clone()
+		} else if key.isUndefined() {
+			key = def.clone()
+		}
+		items = append(items, keyedItem{key: key, item: item})
+	}
+	if err := iter.Err(); err != nil {
+		return Value{}, err
+	}
+	slices.SortStableFunc(items, func(a, b keyedItem) int { return cmp(a.key, b.key) })
+
+	var rv []Value
+	for i := 0; i < len(items); {
+		j := i + 1
+		for j < len(items) && cmp(items[i].key, items[j].key) == 0 {
+			j++
+		}
+		group := &groupTuple{grouper: items[i].key, list: make([]Value, 0, j-i)}
+		for _, it := range items[i:j] {
+			group.list = append(group.list, it.item)
+		}
+		rv = append(rv, ValueFromObject(group))
+		i = j
+	}
+	return valueFromSlice(rv), nil
+}
+
+// groupTuple is a group returned by the groupby filter.
+//
+// It is a sequence of the grouper and the list so that it can be unpacked
+// and also provides them as the `grouper` and `list` attributes.
+type groupTuple struct {
+	grouper Value
+	list    []Value
+}
+
+var _ = SeqObject((*groupTuple)(nil))
+var _ = StructObject((*groupTuple)(nil))
+
+func (*groupTuple) Kind() ObjectKind { return ObjectKindSeq }
+
+func (g *groupTuple) GetItem(idx uint) option.Option[Value] {
+	switch idx {
+	case 0:
+		return option.Some(g.grouper)
+	case 1:
+		return option.Some(valueFromSlice(g.list))
+	}
+	return option.None[Value]()
+}
+
+func (*groupTuple) ItemCount() uint { return 2 }
+
+func (g *groupTuple) GetField(name string) option.Option[Value] {
+	switch name {
+	case "grouper":
+		return g.GetItem(0)
+	case "list":
+		return g.GetItem(1)
+	}
+	return option.None[Value]()
+}
+
+func (*groupTuple) StaticFields() option.Option[[]string] {
+	return option.Some([]string{"grouper", "list"})
+}
+
+func (*groupTuple) Fields() []string { return nil }
+
 // Batch items.
 //
 // This filter works pretty much like `slice` just the other way round. It
@@ -1131,6 +1634,9 @@ func batchFilter(state *State, val Value, count uint, fillWith option.Option[Val
 		}
 		tmp = append(tmp, item)
 	}
+	if err := iter.Err(); err != nil {
+		return Value{}, err
+	}
 
 	if len(tmp) != 0 {
 		if filler := (Value{}); fillWith.UnwrapTo(&filler) {
@@ -1176,7 +1682,10 @@ func sliceFilter(state *State, val Value, count uint, fillWith option.Option[Val
 	if err != nil {
 		return Value{}, err
 	}
-	items := iter.Collect()
+	items, err := iter.Collect()
+	if err != nil {
+		return Value{}, err
+	}
 	l := uint(len(items))
 	itemsPerSlice := l / count
 	slicesWithExtra := l % count
@@ -1203,13 +1712,113 @@ func sliceFilter(state *State, val Value, count uint, fillWith option.Option[Val
 	return valueFromSlice(rv), nil
 }
 
-func indentFilter(val string, width uint, indentFirstLine, indentBlankLines option.Option[bool]) string {
-	stripTrailingNewline := func(s *string) {
-		if strings.HasSuffix(*s, "\n") {
-			*s = (*s)[:len(*s)-1]
+// Splits a string into a list of strings.
+//
+// If `sep` is omitted or none, the string is split at runs of whitespace
+// and leading and trailing whitespace is ignored.  Otherwise it is split at
+// each occurrence of `sep`.  If `maxsplit` is given and not negative, at
+// most that many splits are done.
+//
+// ```jinja
+// {{ "a,b,c"|split(",", 1) }}
+//
+//	-> ["a", "b,c"]
+//
+// ```
+func splitFilter(s string, args ...Value) ([]string, error) {
+	boundArgs, err := bindArgs(args, "sep", "maxsplit")
+	if err != nil {
+		return nil, err
+	}
+	sep, err := argOr(boundArgs[0], option.None[string](), func(v Value) (option.Option[string], error) {
+		s, err := valueTryToGoString(v)
+		return option.Some(s), err
+	})
+	if err != nil {
+		return nil, err
+	}
+	maxSplit, err := argOr(boundArgs[1], int64(-1), valueTryToGoInt64)
+	if err != nil {
+		return nil, err
+	}
+
+	var sepStr string
+	if sep.UnwrapTo(&sepStr) {
+		if sepStr == "" {
+			return nil, NewError(InvalidOperation, "empty separator")
 		}
-		if strings.HasSuffix(*s, "\r") {
-			*s = (*s)[:len(*s)-1]
+		if maxSplit < 0 {
+			return strings.Split(s, sepStr), nil
+		}
+		return strings.SplitN(s, sepStr, int(min(maxSplit, math.MaxInt32))+1), nil
+	}
+
+	var rv []string
+	for {
+		s = strings.TrimLeftFunc(s, unicode.IsSpace)
+		if s == "" {
+			return rv, nil
+		}
+		if maxSplit >= 0 && int64(len(rv)) == maxSplit {
+			return append(rv, s), nil
+		}
+		i := strings.IndexFunc(s, unicode.IsSpace)
+		if i == -1 {
+			return append(rv, s), nil
+		}
+		rv = append(rv, s[:i])
+		s = s[i:]
+	}
+}
+
+// Splits a string into a list of lines.
+//
+// Lines are split at line boundaries like `\n`, `\r\n` and `\r`, which are
+// not included in the result.  A trailing line boundary does not produce
+// an empty last line.
+//
+// ```jinja
+// {% for line in text|lines %}
+//
+//	<p>{{ line }}</p>
+//
+// {% endfor %}
+// ```
+func linesFilter(s string) []string {
+	var rv []string
+	for s != "" {
+		i := strings.IndexFunc(s, isLineBoundary)
+		if i == -1 {
+			return append(rv, s)
+		}
+		rv = append(rv, s[:i])
+		if strings.HasPrefix(s[i:], "\r\n") {
+			s = s[i+2:]
+		} else {
+			_, size := utf8.DecodeRuneInString(s[i:])
+			s = s[i+size:]
+		}
+	}
+	return rv
+}
+
+// isLineBoundary reports whether r is one of the line boundaries of
+// Python's str.splitlines.
+func isLineBoundary(r rune) bool {
+	switch r {
+	case '\n', '\r', '\v', '\f', '\x1c', '\x1d', '\x1e', '\u0085', '\u2028', '\u2029':
+		return true
+	}
+	return false
+}
+
+func indentFilter(val string, width uint, indentFirstLine, indentBlankLines option.Option[bool]) string {
+	stripTrailingNewline := func(s *string) {
+		if strings.HasSuffix(*s, "\n") {
+			*s = (*s)[:len(*s)-1]
+		}
+		if strings.HasSuffix(*s, "\r") {
+			*s = (*s)[:len(*s)-1]
 		}
 	}
 
@@ -1242,48 +1851,17 @@ func indentFilter(val string, width uint, indentFirstLine, indentBlankLines opti
 	return rv
 }
 
+// URL encodes a value.
+//
+// If given a map it encodes the parameters into a query set, otherwise it
+// encodes the stringified value.  If the value is none or undefined, an
+// empty string is returned.
+//
+// ```jinja
+// <a href="/search?{{ {"q": "my search", "lang": "fr"}|urlencode }}">Search</a>
+// ```
 func urlencodeFilter(val Value) (string, error) {
-	shouldEscape := func(c rune) bool {
-		return !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
-			c == '/' || c == '.' || c == '-' || c == '_')
-	}
-
-	urlEscape := func(s string) string {
-		// This implementation is adapted from Go's net/url.escape function.
-		hexCount := 0
-		for _, c := range s {
-			if shouldEscape(c) {
-				hexCount++
-			}
-		}
-		if hexCount == 0 {
-			return s
-		}
-
-		var buf [64]byte
-		var t []byte
-		required := len(s) + 2*hexCount
-		if required <= len(buf) {
-			t = buf[:required]
-		} else {
-			t = make([]byte, required)
-		}
-		j := 0
-		for i, c := range s {
-			if shouldEscape(c) {
-				const upperhex = "0123456789ABCDEF"
-				t[j] = '%'
-				t[j+1] = upperhex[c>>4]
-				t[j+2] = upperhex[c&15]
-				j += 3
-			} else {
-				t[j] = s[i]
-				j++
-			}
-		}
-		return string(t)
-	}
-
+	const safe = "/.-_"
 	if val.Kind() == ValueKindMap {
 		iter, err := val.tryIter()
 		if err != nil {
@@ -1299,7 +1877,7 @@ func urlencodeFilter(val Value) (string, error) {
 			if err != nil {
 				return "", err
 			}
-			fmt.Fprintf(&b, "%s=%s", urlEscape(k.String()), urlEscape(v.String()))
+			fmt.Fprintf(&b, "%s=%s", urlEscape(k.String(), safe, false), urlEscape(v.String(), safe, false))
 		}
 		return b.String(), nil
 	}
@@ -1307,70 +1885,514 @@ func urlencodeFilter(val Value) (string, error) {
 	case noneValue, undefinedValue:
 		return "", nil
 	case bytesValue:
-		return urlEscape(string(v.B)), nil
+		return urlEscape(string(v.B), safe, false), nil
 	case stringValue:
-		return urlEscape(v.Str), nil
+		return urlEscape(v.Str, safe, false), nil
 	default:
-		return urlEscape(val.String()), nil
+		return urlEscape(val.String(), safe, false), nil
 	}
 }
 
-func selectOrReject(state *State, invert bool, val Value, attr, testName option.Option[string], args ...Value) ([]Value, error) {
-	var rv []Value
+// urlEscape percent-encodes the bytes of s except ASCII letters, digits
+// and the characters in safe.  If plus is true, spaces are encoded as `+`.
+func urlEscape(s, safe string, plus bool) string {
+	// This implementation is adapted from Go's net/url.escape function.
+	shouldEscape := func(c byte) bool {
+		return !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+			strings.IndexByte(safe, c) != -1)
+	}
+
+	spaceCount, hexCount := 0, 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if shouldEscape(c) {
+			if c == ' ' && plus {
+				spaceCount++
+			} else {
+				hexCount++
+			}
+		}
+	}
+	if spaceCount == 0 && hexCount == 0 {
+		return s
+	}
+
+	var buf [64]byte
+	var t []byte
+	required := len(s) + 2*hexCount
+	if required <= len(buf) {
+		t = buf[:required]
+	} else {
+		t = make([]byte, required)
+	}
+	j := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == ' ' && plus:
+			t[j] = '+'
+			j++
+		case shouldEscape(c):
+			const upperhex = "0123456789ABCDEF"
+			t[j] = '%'
+			t[j+1] = upperhex[c>>4]
+			t[j+2] = upperhex[c&15]
+			j += 3
+		default:
+			t[j] = c
+			j++
+		}
+	}
+	return string(t)
+}
+
+// Quotes a value for use in a query string.
+//
+// Unlike `urlencode` this also escapes `/` and encodes spaces as `+` like
+// Python's `urllib.parse.quote_plus`.  Additional characters which should
+// not be escaped can be given with the `safe` argument.
+//
+// ```jinja
+// {{ "a b/c&d"|quote_plus }}
+//
+//	-> a+b%2Fc%26d
+//
+// ```
+func quotePlus(val Value, safe option.Option[string]) (string, error) {
+	b, err := valueAsBytes(val)
+	if err != nil {
+		return "", err
+	}
+	return urlEscape(string(b), "_.-~"+safe.UnwrapOr(""), true), nil
+}
+
+// Decodes a URL encoded value.
+//
+// Percent escapes are decoded and `+` is decoded as a space.
+//
+// ```jinja
+// {{ "a+b%2Fc%26d"|urldecode }}
+//
+//	-> a b/c&d
+//
+// ```
+func urldecode(s string) (string, error) {
+	rv, err := url.QueryUnescape(s)
+	if err != nil {
+		return "", NewError(InvalidOperation, "invalid URL encoding").withSource(err)
+	}
+	return rv, nil
+}
+
+var (
+	urlizeHTTPRe = regexp.MustCompile(`(?i)^((https?://|www\.)(([\pL\pN_%-]+\.)+)?([a-z]{2,63}|xn--[\pL\pN_%]{2,59})|` +
+		`([\pL\pN_%-]{2,63}\.)+(com|net|int|edu|gov|org|info|mil)|` +
+		`(https?://)((([\d]{1,3})(\.[\d]{1,3}){3})|(\[([\da-f]{0,4}:){2}([\da-f]{0,4}:?){1,6}\])))` +
+		`(?::[\d]{1,5})?(?:[/?#]\S*)?$`)
+	urlizeEmailRe = regexp.MustCompile(`^\S+@[\pL\pN_][\pL\pN_.-]*\.[\pL\pN_]+$`)
+
+	// urlizeEscaper is like htmlEscaper but keeps slashes so that the
+	// generated links stay readable.
+	urlizeEscaper = strings.NewReplacer(
+		`&`, "&amp;",
+		`'`, "&#x27;",
+		`<`, "&lt;",
+		`>`, "&gt;",
+		`"`, "&quot;",
+	)
+)
+
+// Converts URLs and email addresses in text into clickable links.
+//
+// Words starting with `http://`, `https://` or `www.`, bare domains ending
+// in a common top level domain, and email addresses are turned into links.
+// The rest of the text is HTML escaped unless the value is already safe.
+// Surrounding parentheses and trailing punctuation are not part of a link.
+//
+// Keyword arguments:
+//
+//   - `trim_url_limit`: shortens the link texts to this many characters
+//     followed by `...`.
+//   - `nofollow`: adds `nofollow` to the `rel` attribute.
+//   - `target`: the `target` attribute of the links.
+//   - `rel`: additional space separated values of the `rel` attribute which
+//     always contains `noopener`.
+//
+// ```jinja
+// {{ "see www.example.com/docs, or mail info@example.com"|urlize }}
+//
+//	-> see <a href="https://www.example.com/docs" rel="noopener">www.example.com/docs</a>, or mail <a href="mailto:info@example.com">info@example.com</a>
+//
+// ```
+func urlizeFilter(state *State, val Value, args ...Value) (Value, error) {
+	boundArgs, err := bindArgs(args, "trim_url_limit", "nofollow", "target", "rel")
+	if err != nil {
+		return Value{}, err
+	}
+	trimLimit, err := argOr(boundArgs[0], option.None[uint](), func(v Value) (option.Option[uint], error) {
+		n, err := valueTryToGoUint(v)
+		return option.Some(n), err
+	})
+	if err != nil {
+		return Value{}, err
+	}
+	nofollow, err := argOr(boundArgs[1], false, valueTryToGoBool)
+	if err != nil {
+		return Value{}, err
+	}
+	target, err := argOr(boundArgs[2], "", valueTryToGoString)
+	if err != nil {
+		return Value{}, err
+	}
+	rel, err := argOr(boundArgs[3], "", valueTryToGoString)
+	if err != nil {
+		return Value{}, err
+	}
+
+	rels := append(strings.Fields(rel), "noopener")
+	if nofollow {
+		rels = append(rels, "nofollow")
+	}
+	slices.Sort(rels)
+	attrs := fmt.Sprintf(` rel="%s"`, urlizeEscaper.Replace(strings.Join(slices.Compact(rels), " ")))
+	if target != "" {
+		attrs += fmt.Sprintf(` target="%s"`, urlizeEscaper.Replace(target))
+	}
+
+	var text string
+	if !valueAsOptionString(val).UnwrapTo(&text) {
+		text = val.String()
+	}
+	escape := urlizeEscaper.Replace
+	if val.isSafe() {
+		escape = func(s string) string { return s }
+	}
+	trim := func(s string) string {
+		var limit uint
+		if trimLimit.UnwrapTo(&limit) && uint(utf8.RuneCountInString(s)) > limit {
+			return string([]rune(s)[:limit]) + "..."
+		}
+		return s
+	}
+
+	var b strings.Builder
+	for len(text) > 0 {
+		i := strings.IndexFunc(text, unicode.IsSpace)
+		if i == 0 {
+			if i = strings.IndexFunc(text, func(r rune) bool { return !unicode.IsSpace(r) }); i == -1 {
+				i = len(text)
+			}
+			b.WriteString(text[:i])
+			text = text[i:]
+			continue
+		} else if i == -1 {
+			i = len(text)
+		}
+		word := text[:i]
+		text = text[i:]
+
+		middle := strings.TrimLeft(word, "(<")
+		head := word[:len(word)-len(middle)]
+		tail := middle[len(strings.TrimRight(middle, ")>.,")):]
+		middle = middle[:len(middle)-len(tail)]
+		// Prefer balancing parentheses in URLs instead of ignoring a
+		// trailing character.
+		for _, pair := range [...][2]string{{"(", ")"}, {"<", ">"}} {
+			startCount := strings.Count(middle, pair[0])
+			if startCount <= strings.Count(middle, pair[1]) {
+				continue
+			}
+			for n := min(startCount, strings.Count(tail, pair[1])); n > 0; n-- {
+				end := strings.Index(tail, pair[1]) + len(pair[1])
+				middle += tail[:end]
+				tail = tail[end:]
+			}
+		}
+
+		b.WriteString(escape(head))
+		switch {
+		case urlizeHTTPRe.MatchString(middle):
+			href := middle
+			if !strings.HasPrefix(middle, "https://") && !strings.HasPrefix(middle, "http://") {
+				href = "https://" + middle
+			}
+			fmt.Fprintf(&b, `<a href="%s"%s>%s</a>`, escape(href), attrs, escape(trim(middle)))
+		case strings.HasPrefix(middle, "mailto:") && urlizeEmailRe.MatchString(middle[len("mailto:"):]):
+			fmt.Fprintf(&b, `<a href="%s">%s</a>`, escape(middle), escape(middle[len("mailto:"):]))
+		case strings.Contains(middle, "@") && !strings.HasPrefix(middle, "www.") &&
+			!strings.Contains(middle, ":") && urlizeEmailRe.MatchString(middle):
+			fmt.Fprintf(&b, `<a href="mailto:%s">%s</a>`, escape(middle), escape(middle))
+		default:
+			b.WriteString(escape(middle))
+		}
+		b.WriteString(escape(tail))
+	}
+	if state.AutoEscape().isNone() {
+		return valueFromString(b.String()), nil
+	}
+	return ValueFromSafeString(b.String()), nil
+}
+
+// Formats a number of bytes as a human readable file size.
+//
+// By default decimal prefixes (`kB`, `MB`, `GB`, ...) are used.  If
+// `binary` is true, binary prefixes (`KiB`, `MiB`, `GiB`, ...) are used
+// instead.  The value can be a number or a string containing a number.
+//
+// ```jinja
+// {{ 1234567|filesizeformat }}
+//
+//	-> 1.2 MB
+//
+// {{ 1234567|filesizeformat(true) }}
+//
+//	-> 1.2 MiB
+//
+// ```
+func filesizeformat(val Value, binary option.Option[bool]) (string, error) {
+	var size float64
+	if s := ""; valueAsOptionString(val).UnwrapTo(&s) {
+		var err error
+		if size, err = strconv.ParseFloat(strings.TrimSpace(s), 64); err != nil {
+			return "", NewError(InvalidOperation, "cannot convert string to number").withSource(err)
+		}
+	} else if !val.asF64().UnwrapTo(&size) || val.Kind() != ValueKindNumber {
+		return "", NewError(InvalidOperation,
+			fmt.Sprintf("cannot format %s as file size", val.Kind()))
+	}
+
+	base := 1000.0
+	prefixes := []string{"kB", "MB", "GB", "TB", "PB", "EB", "ZB", "YB"}
+	if binary.UnwrapOr(false) {
+		base = 1024.0
+		prefixes = []string{"KiB", "MiB", "GiB", "TiB", "PiB", "EiB", "ZiB", "YiB"}
+	}
+	switch {
+	case size == 1:
+		return "1 Byte", nil
+	case size < base:
+		return fmt.Sprintf("%d Bytes", int64(size)), nil
+	}
+	unit := base
+	for _, prefix := range prefixes {
+		unit *= base
+		if size < unit || prefix == prefixes[len(prefixes)-1] {
+			return fmt.Sprintf("%.1f %s", base*size/unit, prefix), nil
+		}
+	}
+	panic("unreachable")
+}
+
+// valueAsBytes returns the content of a string or bytes value.
+func valueAsBytes(val Value) ([]byte, error) {
+	switch v := val.data.(type) {
+	case stringValue:
+		return []byte(v.Str), nil
+	case bytesValue:
+		return v.B, nil
+	}
+	return nil, NewError(InvalidOperation, fmt.Sprintf("expected string or bytes, got %s", val.Kind()))
+}
+
+// Encodes a string or bytes with base64.
+//
+// If `urlsafe` is true, the URL safe alphabet is used.
+//
+// ```jinja
+// {{ "s3cr3t"|b64encode }}
+//
+//	-> czNjcjN0
+//
+// ```
+func b64encode(val Value, args ...Value) (string, error) {
+	bound, err := bindArgs(args, "urlsafe")
+	if err != nil {
+		return "", err
+	}
+	urlsafe, err := argOr(bound[0], false, valueTryToGoBool)
+	if err != nil {
+		return "", err
+	}
+	b, err := valueAsBytes(val)
+	if err != nil {
+		return "", err
+	}
+	if urlsafe {
+		return base64.URLEncoding.EncodeToString(b), nil
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// Decodes a base64 encoded string.
+//
+// Both the standard and the URL safe alphabet are accepted with or without
+// padding.  The result is a string if it is valid UTF-8 and bytes
+// otherwise.
+//
+// ```jinja
+// {{ "czNjcjN0"|b64decode }}
+//
+//	-> s3cr3t
+//
+// ```
+func b64decode(val Value) (Value, error) {
+	b, err := valueAsBytes(val)
+	if err != nil {
+		return Value{}, err
+	}
+	s := strings.TrimRight(string(b), "=")
+	enc := base64.RawStdEncoding
+	if strings.ContainsAny(s, "-_") {
+		enc = base64.RawURLEncoding
+	}
+	decoded, err := enc.DecodeString(s)
+	if err != nil {
+		return Value{}, NewError(InvalidOperation, "invalid base64 data").withSource(err)
+	}
+	if utf8.Valid(decoded) {
+		return valueFromString(string(decoded)), nil
+	}
+	return valueFromBytes(decoded), nil
+}
+
+// Encodes a string or bytes as lowercase hexadecimal digits.
+//
+// ```jinja
+// {{ "hi!"|hexencode }}
+//
+//	-> 686921
+//
+// ```
+func hexencode(val Value) (string, error) {
+	b, err := valueAsBytes(val)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Returns the hexadecimal SHA-1 digest of a string or bytes.
+//
+// ```jinja
+// {{ "abc"|sha1 }}
+//
+//	-> a9993e364706816aba3e25717850c26c9cd0d89d
+//
+// ```
+func sha1Filter(val Value) (string, error) {
+	return hexDigest(val, sha1.New())
+}
+
+// Returns the hexadecimal SHA-256 digest of a string or bytes.
+//
+// ```jinja
+// {{ "abc"|sha256 }}
+//
+//	-> ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad
+//
+// ```
+func sha256Filter(val Value) (string, error) {
+	return hexDigest(val, sha256.New())
+}
+
+// Returns the hexadecimal MD5 digest of a string or bytes.
+//
+// ```jinja
+// {{ "abc"|md5 }}
+//
+//	-> 900150983cd24fb0d6963f7d28e17f72
+//
+// ```
+func md5Filter(val Value) (string, error) {
+	return hexDigest(val, md5.New())
+}
+
+func hexDigest(val Value, h hash.Hash) (string, error) {
+	b, err := valueAsBytes(val)
+	if err != nil {
+		return "", err
+	}
+	h.Write(b)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func selectOrReject(state *State, invert bool, val Value, attr, testName option.Option[string], args ...Value) (Value, error) {
 	test := option.None[BoxedTest]()
 	if testName.IsSome() {
 		test = state.Env().getTest(testName.Unwrap())
 		if test.IsNone() {
-			return nil, NewError(UnknownTest, "")
+			return Value{}, NewError(UnknownTest, "")
 		}
 	}
-	iter, err := state.UndefinedBehavior().tryIter(val)
-	if err != nil {
-		return nil, err
-	}
-	for item := (Value{}); iter.Next().UnwrapTo(&item); {
+	selected := func(item Value) (bool, error) {
 		var testVal Value
 		if attr.IsSome() {
+			var err error
 			testVal, err = getAttr(item, attr.Unwrap())
 			if err != nil {
-				return nil, err
+				return false, err
 			}
 		} else {
 			testVal = item.clone()
 		}
-		var passed bool
-		if test.IsSome() {
-			iter, _ := valueFromSlice([]Value{testVal}).tryIter()
-			iter2, _ := valueFromSlice(args).tryIter()
-			chainedIter := iter.Chain(iter2.Cloned())
-			testArgs := chainedIter.Collect()
-			passed, err = test.Unwrap()(state, testArgs)
-			if err != nil {
-				return nil, err
+		if test.IsNone() {
+			return testVal.isTrue() != invert, nil
+		}
+		iter, _ := valueFromSlice([]Value{testVal}).tryIter()
+		iter2, _ := valueFromSlice(args).tryIter()
+		chainedIter := iter.Chain(iter2.Cloned())
+		testArgs, err := chainedIter.Collect()
+		if err != nil {
+			return false, err
+		}
+		passed, err := test.Unwrap()(state, testArgs)
+		if err != nil {
+			return false, err
+		}
+		return passed != invert, nil
+	}
+	if obj, ok := valueAsIterableObject(val); ok {
+		return ValueFromObject(&mappedIterableObject{obj: obj, f: func(item Value) (option.Option[Value], error) {
+			ok, err := selected(item)
+			if err != nil || !ok {
+				return option.None[Value](), err
 			}
-		} else {
-			passed = testVal.isTrue()
+			return option.Some(item), nil
+		}}), nil
+	}
+
+	iter, err := state.UndefinedBehavior().tryIter(val)
+	if err != nil {
+		return Value{}, err
+	}
+	var rv []Value
+	for item := (Value{}); iter.Next().UnwrapTo(&item); {
+		ok, err := selected(item)
+		if err != nil {
+			return Value{}, err
 		}
-		if passed != invert {
+		if ok {
 			rv = append(rv, item)
 		}
 	}
-	return rv, nil
+	if err := iter.Err(); err != nil {
+		return Value{}, err
+	}
+	return valueFromSlice(rv), nil
 }
 
-func selectFilter(state *State, val Value, testName option.Option[string], args ...Value) ([]Value, error) {
+func selectFilter(state *State, val Value, testName option.Option[string], args ...Value) (Value, error) {
 	return selectOrReject(state, false, val, option.None[string](), testName, args...)
 }
 
-func selectAttrFilter(state *State, val Value, attr string, testName option.Option[string], args ...Value) ([]Value, error) {
+func selectAttrFilter(state *State, val Value, attr string, testName option.Option[string], args ...Value) (Value, error) {
 	return selectOrReject(state, false, val, option.Some(attr), testName, args...)
 }
 
-func rejectFilter(state *State, val Value, testName option.Option[string], args ...Value) ([]Value, error) {
+func rejectFilter(state *State, val Value, testName option.Option[string], args ...Value) (Value, error) {
 	return selectOrReject(state, true, val, option.None[string](), testName, args...)
 }
 
-func rejectAttrFilter(state *State, val Value, attr string, testName option.Option[string], args ...Value) ([]Value, error) {
+func rejectAttrFilter(state *State, val Value, attr string, testName option.Option[string], args ...Value) (Value, error) {
 	return selectOrReject(state, true, val, option.Some(attr), testName, args...)
 }
 
@@ -1424,8 +2446,7 @@ func uniqueFilter(values []Value) Value {
 // ```jinja
 // Users on this page: {{ titles|map('lower')|join(', ') }}
 // ```
-func mapFilter(state *State, val Value, args ...Value) ([]Value, error) {
-	rv := make([]Value, 0, val.len().UnwrapOr(0))
+func mapFilter(state *State, val Value, args ...Value) (Value, error) {
 	var kwargs Kwargs
 	var err error
 	if len(args) == 0 {
@@ -1439,17 +2460,15 @@ func mapFilter(state *State, val Value, args ...Value) ([]Value, error) {
 		}
 	}
 
+	var f func(item Value) (Value, error)
 	if attrVal := (Value{}); kwargs.GetValue("attribute").UnwrapTo(&attrVal) {
 		if len(args) != 0 {
-			return nil, NewError(TooManyArguments, "")
+			return Value{}, NewError(TooManyArguments, "")
 		}
 		defVal := kwargs.GetValue("default").UnwrapOr(Undefined)
-		iter, err := state.UndefinedBehavior().tryIter(val)
-		if err != nil {
-			return nil, err
-		}
-		for item := (Value{}); iter.Next().UnwrapTo(&item); {
+		f = func(item Value) (Value, error) {
 			var subVal Value
+			var err error
 			if path := ""; valueAsOptionString(attrVal).UnwrapTo(&path) {
 				subVal, err = getPath(item, path)
 			} else {
@@ -1457,76 +2476,648 @@ func mapFilter(state *State, val Value, args ...Value) ([]Value, error) {
 			}
 			if err != nil {
 				if defVal.isUndefined() {
-					return nil, err
+					return Value{}, err
 				}
-				subVal = defVal.clone()
+				return defVal.clone(), nil
 			} else if subVal.isUndefined() {
-				subVal = defVal.clone()
+				return defVal.clone(), nil
 			}
-			rv = append(rv, subVal)
+			return subVal, nil
+		}
+	} else {
+		// filter mapping
+		if len(args) == 0 {
+			return Value{}, NewError(InvalidOperation, "filter name is required")
+		}
+		filterNameVal := args[0]
+		optFilterName := valueAsOptionString(filterNameVal)
+		if optFilterName.IsNone() {
+			return Value{}, NewError(InvalidOperation, "filter name must be a string")
+		}
+		filterName := optFilterName.Unwrap()
+		optFilter := state.Env().getFilter(filterName)
+		if optFilter.IsNone() {
+			return Value{}, NewError(UnknownFilter, "")
+		}
+		filter := optFilter.Unwrap()
+		f = func(item Value) (Value, error) {
+			iter2, _ := valueFromSlice([]Value{item.clone()}).tryIter()
+			iter3, _ := valueFromSlice(args[1:]).tryIter()
+			iter4 := iter2.Chain(iter3.Cloned())
+			newArgs, err := iter4.Collect()
+			if err != nil {
+				return Value{}, err
+			}
+			return filter(state, newArgs)
 		}
-		return rv, nil
 	}
 
-	// filter mapping
-	if len(args) == 0 {
-		return nil, NewError(InvalidOperation, "filter name is required")
-	}
-	filterNameVal := args[0]
-	optFilterName := valueAsOptionString(filterNameVal)
-	if optFilterName.IsNone() {
-		return nil, NewError(InvalidOperation, "filter name must be a string")
-	}
-	filterName := optFilterName.Unwrap()
-	optFilter := state.Env().getFilter(filterName)
-	if optFilter.IsNone() {
-		return nil, NewError(UnknownFilter, "")
+	if obj, ok := valueAsIterableObject(val); ok {
+		return ValueFromObject(&mappedIterableObject{obj: obj, f: func(item Value) (option.Option[Value], error) {
+			rv, err := f(item)
+			if err != nil {
+				return option.None[Value](), err
+			}
+			return option.Some(rv), nil
+		}}), nil
 	}
-	filter := optFilter.Unwrap()
+
 	iter, err := state.UndefinedBehavior().tryIter(val)
 	if err != nil {
-		return nil, err
+		return Value{}, err
 	}
+	rv := make([]Value, 0, val.len().UnwrapOr(0))
 	for item := (Value{}); iter.Next().UnwrapTo(&item); {
-		iter2, _ := valueFromSlice([]Value{item.clone()}).tryIter()
-		iter3, _ := valueFromSlice(args[1:]).tryIter()
-		iter4 := iter2.Chain(iter3.Cloned())
-		newArgs := iter4.Collect()
-		rvItem, err := filter(state, newArgs)
+		rvItem, err := f(item)
 		if err != nil {
-			return nil, err
+			return Value{}, err
 		}
 		rv = append(rv, rvItem)
 	}
+	if err := iter.Err(); err != nil {
+		return Value{}, err
+	}
+	return valueFromSlice(rv), nil
+}
 
-	return rv, nil
+// Serializes a value to JSON.
+//
+// The result is marked safe so it can be embedded in HTML and in
+// JavaScript.  The characters `<`, `>`, `&` and `'` are escaped in
+// strings.  The `indent` argument pretty prints the output with the given
+// number of spaces or with two spaces if it is true.  Keys of maps are
+// sorted unless `sort_keys` is false in which case the order of the map
+// is kept.
+//
+// ```jinja
+// <script>const CONFIG = {{ config|tojson }};</script>
+// {{ {"b": 1, "a": [2]}|tojson(indent=2, sort_keys=false) }}
+//
+//	-> {
+//	->   "b": 1,
+//	->   "a": [
+//	->     2
+//	->   ]
+//	-> }
+//
+// ```
+func tojson(val Value, args ...Value) (Value, error) {
+	bound, err := bindArgs(args, "indent", "sort_keys")
+	if err != nil {
+		return Value{}, err
+	}
+	indent := ""
+	var indentVal Value
+	if bound[0].UnwrapTo(&indentVal) {
+		switch v := indentVal.data.(type) {
+		case boolValue:
+			if v.B {
+				indent = "  "
+			}
+		case noneValue, undefinedValue:
+		default:
+			n, err := valueTryToGoUint(indentVal)
+			if err != nil {
+				return Value{}, err
+			}
+			indent = strings.Repeat(" ", int(n))
+		}
+	}
+	sortKeys, err := argOr(bound[1], true, valueTryToGoBool)
+	if err != nil {
+		return Value{}, err
+	}
+	s, err := marshalJSON(val, indent, sortKeys)
+	if err != nil {
+		return Value{}, err
+	}
+	return ValueFromSafeString(s), nil
 }
 
-var jsonReplacer = strings.NewReplacer("<", `\u003c`, ">", `\u003e`, "&", `\u0026`, `'`, `\u0027`)
+// Serializes a value to YAML.
+//
+// The output uses block style with `indent` spaces (default 2) for each
+// level and keeps the order of maps unless `sort_keys` is true.  Like
+// `tojson` the result is marked safe as `<`, `>`, `&` and `'` only appear
+// escaped in quoted strings.
+//
+// ```jinja
+// {{ {"name": "web", "ports": [80, 443]}|toyaml }}
+//
+//	-> name: web
+//	-> ports:
+//	->   - 80
+//	->   - 443
+//
+// ```
+func toyaml(val Value, args ...Value) (Value, error) {
+	bound, err := bindArgs(args, "indent", "sort_keys")
+	if err != nil {
+		return Value{}, err
+	}
+	indent, err := argOr(bound[0], 2, valueTryToGoInt)
+	if err != nil {
+		return Value{}, err
+	}
+	if indent < 1 || indent > 9 {
+		return Value{}, NewError(InvalidOperation, fmt.Sprintf("expected 1 <= indent <= 9, got %d", indent))
+	}
+	sortKeys, err := argOr(bound[1], false, valueTryToGoBool)
+	if err != nil {
+		return Value{}, err
+	}
+	data, err := valueToData(val, sortKeys)
+	if err != nil {
+		return Value{}, err
+	}
+	w := yamlWriter{indent: indent}
+	switch v := data.(type) {
+	case dataMap:
+		if len(v) != 0 {
+			err = w.writeMap(v, 0, false)
+			break
+		}
+		err = w.writeScalar(v, indent)
+	case []any:
+		if len(v) != 0 {
+			err = w.writeSeq(v, 0, false)
+			break
+		}
+		err = w.writeScalar(v, indent)
+	default:
+		err = w.writeScalar(v, indent)
+	}
+	if err != nil {
+		return Value{}, NewError(InvalidOperation, "cannot serialize to YAML").withSource(err)
+	}
+	return ValueFromSafeString(strings.TrimSuffix(w.b.String(), "\n")), nil
+}
 
-func tojson(val Value, pretty option.Option[bool]) (Value, error) {
-	jsonObj, err := valueTryToJSONObject(val)
+// Serializes a map to TOML.
+//
+// Nested maps become tables and lists of maps become arrays of tables.
+// The order of maps is kept unless `sort_keys` is true, except that plain
+// values are written before the tables of a map as required by TOML.
+// None values cannot be represented in TOML and cause an error.  Like
+// `tojson` the result is marked safe.
+//
+// ```jinja
+// {{ {"title": "demo", "server": {"port": 8080}}|totoml }}
+//
+//	-> title = "demo"
+//	->
+//	-> [server]
+//	-> port = 8080
+//
+// ```
+func totoml(val Value, args ...Value) (Value, error) {
+	bound, err := bindArgs(args, "sort_keys")
 	if err != nil {
-		return Value{}, NewError(InvalidOperation, "cannot serialize to JSON")
+		return Value{}, err
 	}
-	var b strings.Builder
-	encoder := json.NewEncoder(&b)
-	encoder.SetEscapeHTML(false)
-	if pretty.UnwrapOr(false) {
-		encoder.SetIndent("", "  ")
+	sortKeys, err := argOr(bound[0], false, valueTryToGoBool)
+	if err != nil {
+		return Value{}, err
 	}
-	if err := encoder.Encode(jsonObj); err != nil {
-		return Value{}, NewError(InvalidOperation, "cannot serialize to JSON")
+	data, err := valueToData(val, sortKeys)
+	if err != nil {
+		return Value{}, err
 	}
-	marshaled := b.String()
-	s := jsonReplacer.Replace(strings.TrimSuffix(marshaled, "\n"))
-	return ValueFromSafeString(s), nil
+	m, ok := data.(dataMap)
+	if !ok {
+		return Value{}, NewError(InvalidOperation, "cannot serialize to TOML: value is not a map")
+	}
+	var w tomlWriter
+	if err := w.writeTable(m, nil); err != nil {
+		return Value{}, NewError(InvalidOperation, "cannot serialize to TOML").withSource(err)
+	}
+	return ValueFromSafeString(strings.TrimSuffix(w.b.String(), "\n")), nil
+}
+
+// Parses a JSON string into a value.
+//
+// The order of the keys of objects is kept.
+//
+// ```jinja
+// {% set config = '{"debug": true, "port": 8080}'|fromjson %}
+// {{ config.port }}
+//
+//	-> 8080
+//
+// ```
+func fromjson(s string) (Value, error) {
+	return unmarshalJSON(s)
+}
+
+// Parses a YAML string into a value.
+//
+// The document is read with the YAML 1.2 core schema and the order of the
+// keys of mappings is kept.  Anchors, aliases, tags and multiple documents
+// are not supported.
+//
+// ```jinja
+// {% set config = "debug: true\nports: [80, 443]"|fromyaml %}
+// {{ config.ports|join(",") }}
+//
+//	-> 80,443
+//
+// ```
+func fromyaml(s string) (Value, error) {
+	return unmarshalYAML(s)
 }
 
 func pprint(val Value) string {
 	return fmt.Sprintf(rustfmt.DebugPrettyString, val)
 }
 
+// Apply the values to a printf-style format string.
+//
+// This follows the semantics of Python's `%` operator.  Positional
+// arguments are consumed by `%s`, `%d`, `%f`, `%x`, `%r` and friends
+// which also support width and precision.  Keyword arguments can be
+// referenced by name with `%(name)s`.
+//
+// ```jinja
+// {{ "%s has %d items"|format(name, count) }}
+// {{ "%.2f"|format(price) }}
+// {{ "%(user)s logged in"|format(user="john") }}
+// ```
+func formatFilter(format string, args ...Value) (string, error) {
+	if len(args) > 0 {
+		last := args[len(args)-1]
+		if m, ok := last.data.(mapValue); ok && m.Type == mapTypeKwargs {
+			if len(args) > 1 {
+				return "", NewError(InvalidOperation,
+					"can't handle positional and keyword arguments at the same time")
+			}
+			return printfFormat(format, printfArgs{mapping: option.Some(last)})
+		}
+	}
+	return printfFormat(format, printfArgs{positional: args})
+}
+
+// Returns a truncated copy of the string.
+//
+// The length is specified with the first parameter which defaults to 255.
+// If the second parameter `killwords` is true the filter will cut the text
+// at length.  Otherwise it will discard the last word.  If the text was in
+// fact truncated it will append `end` which defaults to `"..."`.  Strings
+// which only exceed the length by the tolerance margin given by `leeway`
+// (default 5) are not truncated.  Lengths are counted in characters.
+//
+// ```jinja
+// {{ "foo bar baz qux"|truncate(9) }}
+//
+//	-> foo...
+//
+// {{ "foo bar baz qux"|truncate(9, true) }}
+//
+//	-> foo ba...
+//
+// {{ "foo bar baz qux"|truncate(11, end=" [...]", leeway=0) }}
+//
+//	-> foo [...]
+//
+// ```
+func truncateFilter(s string, args ...Value) (string, error) {
+	bound, err := bindArgs(args, "length", "killwords", "end", "leeway")
+	if err != nil {
+		return "", err
+	}
+	length, err := argOr(bound[0], 255, valueTryToGoInt)
+	if err != nil {
+		return "", err
+	}
+	killwords, err := argOr(bound[1], false, valueTryToGoBool)
+	if err != nil {
+		return "", err
+	}
+	end, err := argOr(bound[2], "...", valueTryToGoString)
+	if err != nil {
+		return "", err
+	}
+	leeway, err := argOr(bound[3], 5, valueTryToGoInt)
+	if err != nil {
+		return "", err
+	}
+
+	endLen := utf8.RuneCountInString(end)
+	if length < endLen {
+		return "", NewError(InvalidOperation, fmt.Sprintf("expected length >= %d, got %d", endLen, length))
+	}
+	if leeway < 0 {
+		return "", NewError(InvalidOperation, fmt.Sprintf("expected leeway >= 0, got %d", leeway))
+	}
+	runes := []rune(s)
+	if len(runes) <= length+leeway {
+		return s, nil
+	}
+	rv := string(runes[:length-endLen])
+	if !killwords {
+		if i := strings.LastIndexByte(rv, ' '); i != -1 {
+			rv = rv[:i]
+		}
+	}
+	return rv + end, nil
+}
+
+// Wrap a string to the given width.
+//
+// Existing newlines are treated as paragraphs to be wrapped separately.
+// The first parameter `width` defaults to 79.  If `break_long_words` is
+// false (default is true), words longer than width are not split.  The
+// lines are joined with `wrapstring` which defaults to a newline.
+//
+// ```jinja
+// {{ "a very long sentence"|wordwrap(8) }}
+//
+//	-> a very
+//	   long
+//	   sentence
+//
+// ```
+func wordwrapFilter(s string, args ...Value) (string, error) {
+	bound, err := bindArgs(args, "width", "break_long_words", "wrapstring")
+	if err != nil {
+		return "", err
+	}
+	width, err := argOr(bound[0], 79, valueTryToGoInt)
+	if err != nil {
+		return "", err
+	}
+	breakLongWords, err := argOr(bound[1], true, valueTryToGoBool)
+	if err != nil {
+		return "", err
+	}
+	wrapstring, err := argOr(bound[2], "\n", valueTryToGoString)
+	if err != nil {
+		return "", err
+	}
+	if width <= 0 {
+		return "", NewError(InvalidOperation, fmt.Sprintf("invalid width %d (must be > 0)", width))
+	}
+
+	s = strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", "\n"), "\r", "\n")
+	s = strings.TrimSuffix(s, "\n")
+	paragraphs := strings.Split(s, "\n")
+	wrapped := make([]string, 0, len(paragraphs))
+	for _, para := range paragraphs {
+		wrapped = append(wrapped, strings.Join(wrapLine(para, width, breakLongWords), wrapstring))
+	}
+	return strings.Join(wrapped, wrapstring), nil
+}
+
+// wrapLine splits a line into lines of at most width characters like
+// Python's textwrap.wrap.  Whitespace is kept inside lines but dropped at
+// the end of lines and at the start of all lines but the first one.
+func wrapLine(line string, width int, breakLongWords bool) []string {
+	// chunks alternate between words and runs of whitespace.
+	var chunks [][]rune
+	for _, r := range line {
+		isSpace := unicode.IsSpace(r)
+		if n := len(chunks); n > 0 && unicode.IsSpace(chunks[n-1][0]) == isSpace {
+			chunks[n-1] = append(chunks[n-1], r)
+		} else {
+			chunks = append(chunks, []rune{r})
+		}
+	}
+	isSpaceChunk := func(chunk []rune) bool { return unicode.IsSpace(chunk[0]) }
+
+	var lines []string
+	for len(chunks) > 0 {
+		if len(lines) > 0 && isSpaceChunk(chunks[0]) {
+			chunks = chunks[1:]
+			if len(chunks) == 0 {
+				break
+			}
+		}
+		var cur []rune
+		for len(chunks) > 0 && len(cur)+len(chunks[0]) <= width {
+			cur = append(cur, chunks[0]...)
+			chunks = chunks[1:]
+		}
+		if len(chunks) > 0 && len(chunks[0]) > width {
+			if breakLongWords {
+				n := max(width-len(cur), 1)
+				cur = append(cur, chunks[0][:n]...)
+				chunks[0] = chunks[0][n:]
+			} else if len(cur) == 0 {
+				cur = chunks[0]
+				chunks = chunks[1:]
+			}
+		}
+		end := len(cur)
+		for end > 0 && unicode.IsSpace(cur[end-1]) {
+			end--
+		}
+		if end > 0 {
+			lines = append(lines, string(cur[:end]))
+		}
+	}
+	return lines
+}
+
+// Centers the string in a field of the given width.
+//
+// The width defaults to 80.  Strings longer than the width are returned
+// unchanged.
+//
+// ```jinja
+// [{{ "foo"|center(9) }}]
+//
+//	-> [   foo   ]
+//
+// ```
+func centerFilter(s string, width option.Option[uint]) string {
+	w := int(width.UnwrapOr(80))
+	margin := w - utf8.RuneCountInString(s)
+	if margin <= 0 {
+		return s
+	}
+	// This is the same as Python's str.center.
+	left := margin/2 + (margin & w & 1)
+	return strings.Repeat(" ", left) + s + strings.Repeat(" ", margin-left)
+}
+
+var wordRe = regexp.MustCompile(`[\p{L}\p{M}\p{N}_]+`)
+
+// Counts the words in the string.
+//
+// A word is a sequence of letters, digits and underscores.
+//
+// ```jinja
+// {{ "Hello, world!"|wordcount }}
+//
+//	-> 2
+//
+// ```
+func wordcount(s string) uint {
+	return uint(len(wordRe.FindAllStringIndex(s, -1)))
+}
+
+// Strips SGML/XML tags and replaces adjacent whitespace with one space.
+//
+// HTML entities are decoded so the result is plain text which will be
+// escaped again by auto escaping.
+//
+// ```jinja
+// {{ "<p>Hello <b>World</b>\n &amp; co</p>"|striptags }}
+//
+//	-> Hello World &amp; co
+//
+// ```
+func striptags(s string) string {
+	s = stripDelimited(s, "<!--", "-->")
+	s = stripDelimited(s, "<", ">")
+	s = strings.Join(strings.Fields(s), " ")
+	return html.UnescapeString(s)
+}
+
+// stripDelimited removes all occurrences of text from start to end.  An
+// unclosed start is kept.
+func stripDelimited(s, start, end string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, start)
+		if i == -1 {
+			break
+		}
+		j := strings.Index(s[i+len(start):], end)
+		if j == -1 {
+			break
+		}
+		b.WriteString(s[:i])
+		s = s[i+len(start)+j+len(end):]
+	}
+	b.WriteString(s)
+	return b.String()
+}
+
+// Replaces matches of a regular expression.
+//
+// The pattern uses the syntax of Go's `regexp` package.  In the
+// `replacement` (default `""`) `$1` or `${name}` refer to the text of the
+// capturing groups.  `count` limits the number of replacements and
+// defaults to 0 which replaces all matches.  The `ignorecase` and
+// `multiline` arguments enable the corresponding flags.
+//
+// ```jinja
+// {{ "2024-01-31"|regex_replace("(\\d+)-(\\d+)-(\\d+)", "$3.$2.$1") }}
+//
+//	-> 31.01.2024
+//
+// {{ "a-b-c"|regex_replace("-", "+", count=1) }}
+//
+//	-> a+b-c
+//
+// ```
+func regexReplaceFilter(state *State, s, pattern string, args ...Value) (string, error) {
+	bound, err := bindArgs(args, "replacement", "ignorecase", "multiline", "count")
+	if err != nil {
+		return "", err
+	}
+	repl, err := argOr(bound[0], "", valueTryToGoString)
+	if err != nil {
+		return "", err
+	}
+	count, err := argOr(bound[3], 0, valueTryToGoInt)
+	if err != nil {
+		return "", err
+	}
+	if count < 0 {
+		return "", NewError(InvalidOperation, fmt.Sprintf("expected count >= 0, got %d", count))
+	}
+	re, err := compileRegex(state, pattern, bound[1], bound[2])
+	if err != nil {
+		return "", err
+	}
+	if count == 0 {
+		return re.ReplaceAllString(s, repl), nil
+	}
+	var b []byte
+	last := 0
+	for _, m := range re.FindAllStringSubmatchIndex(s, count) {
+		b = append(b, s[last:m[0]]...)
+		b = re.ExpandString(b, repl, s, m)
+		last = m[1]
+	}
+	b = append(b, s[last:]...)
+	return string(b), nil
+}
+
+// Returns the first match of a regular expression or none.
+//
+// The pattern uses the syntax of Go's `regexp` package.  The `ignorecase`
+// and `multiline` arguments enable the corresponding flags.
+//
+// ```jinja
+// {{ "fix: handle PROJ-123 timeout"|regex_search("[A-Z]+-\\d+") }}
+//
+//	-> PROJ-123
+//
+// ```
+func regexSearchFilter(state *State, s, pattern string, args ...Value) (Value, error) {
+	bound, err := bindArgs(args, "ignorecase", "multiline")
+	if err != nil {
+		return Value{}, err
+	}
+	re, err := compileRegex(state, pattern, bound[0], bound[1])
+	if err != nil {
+		return Value{}, err
+	}
+	loc := re.FindStringIndex(s)
+	if loc == nil {
+		return none, nil
+	}
+	return valueFromString(s[loc[0]:loc[1]]), nil
+}
+
+// Returns all non-overlapping matches of a regular expression.
+//
+// If the pattern has no capturing groups the matched strings are returned.
+// With one group the texts of the group are returned and with more groups
+// each match is a list of the group texts.  Groups which did not
+// participate in a match are empty strings.  The `ignorecase` and
+// `multiline` arguments enable the corresponding flags.
+//
+// ```jinja
+// {{ "a1 b22 c333"|regex_findall("\\d+") }}
+//
+//	-> ["1", "22", "333"]
+//
+// {{ "a=1, b=2"|regex_findall("(\\w)=(\\d)") }}
+//
+//	-> [["a", "1"], ["b", "2"]]
+//
+// ```
+func regexFindallFilter(state *State, s, pattern string, args ...Value) (Value, error) {
+	bound, err := bindArgs(args, "ignorecase", "multiline")
+	if err != nil {
+		return Value{}, err
+	}
+	re, err := compileRegex(state, pattern, bound[0], bound[1])
+	if err != nil {
+		return Value{}, err
+	}
+	matches := re.FindAllStringSubmatch(s, -1)
+	rv := make([]Value, 0, len(matches))
+	for _, m := range matches {
+		switch len(m) {
+		case 1:
+			rv = append(rv, valueFromString(m[0]))
+		case 2:
+			rv = append(rv, valueFromString(m[1]))
+		default:
+			groups := make([]Value, 0, len(m)-1)
+			for _, g := range m[1:] {
+				groups = append(groups, valueFromString(g))
+			}
+			rv = append(rv, valueFromSlice(groups))
+		}
+	}
+	return valueFromSlice(rv), nil
+}
+
 type filterObject struct {
 	name   string
 	filter BoxedFilter
@@ -32,3 +32,35 @@ func ExampleEnvironment_AddFilter() {
 	fmt.Println(got)
 	// Output: this-is-my-page
 }
+
+func ExampleValue_Iter() {
+	sum := func(val mjingo.Value) (mjingo.Value, error) {
+		iter, err := val.Iter()
+		if err != nil {
+			return mjingo.Value{}, err
+		}
+		var total int64
+		for item := (mjingo.Value{}); iter.Next().UnwrapTo(&item); {
+			var n int64
+			if !item.AsInt64().UnwrapTo(&n) {
+				return mjingo.Value{}, mjingo.NewError(mjingo.InvalidOperation, "not an integer")
+			}
+			total += n
+		}
+		return mjingo.ValueFromInt(total), nil
+	}
+
+	env := mjingo.NewEnvironment()
+	env.AddFilter("intsum", mjingo.BoxedFilterFromFixedArity1ArgWithErrFunc(sum))
+	context := mjingo.ValueFromMap(map[string]mjingo.Value{
+		"values": mjingo.ValueFromSlice([]mjingo.Value{
+			mjingo.ValueFromInt(1), mjingo.ValueFromInt(2), mjingo.ValueFromInt(3),
+		}),
+	})
+	got, err := env.RenderStr(`{{ values|intsum }}`, context)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(got)
+	// Output: 6
+}
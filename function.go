@@ -638,5 +638,8 @@ func dictFunc(val Value) (Value, error) {
 	case mapValue:
 		return valueFromIndexMap(v.Map), nil
 	}
+	if mapObj, ok := valueAsMapObject(val); ok {
+		return valueFromIndexMap(valueMapFromMapObject(mapObj)), nil
+	}
 	return Value{}, NewError(InvalidOperation, "")
 }
@@ -0,0 +1,274 @@
+package mjingo
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+
+	"github.com/hnakamur/mjingo/internal/rustfmt"
+	"github.com/hnakamur/mjingo/option"
+)
+
+// cyclerObject cycles through a list of values.
+//
+// Unlike `loop.cycle` it keeps its position across loops.
+type cyclerObject struct {
+	items []Value
+	pos   uint
+}
+
+var _ = (Object)((*cyclerObject)(nil))
+var _ = (CallMethoder)((*cyclerObject)(nil))
+var _ = (StructObject)((*cyclerObject)(nil))
+var _ = (rustfmt.Formatter)((*cyclerObject)(nil))
+
+// Creates a cycler which cycles through the given values.
+//
+// The cycler has a `next()` method which returns the current value and
+// advances to the next one, a `reset()` method which goes back to the
+// first value and a `current` attribute.  Unlike `loop.cycle` a cycler can
+// be used across multiple loops.
+//
+// ```jinja
+// {% set row_class = cycler("odd", "even") %}
+// {% for user in users %}<li class="{{ row_class.next() }}">{{ user }}</li>{% endfor %}
+// {% for group in groups %}<li class="{{ row_class.next() }}">{{ group }}</li>{% endfor %}
+// ```
+func cyclerFunc(items ...Value) (Value, error) {
+	if len(items) == 0 {
+		return Value{}, NewError(MissingArgument, "at least one item has to be provided")
+	}
+	return ValueFromObject(&cyclerObject{items: items}), nil
+}
+
+func (*cyclerObject) Kind() ObjectKind { return ObjectKindStruct }
+
+func (c *cyclerObject) CallMethod(_ *State, name string, args []Value) (Value, error) {
+	switch name {
+	case "next":
+		if len(args) > 0 {
+			return Value{}, NewError(TooManyArguments, "")
+		}
+		rv := c.items[c.pos]
+		c.pos = (c.pos + 1) % uint(len(c.items))
+		return rv.clone(), nil
+	case "reset":
+		if len(args) > 0 {
+			return Value{}, NewError(TooManyArguments, "")
+		}
+		c.pos = 0
+		return none, nil
+	}
+	return Value{}, NewError(UnknownMethod, fmt.Sprintf("cycler has no method named %s", name))
+}
+
+func (*cyclerObject) StaticFields() option.Option[[]string] {
+	return option.Some([]string{"current"})
+}
+
+func (*cyclerObject) Fields() []string { return nil }
+
+func (c *cyclerObject) GetField(name string) option.Option[Value] {
+	if name == "current" {
+		return option.Some(c.items[c.pos].clone())
+	}
+	return option.None[Value]()
+}
+
+func (*cyclerObject) SupportsCustomVerb(verb rune) bool {
+	return verb == rustfmt.DebugVerb || verb == rustfmt.DisplayVerb
+}
+
+func (c *cyclerObject) Format(f fmt.State, verb rune) {
+	switch verb {
+	case rustfmt.DisplayVerb:
+		io.WriteString(f, "<cycler>")
+	case rustfmt.DebugVerb:
+		s := rustfmt.NewDebugStruct("Cycler")
+		s.Field("items", valueFromSlice(c.items))
+		s.Field("current", c.items[c.pos])
+		s.Format(f, verb)
+	default:
+		// https://github.com/golang/go/issues/51195#issuecomment-1563538796
+		type hideMethods cyclerObject
+		type cyclerObject hideMethods
+		fmt.Fprintf(f, fmt.FormatString(f, verb), cyclerObject(*c))
+	}
+}
+
+// joinerObject returns an empty string when called for the first time and
+// the separator on all following calls.
+type joinerObject struct {
+	sep  string
+	used bool
+}
+
+var _ = (Object)((*joinerObject)(nil))
+var _ = (Caller)((*joinerObject)(nil))
+var _ = (rustfmt.Formatter)((*joinerObject)(nil))
+
+// Creates a joiner which helps to join multiple sections.
+//
+// A joiner returns an empty string when it is called for the first time
+// and the separator (by default `", "`) every time after that.  This is
+// useful to separate items which are only output under some conditions.
+//
+// ```jinja
+// {% set pipe = joiner("|") %}
+// {% if categories %}{{ pipe() }}Categories: {{ categories|join(", ") }}{% endif %}
+// {% if author %}{{ pipe() }}Author: {{ author }}{% endif %}
+// {% if can_edit %}{{ pipe() }}<a href="?action=edit">Edit</a>{% endif %}
+// ```
+func joinerFunc(sep option.Option[string]) Value {
+	return ValueFromObject(&joinerObject{sep: sep.UnwrapOr(", ")})
+}
+
+func (*joinerObject) Kind() ObjectKind { return ObjectKindPlain }
+
+func (j *joinerObject) Call(_ *State, args []Value) (Value, error) {
+	if len(args) > 0 {
+		return Value{}, NewError(TooManyArguments, "")
+	}
+	if !j.used {
+		j.used = true
+		return valueFromString(""), nil
+	}
+	return valueFromString(j.sep), nil
+}
+
+func (*joinerObject) SupportsCustomVerb(verb rune) bool {
+	return verb == rustfmt.DebugVerb || verb == rustfmt.DisplayVerb
+}
+
+func (j *joinerObject) Format(f fmt.State, verb rune) {
+	switch verb {
+	case rustfmt.DisplayVerb, rustfmt.DebugVerb:
+		fmt.Fprintf(f, "<joiner %q>", j.sep)
+	default:
+		// https://github.com/golang/go/issues/51195#issuecomment-1563538796
+		type hideMethods joinerObject
+		type joinerObject hideMethods
+		fmt.Fprintf(f, fmt.FormatString(f, verb), joinerObject(*j))
+	}
+}
+
+// lipsumSeed is the fixed seed of lipsum so that it always generates the
+// same text for the same arguments.
+const lipsumSeed = 1
+
+// lipsumMaxWords limits the number of words lipsum generates.
+const lipsumMaxWords = 100000
+
+var lipsumWords = strings.Fields(`a ac accumsan ad adipiscing aenean aliquam
+aliquet amet ante aptent arcu at auctor augue bibendum blandit class commodo
+condimentum congue consectetuer consequat conubia convallis cras cubilia
+cum curabitur curae cursus dapibus diam dictum dictumst dignissim dis dolor
+donec dui duis egestas eget eleifend elementum elit enim erat eros est et
+etiam eu euismod facilisi facilisis fames faucibus felis fermentum feugiat
+fringilla fusce gravida habitant habitasse hac hendrerit hymenaeos iaculis id
+imperdiet in inceptos integer interdum ipsum justo lacinia lacus laoreet
+lectus leo libero ligula litora lobortis lorem luctus maecenas magna magnis
+malesuada massa mattis mauris metus mi molestie mollis montes morbi mus nam
+nascetur natoque nec neque netus nibh nisi nisl non nonummy nostra nulla
+nullam nunc odio orci ornare parturient pede pellentesque penatibus per
+pharetra phasellus placerat platea porta porttitor posuere potenti praesent
+pretium primis proin pulvinar purus quam quis quisque rhoncus ridiculus risus
+rutrum sagittis sapien scelerisque sed sem semper senectus sit sociis
+sociosqu sodales sollicitudin suscipit suspendisse taciti tellus tempor
+tempus tincidunt torquent tortor tristique turpis ullamcorper ultrices
+ultricies urna ut varius vehicula vel velit venenatis vestibulum vitae
+vivamus viverra volutpat vulputate`)
+
+// Generates some lorem ipsum for the layout of a template.
+//
+// By default five paragraphs of HTML are generated, each between 20 and
+// 100 words.  If `html` is false, plain text paragraphs separated by
+// blank lines are returned instead.  The text is generated from a fixed
+// seed, so the same arguments always produce the same text.
+//
+// ```jinja
+// <div class="mockup">{{ lipsum(2, min=5, max=10) }}</div>
+// ```
+func lipsumFunc(args ...Value) (Value, error) {
+	boundArgs, err := bindArgs(args, "n", "html", "min", "max")
+	if err != nil {
+		return Value{}, err
+	}
+	n, err := argOr(boundArgs[0], uint(5), valueTryToGoUint)
+	if err != nil {
+		return Value{}, err
+	}
+	html, err := argOr(boundArgs[1], true, valueTryToGoBool)
+	if err != nil {
+		return Value{}, err
+	}
+	minWords, err := argOr(boundArgs[2], uint(20), valueTryToGoUint)
+	if err != nil {
+		return Value{}, err
+	}
+	maxWords, err := argOr(boundArgs[3], uint(100), valueTryToGoUint)
+	if err != nil {
+		return Value{}, err
+	}
+	if minWords == 0 || maxWords <= minWords {
+		return Value{}, NewError(InvalidOperation, "lipsum requires 0 < min < max")
+	}
+	if maxWords > lipsumMaxWords || n > lipsumMaxWords/maxWords {
+		return Value{}, NewError(InvalidOperation, "lipsum would generate too many words")
+	}
+
+	r := rand.New(rand.NewSource(lipsumSeed))
+	randRange := func(lo, hi int) int { return lo + r.Intn(hi-lo) }
+	paragraphs := make([]string, 0, n)
+	for i := uint(0); i < n; i++ {
+		var b strings.Builder
+		nextCapitalized := true
+		lastComma, lastFullStop := 0, 0
+		last := ""
+		count := randRange(int(minWords), int(maxWords))
+		for idx := 0; idx < count; idx++ {
+			word := lipsumWords[r.Intn(len(lipsumWords))]
+			for word == last {
+				word = lipsumWords[r.Intn(len(lipsumWords))]
+			}
+			last = word
+			if idx > 0 {
+				b.WriteByte(' ')
+			}
+			if nextCapitalized {
+				word = strings.ToUpper(word[:1]) + word[1:]
+				nextCapitalized = false
+			}
+			b.WriteString(word)
+			// add commas
+			if idx-randRange(3, 8) > lastComma {
+				lastComma = idx
+				lastFullStop += 2
+				b.WriteByte(',')
+			}
+			// add end of sentences
+			if idx-randRange(10, 20) > lastFullStop {
+				lastComma, lastFullStop = idx, idx
+				b.WriteByte('.')
+				nextCapitalized = true
+			}
+		}
+		// ensure that the paragraph ends with a dot.
+		p := b.String()
+		if strings.HasSuffix(p, ",") {
+			p = p[:len(p)-1] + "."
+		} else if !strings.HasSuffix(p, ".") {
+			p += "."
+		}
+		paragraphs = append(paragraphs, p)
+	}
+
+	if !html {
+		return valueFromString(strings.Join(paragraphs, "\n\n")), nil
+	}
+	for i, p := range paragraphs {
+		paragraphs[i] = "<p>" + p + "</p>"
+	}
+	return ValueFromSafeString(strings.Join(paragraphs, "\n")), nil
+}
@@ -0,0 +1,214 @@
+package mjingo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hnakamur/mjingo/option"
+)
+
+// Translator is the interface for looking up translated messages.
+//
+// The methods follow the gettext conventions.  The plural variants choose
+// the plural form for n and the context variants look up messages with a
+// disambiguating context.  When no translation is found, the methods should
+// return msgid, or msgidPlural if n is not 1.
+//
+// A translator is set to an environment with [Environment.SetTranslator].
+// [Catalog] is an implementation which reads gettext `.po` and `.mo` files.
+type Translator interface {
+	Gettext(msgid string) string
+	NGettext(msgid, msgidPlural string, n int64) string
+	PGettext(msgctxt, msgid string) string
+	NPGettext(msgctxt, msgid, msgidPlural string, n int64) string
+}
+
+// nullTranslator is the translator used when no translator is set.
+// It returns messages untranslated.
+type nullTranslator struct{}
+
+var _ = Translator(nullTranslator{})
+
+func (nullTranslator) Gettext(msgid string) string { return msgid }
+
+func (nullTranslator) NGettext(msgid, msgidPlural string, n int64) string {
+	if n == 1 {
+		return msgid
+	}
+	return msgidPlural
+}
+
+func (nullTranslator) PGettext(_, msgid string) string { return msgid }
+
+func (t nullTranslator) NPGettext(_, msgid, msgidPlural string, n int64) string {
+	return t.NGettext(msgid, msgidPlural, n)
+}
+
+type gettextKind int
+
+const (
+	gettextKindSingular gettextKind = iota
+	gettextKindPlural
+	gettextKindContext
+	gettextKindContextPlural
+)
+
+func (k gettextKind) name() string {
+	switch k {
+	case gettextKindSingular:
+		return "gettext"
+	case gettextKindPlural:
+		return "ngettext"
+	case gettextKindContext:
+		return "pgettext"
+	case gettextKindContextPlural:
+		return "npgettext"
+	default:
+		panic("unreachable")
+	}
+}
+
+// stringArgCount returns the number of leading string arguments.
+func (k gettextKind) stringArgCount() int {
+	switch k {
+	case gettextKindSingular:
+		return 1
+	case gettextKindPlural, gettextKindContext:
+		return 2
+	case gettextKindContextPlural:
+		return 3
+	default:
+		panic("unreachable")
+	}
+}
+
+func (k gettextKind) hasCount() bool {
+	return k == gettextKindPlural || k == gettextKindContextPlural
+}
+
+// TranslatorGlobals returns the gettext functions bound to t.
+//
+// The returned map has the `_`, `gettext`, `ngettext`, `pgettext` and
+// `npgettext` functions which are also available as globals of an
+// environment created with [NewEnvironment].  The globals use the translator
+// of the environment.  Passing the returned functions in the context of a
+// render overrides the globals, which allows to render templates for
+// multiple languages with a single environment.
+func TranslatorGlobals(t Translator) map[string]Value {
+	rv := make(map[string]Value)
+	addGettextFunctions(rv, func(*State) Translator { return t })
+	return rv
+}
+
+func addGettextFunctions(globals map[string]Value, lookup func(*State) Translator) {
+	addFunction(globals, "gettext", makeGettextFunc(gettextKindSingular, lookup), "_")
+	addFunction(globals, "ngettext", makeGettextFunc(gettextKindPlural, lookup))
+	addFunction(globals, "pgettext", makeGettextFunc(gettextKindContext, lookup))
+	addFunction(globals, "npgettext", makeGettextFunc(gettextKindContextPlural, lookup))
+}
+
+func envTranslator(state *State) Translator {
+	if state.env.translator != nil {
+		return state.env.translator
+	}
+	return nullTranslator{}
+}
+
+// makeGettextFunc creates a gettext style function.
+//
+// Like the newstyle gettext of Jinja2, the translated message is formatted
+// with the keyword arguments as `%(name)s` placeholders if keyword arguments
+// are given.  The plural variants always format the message and the count is
+// available as `num`.
+// If auto escaping is enabled, the translated message is treated as safe and
+// the keyword argument values are escaped.
+func makeGettextFunc(kind gettextKind, lookup func(*State) Translator) BoxedFunc {
+	return func(state *State, args []Value) (Value, error) {
+		var kwargs *valueMap
+		if len(args) > 0 {
+			if m, ok := args[len(args)-1].data.(mapValue); ok && m.Type == mapTypeKwargs {
+				kwargs = m.Map.Clone()
+				args = args[:len(args)-1]
+			}
+		}
+
+		want := kind.stringArgCount()
+		if kind.hasCount() {
+			want++
+		}
+		if len(args) < want {
+			return Value{}, NewError(MissingArgument,
+				fmt.Sprintf("%s requires %d arguments", kind.name(), want))
+		} else if len(args) > want {
+			return Value{}, NewError(TooManyArguments, "")
+		}
+		strs := make([]string, kind.stringArgCount())
+		for i := range strs {
+			s, ok := valueAsGoString(args[i])
+			if !ok {
+				return Value{}, NewError(InvalidOperation,
+					fmt.Sprintf("%s expects string arguments", kind.name()))
+			}
+			strs[i] = s
+		}
+		var n int64
+		if kind.hasCount() {
+			var err error
+			if n, err = args[len(args)-1].tryToI64(); err != nil {
+				return Value{}, NewError(InvalidOperation,
+					fmt.Sprintf("%s expects an integer count", kind.name())).withSource(err)
+			}
+			if kwargs == nil {
+				kwargs = newValueMap()
+			}
+			if _, ok := kwargs.Get(keyRefFromString("num")); !ok {
+				kwargs.Set(keyRefFromString("num"), valueFromI64(n))
+			}
+		}
+
+		t := lookup(state)
+		var rv string
+		switch kind {
+		case gettextKindSingular:
+			rv = t.Gettext(strs[0])
+		case gettextKindPlural:
+			rv = t.NGettext(strs[0], strs[1], n)
+		case gettextKindContext:
+			rv = t.PGettext(strs[0], strs[1])
+		case gettextKindContextPlural:
+			rv = t.NPGettext(strs[0], strs[1], strs[2], n)
+		}
+		return formatTranslation(state, rv, kwargs)
+	}
+}
+
+func formatTranslation(state *State, msg string, kwargs *valueMap) (Value, error) {
+	_, noEscape := state.AutoEscape().(autoEscapeNone)
+	if kwargs == nil {
+		if noEscape {
+			return valueFromString(msg), nil
+		}
+		return ValueFromSafeString(msg), nil
+	}
+
+	vars := valueMapWithCapacity(kwargs.Len())
+	for _, key := range kwargs.Keys() {
+		val, _ := kwargs.Get(key)
+		if _, ok := val.data.(stringValue); ok && !noEscape && !val.isSafe() {
+			var b strings.Builder
+			if err := writeEscaped(newOutput(&b), state.AutoEscape(), val); err != nil {
+				return Value{}, err
+			}
+			val = ValueFromSafeString(b.String())
+		}
+		vars.Set(key, val)
+	}
+	rv, err := printfFormat(msg, printfArgs{mapping: option.Some(valueFromIndexMap(vars))})
+	if err != nil {
+		return Value{}, err
+	}
+	if noEscape {
+		return valueFromString(rv), nil
+	}
+	return ValueFromSafeString(rv), nil
+}
@@ -182,6 +182,11 @@ type returnInstruction struct{}
 type isUndefinedInstruction struct{}
 type encloseInstruction struct{ Name string }
 type getClosureInstruction struct{}
+type callCustomTagInstruction struct {
+	Name       string
+	ArgCount   uint
+	JumpTarget uint
+}
 
 type lineInfo struct {
 	firstInstruction uint32
@@ -254,6 +259,7 @@ var _ = instruction(returnInstruction{})
 var _ = instruction(isUndefinedInstruction{})
 var _ = instruction(encloseInstruction{})
 var _ = instruction(getClosureInstruction{})
+var _ = instruction(callCustomTagInstruction{})
 
 func (emitRawInstruction) Typ() instType           { return instTypeEmitRaw }
 func (storeLocalInstruction) Typ() instType        { return instTypeStoreLocal }
@@ -316,6 +322,7 @@ func (returnInstruction) Typ() instType            { return instTypeReturn }
 func (isUndefinedInstruction) Typ() instType       { return instTypeIsUndefined }
 func (encloseInstruction) Typ() instType           { return instTypeEnclose }
 func (getClosureInstruction) Typ() instType        { return instTypeGetClosure }
+func (callCustomTagInstruction) Typ() instType     { return instTypeCallCustomTag }
 
 func (i emitRawInstruction) Format(f fmt.State, _ rune) {
 	fmt.Fprintf(f, "%s(%q)", i.Typ(), i.Val)
@@ -428,6 +435,9 @@ func (i encloseInstruction) Format(f fmt.State, _ rune) {
 	fmt.Fprintf(f, "%s(%q)", i.Typ(), i.Name)
 }
 func (i getClosureInstruction) Format(f fmt.State, _ rune) { io.WriteString(f, i.Typ().String()) }
+func (i callCustomTagInstruction) Format(f fmt.State, _ rune) {
+	fmt.Fprintf(f, "%s(%q, %d, %d)", i.Typ(), i.Name, i.ArgCount, i.JumpTarget)
+}
 
 type instType uint
 
@@ -623,6 +633,10 @@ const (
 
 	// Returns the closure of this context level.
 	instTypeGetClosure
+
+	// Calls a custom tag.  The body follows this instruction and
+	// ends with a return.
+	instTypeCallCustomTag
 )
 
 func (i instType) String() string {
@@ -749,6 +763,8 @@ func (i instType) String() string {
 		return "Enclose"
 	case instTypeGetClosure:
 		return "GetClosure"
+	case instTypeCallCustomTag:
+		return "CallCustomTag"
 	default:
 		panic("invalid instType")
 	}
@@ -2,8 +2,9 @@ package mjingo
 
 import (
 	"fmt"
+	"math"
 	"math/big"
-	"sync"
+	"math/bits"
 )
 
 // I128 represents an integer in the range between
@@ -11,46 +12,40 @@ import (
 // (both ends inclusive).
 // The zero value for an I128 represents the value 0.
 //
-// Operations always take pointer arguments (*I128) rather
-// than I128 values, and each unique Int value requires
-// its own unique *I128 pointer. To "copy" an I128 value,
-// an existing (or newly allocated) I128 must be set to
-// a new value using the [I128.Set] method; shallow copies
-// of I128s are not supported and may lead to errors.
+// An I128 is stored in two 64-bit words in two's complement form, so
+// arithmetic does not allocate and an I128 can be copied by assignment.
+// Operations take pointer arguments (*I128) in the style of [math/big]
+// and set the receiver to the result.
 //
 // Note that methods may leak the I128's value through timing side-channels.
-// Because of this and because of the scope and complexity of the
-// implementation, I128 is not well-suited to implement cryptographic operations.
-type I128 struct{ n big.Int }
+// Because of this, I128 is not well-suited to implement cryptographic operations.
+type I128 struct{ hi, lo uint64 }
 
 // I128FromInt64 allocates and returns a new I128 set to x.
 func I128FromInt64(x int64) *I128 {
-	return &I128{n: *big.NewInt(x)}
+	return new(I128).SetInt64(x)
 }
 
 // I128FromUint64 allocates and returns a new I128 set to x.
 func I128FromUint64(x uint64) *I128 {
-	var rv I128
-	rv.n.SetUint64(x)
-	return &rv
+	return new(I128).SetUint64(x)
 }
 
 // I128TryFromBigInt allocates and returns a new I128 set to x.
 // If x is out of range of I128, it returns an error.
 func I128TryFromBigInt(x *big.Int) (*I128, error) {
-	if !isI128(x) {
-		return nil, NewError(InvalidOperation, "cannot convert to I128")
+	if x.BitLen() <= 128 {
+		if rv, ok := i128FromMagnitude(x.Sign() < 0, u128FromBigIntAbs(x)); ok {
+			return &rv, nil
+		}
 	}
-	var rv I128
-	rv.n.Set(x)
-	return &rv, nil
+	return nil, NewError(InvalidOperation, "cannot convert to I128")
 }
 
 // CheckedAbs sets z to |x| (the absolute value of x) and returns z.
 // If the operation overflows, the value of z is undefined but the returned value is nil.
 func (z *I128) CheckedAbs(x *I128) *I128 {
-	z.n.Abs(&x.n)
-	return z.checkedVal()
+	return z.setChecked(i128FromMagnitude(false, x.magnitude()))
 }
 
 // Cmp compares x and y and returns:
@@ -58,96 +53,155 @@ func (z *I128) CheckedAbs(x *I128) *I128 {
 //	-1 if x <  y
 //	 0 if x == y
 //	+1 if x >  y
-func (x *I128) Cmp(y *I128) int { return x.n.Cmp(&y.n) }
+func (x *I128) Cmp(y *I128) int {
+	if x.hi != y.hi {
+		if int64(x.hi) < int64(y.hi) {
+			return -1
+		}
+		return 1
+	}
+	return cmpUint64(x.lo, y.lo)
+}
 
 // CheckedDiv sets sets z to the quotient x/y and returns z if y != 0 and the result is in the range of I128.
 // If the operation overflows, the value of z is undefined but the returned value is nil.
 // Div implements Euclidean division (unlike Go); see [math/big.Int.DivMod] for more details.
 func (z *I128) CheckedDiv(x, y *I128) *I128 {
-	var zero I128
-	if y.Cmp(&zero) == 0 {
+	if y.isZero() {
 		return nil
 	}
-	z.n.Div(&x.n, &y.n)
-	return z.checkedVal()
+	xm, ym := x.magnitude(), y.magnitude()
+	q, r := u128DivMod(xm, ym)
+	if x.isNeg() && !r.isZero() {
+		// Round the quotient away from zero so that the remainder
+		// becomes non-negative.
+		q, _ = u128Add(q, U128{lo: 1})
+	}
+	return z.setChecked(i128FromMagnitude(x.isNeg() != y.isNeg(), q))
 }
 
 // CheckedMod sets sets z to the modulus x%y and returns z if y != 0 and the result is in the range of I128.
 // If the operation overflows, the value of z is undefined but the returned value is nil.
 // Mod implements Euclidean modulus (unlike Go); see [math/big.Int.DivMod] for more details.
 func (z *I128) CheckedMod(x, y *I128) *I128 {
-	var zero I128
-	if y.Cmp(&zero) == 0 {
+	if y.isZero() {
 		return nil
 	}
-	z.n.Mod(&x.n, &y.n)
-	return z.checkedVal()
+	ym := y.magnitude()
+	_, r := u128DivMod(x.magnitude(), ym)
+	if x.isNeg() && !r.isZero() {
+		r, _ = u128Sub(ym, r)
+	}
+	return z.setChecked(i128FromMagnitude(false, r))
 }
 
 // CheckedMul sets z to the product x*y and returns z if the result is in the range of I128.
 // If the operation overflows, the value of z is undefined but the returned value is nil.
 func (z *I128) CheckedMul(x, y *I128) *I128 {
-	z.n.Mul(&x.n, &y.n)
-	return z.checkedVal()
+	p, overflow := u128Mul(x.magnitude(), y.magnitude())
+	if overflow {
+		return nil
+	}
+	return z.setChecked(i128FromMagnitude(x.isNeg() != y.isNeg(), p))
 }
 
 // CheckedAdd sets z to the sum x+y and returns z if the result is in the range of I128.
 // If the operation overflows, the value of z is undefined but the returned value is nil.
 func (z *I128) CheckedAdd(x, y *I128) *I128 {
-	z.n.Add(&x.n, &y.n)
-	return z.checkedVal()
+	s, _ := u128Add(x.bits(), y.bits())
+	rv := I128(s)
+	if x.isNeg() == y.isNeg() && rv.isNeg() != x.isNeg() {
+		return nil
+	}
+	*z = rv
+	return z
 }
 
 // CheckedSub sets z to the difference x-y and returns z if the result is in the range of I128.
 // If the operation overflows, the value of z is undefined but the returned value is nil.
 func (z *I128) CheckedSub(x, y *I128) *I128 {
-	z.n.Sub(&x.n, &y.n)
-	return z.checkedVal()
+	d, _ := u128Sub(x.bits(), y.bits())
+	rv := I128(d)
+	if x.isNeg() != y.isNeg() && rv.isNeg() != x.isNeg() {
+		return nil
+	}
+	*z = rv
+	return z
 }
 
 // CheckedNeg sets z to -x and returns z if the result is in the range of I128.
 // If the operation overflows, the value of z is undefined but the returned value is nil.
 func (z *I128) CheckedNeg(x *I128) *I128 {
-	z.n.Neg(&x.n)
-	return z.checkedVal()
+	return z.setChecked(i128FromMagnitude(!x.isNeg(), x.magnitude()))
 }
 
-func (z *I128) checkedVal() *I128 {
-	if isI128(&z.n) {
+// CheckedPow sets z to x**exp and returns z if the result is in the range of I128.
+// If the operation overflows, the value of z is undefined but the returned value is nil.
+func (z *I128) CheckedPow(x *I128, exp uint32) *I128 {
+	// ported from https://github.com/rust-lang/rust/blob/1.72.0/library/core/src/num/int_macros.rs#L875-L899
+	base := *x
+	acc := I128{lo: 1}
+	if exp == 0 {
+		*z = acc
 		return z
 	}
-	return nil
+	for exp > 1 {
+		if exp&1 == 1 {
+			if acc.CheckedMul(&acc, &base) == nil {
+				return nil
+			}
+		}
+		exp /= 2
+		if base.CheckedMul(&base, &base) == nil {
+			return nil
+		}
+	}
+	if acc.CheckedMul(&acc, &base) == nil {
+		return nil
+	}
+	*z = acc
+	return z
 }
 
 // Set sets z to x and returns z.
 func (z *I128) Set(x *I128) *I128 {
-	z.n.Set(&x.n)
+	*z = *x
 	return z
 }
 
 // IsInt64 reports whether x can be represented as an int64.
-func (x *I128) IsInt64() bool { return x.n.IsInt64() }
+func (x *I128) IsInt64() bool { return x.hi == uint64(int64(x.lo)>>63) }
 
 // IsUint64 reports whether x can be represented as a uint64.
-func (x *I128) IsUint64() bool { return x.n.IsUint64() }
+func (x *I128) IsUint64() bool { return x.hi == 0 }
 
 // Int64 returns the int64 representation of x.
 // If x cannot be represented in an int64, the result is undefined.
-func (x *I128) Int64() int64 { return x.n.Int64() }
+func (x *I128) Int64() int64 { return int64(x.lo) }
 
 // Uint64 returns the uint64 representation of x.
 // If x cannot be represented in a uint64, the result is undefined.
-func (x *I128) Uint64() uint64 { return x.n.Uint64() }
+func (x *I128) Uint64() uint64 { return x.lo }
+
+// Float64 returns the float64 value nearest to x.
+func (x *I128) Float64() float64 {
+	m := x.magnitude()
+	f := m.Float64()
+	if x.isNeg() {
+		return -f
+	}
+	return f
+}
 
 // SetInt64 sets z to x and returns z.
 func (z *I128) SetInt64(x int64) *I128 {
-	z.n.SetInt64(x)
+	*z = I128{hi: uint64(x >> 63), lo: uint64(x)}
 	return z
 }
 
 // SetUint64 sets z to x and returns z.
 func (z *I128) SetUint64(x uint64) *I128 {
-	z.n.SetUint64(x)
+	*z = I128{lo: x}
 	return z
 }
 
@@ -164,7 +218,7 @@ func (z *I128) mustSetString(s string, base int) *I128 {
 // (not just a prefix) must be valid for success. If SetString fails,
 // the value of z is undefined but the returned value is nil.
 //
-// The base argument must be 0 or a value between 2 and MaxBase.
+// The base argument must be 0 or a value between 2 and [math/big.MaxBase].
 // For base 0, the number prefix determines the actual base: A prefix of
 // “0b” or “0B” selects base 2, “0”, “0o” or “0O” selects base 8,
 // and “0x” or “0X” selects base 16. Otherwise, the selected base is 10
@@ -184,48 +238,103 @@ func (z *I128) mustSetString(s string, base int) *I128 {
 //
 // If the input is out of range of I128, SetString fails.
 func (z *I128) SetString(s string, base int) (*I128, bool) {
-	r, ok := z.n.SetString(s, base)
-	if !ok || !isI128(r) {
+	neg, s := cutSign(s)
+	m, ok := parseU128(s, base)
+	if !ok {
+		return nil, false
+	}
+	rv, ok := i128FromMagnitude(neg, m)
+	if !ok {
 		return nil, false
 	}
+	*z = rv
 	return z, true
 }
 
 // String returns the decimal representation of x in base 10.
-func (x *I128) String() string { return x.n.String() }
+func (x *I128) String() string {
+	var buf [40]byte
+	return string(x.appendDecimal(buf[:0]))
+}
 
 // BigInt returns a new big.Int whose value is copied from x.
 func (x *I128) BigInt() big.Int {
-	var rv big.Int
-	rv.Set(&x.n)
+	m := x.magnitude()
+	rv := m.BigInt()
+	if x.isNeg() {
+		rv.Neg(&rv)
+	}
 	return rv
 }
 
 // Format implements fmt.Formatter.
-func (x I128) Format(f fmt.State, _ rune) { _, _ = f.Write([]byte(x.String())) }
+func (x I128) Format(f fmt.State, _ rune) {
+	var buf [40]byte
+	_, _ = f.Write(x.appendDecimal(buf[:0]))
+}
+
+func (x *I128) appendDecimal(dst []byte) []byte {
+	if x.isNeg() {
+		dst = append(dst, '-')
+	}
+	m := x.magnitude()
+	return m.appendDecimal(dst)
+}
+
+func (x *I128) isNeg() bool  { return int64(x.hi) < 0 }
+func (x *I128) isZero() bool { return x.hi == 0 && x.lo == 0 }
+
+// bits returns the two's complement representation of x.
+func (x *I128) bits() U128 { return U128(*x) }
+
+// magnitude returns |x|, which is always representable as an U128.
+func (x *I128) magnitude() U128 {
+	if x.isNeg() {
+		return u128Neg(x.bits())
+	}
+	return x.bits()
+}
+
+func (z *I128) setChecked(x I128, ok bool) *I128 {
+	if !ok {
+		return nil
+	}
+	*z = x
+	return z
+}
+
+// i128FromMagnitude returns the I128 whose absolute value is m and which
+// is negative if neg is true.  ok is false if the result is out of range.
+func i128FromMagnitude(neg bool, m U128) (rv I128, ok bool) {
+	if neg {
+		if m.hi > 1<<63 || (m.hi == 1<<63 && m.lo != 0) {
+			return I128{}, false
+		}
+		return I128(u128Neg(m)), true
+	}
+	if m.hi >= 1<<63 {
+		return I128{}, false
+	}
+	return I128(m), true
+}
 
 // U128 represents an integer in the range between
 // 0 and 340282366920938463463374607431768211455
 // (both ends inclusive).
 // The zero value for an U128 represents the value 0.
 //
-// Operations always take pointer arguments (*U128) rather
-// than U128 values, and each unique Int value requires
-// its own unique *U128 pointer. To "copy" an U128 value,
-// an existing (or newly allocated) U128 must be set to
-// a new value using the [U128.Set] method; shallow copies
-// of U128s are not supported and may lead to errors.
+// An U128 is stored in two 64-bit words, so arithmetic does not allocate
+// and an U128 can be copied by assignment.
+// Operations take pointer arguments (*U128) in the style of [math/big]
+// and set the receiver to the result.
 //
 // Note that methods may leak the U128's value through timing side-channels.
-// Because of this and because of the scope and complexity of the
-// implementation, U128 is not well-suited to implement cryptographic operations.
-type U128 struct{ n big.Int }
+// Because of this, U128 is not well-suited to implement cryptographic operations.
+type U128 struct{ hi, lo uint64 }
 
 // U128FromUint64 allocates and returns a new U128 set to x.
 func U128FromUint64(x uint64) *U128 {
-	var rv U128
-	rv.n.SetUint64(x)
-	return &rv
+	return &U128{lo: x}
 }
 
 // U128TryFromInt64 allocates and returns a new U128 set to x.
@@ -234,19 +343,16 @@ func U128TryFromInt64(x int64) (*U128, error) {
 	if x < 0 {
 		return nil, NewError(InvalidOperation, "cannot convert to U128")
 	}
-	var rv U128
-	rv.n.SetInt64(x)
-	return &rv, nil
+	return &U128{lo: uint64(x)}, nil
 }
 
 // U128TryFromBigInt allocates and returns a new U128 set to x.
 // If x is out of range of U128, it returns an error.
 func U128TryFromBigInt(x *big.Int) (*U128, error) {
-	if !isU128(x) {
+	if x.Sign() < 0 || x.BitLen() > 128 {
 		return nil, NewError(InvalidOperation, "cannot convert to U128")
 	}
-	var rv U128
-	rv.n.Set(x)
+	rv := u128FromBigIntAbs(x)
 	return &rv, nil
 }
 
@@ -255,34 +361,124 @@ func U128TryFromBigInt(x *big.Int) (*U128, error) {
 //	-1 if x <  y
 //	 0 if x == y
 //	+1 if x >  y
-func (x *U128) Cmp(y *U128) int { return x.n.Cmp(&y.n) }
+func (x *U128) Cmp(y *U128) int {
+	if x.hi != y.hi {
+		return cmpUint64(x.hi, y.hi)
+	}
+	return cmpUint64(x.lo, y.lo)
+}
+
+// CheckedAdd sets z to the sum x+y and returns z if the result is in the range of U128.
+// If the operation overflows, the value of z is undefined but the returned value is nil.
+func (z *U128) CheckedAdd(x, y *U128) *U128 {
+	s, carry := u128Add(*x, *y)
+	return z.setChecked(s, carry == 0)
+}
+
+// CheckedSub sets z to the difference x-y and returns z if the result is in the range of U128.
+// If the operation overflows, the value of z is undefined but the returned value is nil.
+func (z *U128) CheckedSub(x, y *U128) *U128 {
+	d, borrow := u128Sub(*x, *y)
+	return z.setChecked(d, borrow == 0)
+}
+
+// CheckedMul sets z to the product x*y and returns z if the result is in the range of U128.
+// If the operation overflows, the value of z is undefined but the returned value is nil.
+func (z *U128) CheckedMul(x, y *U128) *U128 {
+	p, overflow := u128Mul(*x, *y)
+	return z.setChecked(p, !overflow)
+}
+
+// CheckedDiv sets z to the quotient x/y and returns z if y != 0.
+// Otherwise the value of z is undefined but the returned value is nil.
+func (z *U128) CheckedDiv(x, y *U128) *U128 {
+	if y.isZero() {
+		return nil
+	}
+	q, _ := u128DivMod(*x, *y)
+	*z = q
+	return z
+}
+
+// CheckedMod sets z to the modulus x%y and returns z if y != 0.
+// Otherwise the value of z is undefined but the returned value is nil.
+func (z *U128) CheckedMod(x, y *U128) *U128 {
+	if y.isZero() {
+		return nil
+	}
+	_, r := u128DivMod(*x, *y)
+	*z = r
+	return z
+}
+
+// CheckedPow sets z to x**exp and returns z if the result is in the range of U128.
+// If the operation overflows, the value of z is undefined but the returned value is nil.
+func (z *U128) CheckedPow(x *U128, exp uint32) *U128 {
+	base := *x
+	acc := U128{lo: 1}
+	if exp == 0 {
+		*z = acc
+		return z
+	}
+	for exp > 1 {
+		if exp&1 == 1 {
+			if acc.CheckedMul(&acc, &base) == nil {
+				return nil
+			}
+		}
+		exp /= 2
+		if base.CheckedMul(&base, &base) == nil {
+			return nil
+		}
+	}
+	if acc.CheckedMul(&acc, &base) == nil {
+		return nil
+	}
+	*z = acc
+	return z
+}
 
 // Set sets z to x and returns z.
 func (z *U128) Set(x *U128) *U128 {
-	z.n.Set(&x.n)
+	*z = *x
 	return z
 }
 
 // IsInt64 reports whether x can be represented as an int64.
-func (x *U128) IsInt64() bool { return x.n.IsInt64() }
+func (x *U128) IsInt64() bool { return x.hi == 0 && x.lo <= math.MaxInt64 }
 
 // IsUint64 reports whether x can be represented as a uint64.
-func (x *U128) IsUint64() bool { return x.n.IsUint64() }
+func (x *U128) IsUint64() bool { return x.hi == 0 }
 
 // Int64 returns the int64 representation of x.
 // If x cannot be represented in an int64, the result is undefined.
-func (x *U128) Int64() int64 { return x.n.Int64() }
+func (x *U128) Int64() int64 { return int64(x.lo) }
 
 // Uint64 returns the uint64 representation of x.
 // If x cannot be represented in a uint64, the result is undefined.
-func (x *U128) Uint64() uint64 { return x.n.Uint64() }
+func (x *U128) Uint64() uint64 { return x.lo }
+
+// Float64 returns the float64 value nearest to x.
+func (x *U128) Float64() float64 {
+	if x.hi == 0 {
+		return float64(x.lo)
+	}
+	// Keep the top 64 bits and fold the dropped bits into the lowest bit
+	// so that the conversion below rounds correctly.
+	n := uint(bits.LeadingZeros64(x.hi))
+	top := x.hi<<n | x.lo>>(64-n)
+	if x.lo<<n != 0 {
+		top |= 1
+	}
+	return math.Ldexp(float64(top), int(64-n))
+}
 
 // SetString sets z to the value of s, interpreted in the given base,
 // and returns z and a boolean indicating success. The entire string
 // (not just a prefix) must be valid for success. If SetString fails,
 // the value of z is undefined but the returned value is nil.
 //
-// The base argument must be 0 or a value between 2 and MaxBase.
+// The base argument must be 0 or a value between 2 and [math/big.MaxBase].
 // For base 0, the number prefix determines the actual base: A prefix of
 // “0b” or “0B” selects base 2, “0”, “0o” or “0O” selects base 8,
 // and “0x” or “0X” selects base 16. Otherwise, the selected base is 10
@@ -302,59 +498,257 @@ func (x *U128) Uint64() uint64 { return x.n.Uint64() }
 //
 // If the input is out of range of U128, SetString fails.
 func (z *U128) SetString(s string, base int) (*U128, bool) {
-	r, ok := z.n.SetString(s, base)
-	if !ok || !isU128(r) {
+	neg, s := cutSign(s)
+	m, ok := parseU128(s, base)
+	if !ok || (neg && !m.isZero()) {
 		return nil, false
 	}
+	*z = m
 	return z, true
 }
 
 // String returns the decimal representation of x in base 10.
-func (x *U128) String() string { return x.n.String() }
+func (x *U128) String() string {
+	var buf [39]byte
+	return string(x.appendDecimal(buf[:0]))
+}
 
 // Format implements fmt.Formatter.
-func (x U128) Format(f fmt.State, _ rune) { _, _ = f.Write([]byte(x.String())) }
+func (x U128) Format(f fmt.State, _ rune) {
+	var buf [39]byte
+	_, _ = f.Write(x.appendDecimal(buf[:0]))
+}
 
 // BigInt returns a new big.Int whose value is copied from x.
 func (x *U128) BigInt() big.Int {
-	var rv big.Int
-	rv.Set(&x.n)
+	var rv, lo big.Int
+	rv.SetUint64(x.hi)
+	rv.Lsh(&rv, 64)
+	rv.Or(&rv, lo.SetUint64(x.lo))
 	return rv
 }
 
-var i128MinAbs = mustNewBigIntFromString("170141183460469231731687303715884105728", 10)
-var i128Min = mustNewBigIntFromString("-170141183460469231731687303715884105728", 10)
-var i128Max = mustNewBigIntFromString("170141183460469231731687303715884105727", 10)
+func (x *U128) appendDecimal(dst []byte) []byte {
+	const tenPow19 = 10_000_000_000_000_000_000
+
+	// The maximum value of U128 has 39 decimal digits.
+	var buf [39]byte
+	i := len(buf)
+	hi, lo := x.hi, x.lo
+	for hi != 0 {
+		var r uint64
+		hi, r = bits.Div64(0, hi, tenPow19)
+		lo, r = bits.Div64(r, lo, tenPow19)
+		for j := 0; j < 19; j++ {
+			i--
+			buf[i] = byte('0' + r%10)
+			r /= 10
+		}
+	}
+	for {
+		i--
+		buf[i] = byte('0' + lo%10)
+		lo /= 10
+		if lo == 0 {
+			break
+		}
+	}
+	return append(dst, buf[i:]...)
+}
 
-func mustNewBigIntFromString(s string, base int) *big.Int {
-	n, ok := new(big.Int).SetString(s, base)
+func (x *U128) isZero() bool { return x.hi == 0 && x.lo == 0 }
+
+func (z *U128) setChecked(x U128, ok bool) *U128 {
 	if !ok {
-		panic(fmt.Sprintf("failed to set big.Int by string %s and base %d", s, base))
+		return nil
 	}
-	return n
+	*z = x
+	return z
 }
 
-var u128Max, twoPow128 *big.Int
+func u128Add(x, y U128) (sum U128, carry uint64) {
+	sum.lo, carry = bits.Add64(x.lo, y.lo, 0)
+	sum.hi, carry = bits.Add64(x.hi, y.hi, carry)
+	return sum, carry
+}
 
-func getU128Max() *big.Int {
-	return sync.OnceValue(func() *big.Int {
-		u128Max = mustNewBigIntFromString("340282366920938463463374607431768211455", 10)
-		return u128Max
-	})()
+func u128Sub(x, y U128) (diff U128, borrow uint64) {
+	diff.lo, borrow = bits.Sub64(x.lo, y.lo, 0)
+	diff.hi, borrow = bits.Sub64(x.hi, y.hi, borrow)
+	return diff, borrow
 }
 
-func getTwoPow128() *big.Int {
-	return sync.OnceValue(func() *big.Int {
-		twoPow128 = mustNewBigIntFromString("340282366920938463463374607431768211456", 10)
-		return twoPow128
-	})()
+// u128Neg returns the two's complement of x.
+func u128Neg(x U128) U128 {
+	rv, _ := u128Sub(U128{}, x)
+	return rv
 }
 
-func isI128(n *big.Int) bool {
-	return n.Cmp(i128Min) >= 0 && n.Cmp(i128Max) <= 0
+func u128Mul(x, y U128) (p U128, overflow bool) {
+	if x.hi != 0 && y.hi != 0 {
+		return U128{}, true
+	}
+	hi, lo := bits.Mul64(x.lo, y.lo)
+	c1hi, c1 := bits.Mul64(x.hi, y.lo)
+	c2hi, c2 := bits.Mul64(x.lo, y.hi)
+	if c1hi != 0 || c2hi != 0 {
+		return U128{}, true
+	}
+	var carry uint64
+	hi, carry = bits.Add64(hi, c1, 0)
+	if carry != 0 {
+		return U128{}, true
+	}
+	hi, carry = bits.Add64(hi, c2, 0)
+	if carry != 0 {
+		return U128{}, true
+	}
+	return U128{hi: hi, lo: lo}, false
+}
+
+// u128DivMod returns the quotient and the remainder of x/y.
+// y must not be zero.
+func u128DivMod(x, y U128) (q, r U128) {
+	if y.hi == 0 {
+		var rem uint64
+		q.hi, rem = bits.Div64(0, x.hi, y.lo)
+		q.lo, rem = bits.Div64(rem, x.lo, y.lo)
+		return q, U128{lo: rem}
+	}
+	if x.Cmp(&y) < 0 {
+		return U128{}, x
+	}
+
+	// The quotient fits in 64 bits since y.hi != 0.  Estimate it from the
+	// normalized top word of y, which is at most one too large.
+	// See Hacker's Delight, 2nd edition, section 9-5.
+	n := uint(bits.LeadingZeros64(y.hi))
+	yn := y.hi<<n | y.lo>>(64-n)
+	xh := x.hi >> 1
+	xl := x.hi<<63 | x.lo>>1
+	tq, _ := bits.Div64(xh, xl, yn)
+	tq >>= 63 - n
+	if tq != 0 {
+		tq--
+	}
+	q = U128{lo: tq}
+	p, _ := u128Mul(q, y)
+	r, _ = u128Sub(x, p)
+	if r.Cmp(&y) >= 0 {
+		q.lo++
+		r, _ = u128Sub(r, y)
+	}
+	return q, r
+}
+
+// u128FromBigIntAbs returns |x|.  x.BitLen() must be at most 128.
+func u128FromBigIntAbs(x *big.Int) U128 {
+	var rv U128
+	for i, w := range x.Bits() {
+		shift := uint(i) * bits.UintSize
+		if shift < 64 {
+			rv.lo |= uint64(w) << shift
+		} else {
+			rv.hi |= uint64(w) << (shift - 64)
+		}
+	}
+	return rv
+}
+
+// cutSign removes an optional leading sign from s.
+func cutSign(s string) (neg bool, rest string) {
+	if len(s) > 0 && (s[0] == '+' || s[0] == '-') {
+		return s[0] == '-', s[1:]
+	}
+	return false, s
 }
 
-func isU128(n *big.Int) bool {
-	var zero big.Int
-	return n.Cmp(&zero) >= 0 && n.Cmp(getU128Max()) <= 0
+// parseU128 parses s without a sign like [math/big.Int.SetString].
+func parseU128(s string, base int) (rv U128, ok bool) {
+	// prefix is 0 (no prefix), '0' (octal prefix "0") or one of 'b', 'o'
+	// and 'x'.
+	var prefix byte
+	if base == 0 {
+		base = 10
+		if len(s) > 1 && s[0] == '0' {
+			switch s[1] | 0x20 {
+			case 'b':
+				base, prefix, s = 2, 'b', s[2:]
+			case 'o':
+				base, prefix, s = 8, 'o', s[2:]
+			case 'x':
+				base, prefix, s = 16, 'x', s[2:]
+			default:
+				base, prefix, s = 8, '0', s[1:]
+			}
+		}
+	} else if base < 2 || base > big.MaxBase {
+		return U128{}, false
+	} else {
+		// underscores are only recognized for base 0.
+		prefix = 0xff
+	}
+
+	// The octal prefix "0" is a digit itself.
+	sawDigit := prefix == '0'
+	prevUnderscore := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '_' && prefix != 0xff {
+			if prevUnderscore || (!sawDigit && prefix == 0) {
+				return U128{}, false
+			}
+			prevUnderscore = true
+			continue
+		}
+		d := digitValue(c, base)
+		if d >= uint64(base) {
+			return U128{}, false
+		}
+		var carry uint64
+		var overflow bool
+		if rv, overflow = u128Mul(rv, U128{lo: uint64(base)}); overflow {
+			return U128{}, false
+		}
+		if rv, carry = u128Add(rv, U128{lo: d}); carry != 0 {
+			return U128{}, false
+		}
+		sawDigit = true
+		prevUnderscore = false
+	}
+	if !sawDigit || prevUnderscore {
+		return U128{}, false
+	}
+	return rv, true
 }
+
+// digitValue returns the value of the digit c in base, or a value
+// not less than base if c is not a valid digit.
+func digitValue(c byte, base int) uint64 {
+	switch {
+	case '0' <= c && c <= '9':
+		return uint64(c - '0')
+	case 'a' <= c && c <= 'z':
+		return uint64(c-'a') + 10
+	case 'A' <= c && c <= 'Z':
+		if base <= 36 {
+			return uint64(c-'A') + 10
+		}
+		return uint64(c-'A') + 36
+	}
+	return big.MaxBase
+}
+
+func cmpUint64(x, y uint64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// i128MinAbs is the absolute value of the minimum I128, which is only
+// representable as an U128.
+var i128MinAbs = U128{hi: 1 << 63}
@@ -1,6 +1,7 @@
 package mjingo
 
 import (
+	"fmt"
 	"math/big"
 	"testing"
 )
@@ -29,3 +30,359 @@ func TestU128TryFromBigInt(t *testing.T) {
 		}
 	})
 }
+
+func mustNewBigIntFromString(s string, base int) *big.Int {
+	n, ok := new(big.Int).SetString(s, base)
+	if !ok {
+		panic(fmt.Sprintf("failed to set big.Int by string %s and base %d", s, base))
+	}
+	return n
+}
+
+var int128TestInputs = []string{
+	"0", "1", "-1", "2", "-2", "3", "7", "-7", "10", "-10",
+	"9223372036854775807", "-9223372036854775808", "9223372036854775808",
+	"18446744073709551615", "18446744073709551616", "-18446744073709551616",
+	"36893488147419103232", "-36893488147419103233",
+	"85070591730234615865843651857942052864",
+	"170141183460469231731687303715884105727",
+	"-170141183460469231731687303715884105727",
+	"-170141183460469231731687303715884105728",
+	"340282366920938463463374607431768211455",
+	"123456789012345678901234567890",
+	"-98765432109876543210987654321",
+}
+
+func TestI128Arithmetic(t *testing.T) {
+	var i128Min, i128Max, u128Max big.Int
+	i128Min.Lsh(big.NewInt(-1), 127)
+	i128Max.Lsh(big.NewInt(1), 127).Sub(&i128Max, big.NewInt(1))
+	u128Max.Lsh(big.NewInt(1), 128).Sub(&u128Max, big.NewInt(1))
+	isI128 := func(n *big.Int) bool { return n.Cmp(&i128Min) >= 0 && n.Cmp(&i128Max) <= 0 }
+	isU128 := func(n *big.Int) bool { return n.Sign() >= 0 && n.Cmp(&u128Max) <= 0 }
+
+	type i128Op struct {
+		name string
+		f    func(z, x, y *I128) *I128
+		want func(x, y *big.Int) *big.Int
+	}
+	i128Ops := []i128Op{
+		{"Add", (*I128).CheckedAdd, func(x, y *big.Int) *big.Int { return new(big.Int).Add(x, y) }},
+		{"Sub", (*I128).CheckedSub, func(x, y *big.Int) *big.Int { return new(big.Int).Sub(x, y) }},
+		{"Mul", (*I128).CheckedMul, func(x, y *big.Int) *big.Int { return new(big.Int).Mul(x, y) }},
+		{"Div", (*I128).CheckedDiv, func(x, y *big.Int) *big.Int {
+			if y.Sign() == 0 {
+				return nil
+			}
+			return new(big.Int).Div(x, y)
+		}},
+		{"Mod", (*I128).CheckedMod, func(x, y *big.Int) *big.Int {
+			if y.Sign() == 0 {
+				return nil
+			}
+			return new(big.Int).Mod(x, y)
+		}},
+	}
+	type u128Op struct {
+		name string
+		f    func(z, x, y *U128) *U128
+		want func(x, y *big.Int) *big.Int
+	}
+	u128Ops := []u128Op{
+		{"Add", (*U128).CheckedAdd, i128Ops[0].want},
+		{"Sub", (*U128).CheckedSub, i128Ops[1].want},
+		{"Mul", (*U128).CheckedMul, i128Ops[2].want},
+		{"Div", (*U128).CheckedDiv, i128Ops[3].want},
+		{"Mod", (*U128).CheckedMod, i128Ops[4].want},
+	}
+
+	for _, xs := range int128TestInputs {
+		for _, ys := range int128TestInputs {
+			xb, yb := mustNewBigIntFromString(xs, 10), mustNewBigIntFromString(ys, 10)
+			if isI128(xb) && isI128(yb) {
+				x, _ := new(I128).SetString(xs, 10)
+				y, _ := new(I128).SetString(ys, 10)
+				for _, op := range i128Ops {
+					want := op.want(xb, yb)
+					var z I128
+					got := op.f(&z, x, y)
+					if want == nil || !isI128(want) {
+						if got != nil {
+							t.Errorf("I128.Checked%s(%s, %s) should overflow, got=%s", op.name, xs, ys, got)
+						}
+					} else if got == nil {
+						t.Errorf("I128.Checked%s(%s, %s) should not overflow, want=%s", op.name, xs, ys, want)
+					} else if got.String() != want.String() {
+						t.Errorf("I128.Checked%s(%s, %s) mismatch, got=%s, want=%s", op.name, xs, ys, got, want)
+					}
+				}
+				if got, want := x.Cmp(y), xb.Cmp(yb); got != want {
+					t.Errorf("I128.Cmp(%s, %s) mismatch, got=%d, want=%d", xs, ys, got, want)
+				}
+			}
+			if isU128(xb) && isU128(yb) {
+				x, _ := new(U128).SetString(xs, 10)
+				y, _ := new(U128).SetString(ys, 10)
+				for _, op := range u128Ops {
+					want := op.want(xb, yb)
+					var z U128
+					got := op.f(&z, x, y)
+					if want == nil || !isU128(want) {
+						if got != nil {
+							t.Errorf("U128.Checked%s(%s, %s) should overflow, got=%s", op.name, xs, ys, got)
+						}
+					} else if got == nil {
+						t.Errorf("U128.Checked%s(%s, %s) should not overflow, want=%s", op.name, xs, ys, want)
+					} else if got.String() != want.String() {
+						t.Errorf("U128.Checked%s(%s, %s) mismatch, got=%s, want=%s", op.name, xs, ys, got, want)
+					}
+				}
+				if got, want := x.Cmp(y), xb.Cmp(yb); got != want {
+					t.Errorf("U128.Cmp(%s, %s) mismatch, got=%d, want=%d", xs, ys, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestI128CheckedPow(t *testing.T) {
+	testCases := []struct {
+		base string
+		exp  uint32
+		want string
+	}{
+		{base: "2", exp: 0, want: "1"},
+		{base: "2", exp: 126, want: "85070591730234615865843651857942052864"},
+		{base: "2", exp: 127},
+		{base: "-2", exp: 127, want: "-170141183460469231731687303715884105728"},
+		{base: "-3", exp: 3, want: "-27"},
+		{base: "10", exp: 38, want: "100000000000000000000000000000000000000"},
+		{base: "10", exp: 39},
+	}
+	for _, tc := range testCases {
+		base := new(I128).mustSetString(tc.base, 10)
+		var z I128
+		got := z.CheckedPow(base, tc.exp)
+		if tc.want == "" {
+			if got != nil {
+				t.Errorf("CheckedPow(%s, %d) should overflow, got=%s", tc.base, tc.exp, got)
+			}
+		} else if got == nil || got.String() != tc.want {
+			t.Errorf("CheckedPow(%s, %d) mismatch, got=%v, want=%s", tc.base, tc.exp, got, tc.want)
+		}
+	}
+	var z U128
+	if got, want := z.CheckedPow(U128FromUint64(2), 127).String(), "170141183460469231731687303715884105728"; got != want {
+		t.Errorf("U128.CheckedPow mismatch, got=%s, want=%s", got, want)
+	}
+	if z.CheckedPow(U128FromUint64(2), 128) != nil {
+		t.Error("U128.CheckedPow should overflow")
+	}
+}
+
+func TestI128SetString(t *testing.T) {
+	testCases := []struct {
+		input string
+		base  int
+	}{
+		{input: "0", base: 10},
+		{input: "+42", base: 10},
+		{input: "-42", base: 10},
+		{input: "ff", base: 16},
+		{input: "-FF", base: 16},
+		{input: "zz", base: 36},
+		{input: "Zz", base: 62},
+		{input: "0x_7fff_ffff", base: 0},
+		{input: "-0b1010", base: 0},
+		{input: "0o17", base: 0},
+		{input: "017", base: 0},
+		{input: "0_17", base: 0},
+		{input: "1_000", base: 0},
+		{input: "08", base: 0},
+		{input: "0x", base: 0},
+		{input: "_1", base: 0},
+		{input: "1__0", base: 0},
+		{input: "1_", base: 0},
+		{input: "1_0", base: 10},
+		{input: "", base: 10},
+		{input: "-", base: 10},
+		{input: "12a", base: 10},
+		{input: "170141183460469231731687303715884105727", base: 10},
+		{input: "170141183460469231731687303715884105728", base: 10},
+		{input: "-170141183460469231731687303715884105728", base: 10},
+		{input: "-170141183460469231731687303715884105729", base: 10},
+		{input: "340282366920938463463374607431768211455", base: 10},
+		{input: "340282366920938463463374607431768211456", base: 10},
+		{input: "ffffffffffffffffffffffffffffffff", base: 16},
+		{input: "100000000000000000000000000000000", base: 16},
+	}
+	for _, tc := range testCases {
+		want, wantOK := new(big.Int).SetString(tc.input, tc.base)
+
+		var i I128
+		_, gotOK := i.SetString(tc.input, tc.base)
+		if ok := wantOK && want.BitLen() <= 127 ||
+			wantOK && want.Sign() < 0 && new(big.Int).Add(want, big.NewInt(1)).BitLen() <= 127; gotOK != ok {
+			t.Errorf("I128.SetString(%q, %d) ok mismatch, got=%v, want=%v", tc.input, tc.base, gotOK, ok)
+		} else if ok && i.String() != want.String() {
+			t.Errorf("I128.SetString(%q, %d) mismatch, got=%s, want=%s", tc.input, tc.base, &i, want)
+		}
+
+		var u U128
+		_, gotOK = u.SetString(tc.input, tc.base)
+		if ok := wantOK && want.Sign() >= 0 && want.BitLen() <= 128; gotOK != ok {
+			t.Errorf("U128.SetString(%q, %d) ok mismatch, got=%v, want=%v", tc.input, tc.base, gotOK, ok)
+		} else if ok && u.String() != want.String() {
+			t.Errorf("U128.SetString(%q, %d) mismatch, got=%s, want=%s", tc.input, tc.base, &u, want)
+		}
+	}
+}
+
+func TestI128SetStringInvalidBase(t *testing.T) {
+	for _, base := range []int{-1, 1, 63} {
+		if _, ok := new(I128).SetString("1", base); ok {
+			t.Errorf("I128.SetString should fail for base %d", base)
+		}
+		if _, ok := new(U128).SetString("1", base); ok {
+			t.Errorf("U128.SetString should fail for base %d", base)
+		}
+	}
+}
+
+func TestI128Conversions(t *testing.T) {
+	for _, s := range int128TestInputs {
+		want := mustNewBigIntFromString(s, 10)
+		wantF, _ := want.Float64()
+		if i, err := I128TryFromBigInt(want); err == nil {
+			if got := i.BigInt(); got.Cmp(want) != 0 {
+				t.Errorf("I128.BigInt mismatch, got=%s, want=%s", &got, want)
+			}
+			if got := i.Float64(); got != wantF {
+				t.Errorf("I128.Float64 mismatch, got=%g, want=%g, input=%s", got, wantF, s)
+			}
+			if got, want := i.IsInt64(), want.IsInt64(); got != want {
+				t.Errorf("I128.IsInt64 mismatch, got=%v, want=%v, input=%s", got, want, s)
+			}
+			if got, want := fmt.Sprint(*i), s; got != want {
+				t.Errorf("I128.Format mismatch, got=%s, want=%s", got, want)
+			}
+		}
+		if u, err := U128TryFromBigInt(want); err == nil {
+			if got := u.BigInt(); got.Cmp(want) != 0 {
+				t.Errorf("U128.BigInt mismatch, got=%s, want=%s", &got, want)
+			}
+			if got := u.Float64(); got != wantF {
+				t.Errorf("U128.Float64 mismatch, got=%g, want=%g, input=%s", got, wantF, s)
+			}
+		}
+	}
+}
+
+func BenchmarkI128(b *testing.B) {
+	x := new(I128).mustSetString("-123456789012345678901234567890", 10)
+	y := new(I128).mustSetString("9876543210987", 10)
+	var z I128
+
+	b.Run("CheckedAdd", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			z.CheckedAdd(x, y)
+		}
+	})
+	b.Run("CheckedMul", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			z.CheckedMul(x, y)
+		}
+	})
+	b.Run("CheckedDiv", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			z.CheckedDiv(x, y)
+		}
+	})
+	b.Run("CheckedMod", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			z.CheckedMod(x, y)
+		}
+	})
+	b.Run("CheckedPow", func(b *testing.B) {
+		b.ReportAllocs()
+		base := I128FromInt64(-7)
+		for i := 0; i < b.N; i++ {
+			z.CheckedPow(base, 45)
+		}
+	})
+	b.Run("SetString", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			z.SetString("-170141183460469231731687303715884105728", 10)
+		}
+	})
+	b.Run("AppendDecimal", func(b *testing.B) {
+		b.ReportAllocs()
+		var buf [40]byte
+		for i := 0; i < b.N; i++ {
+			x.appendDecimal(buf[:0])
+		}
+	})
+}
+
+func BenchmarkU128(b *testing.B) {
+	x := new(U128)
+	x.SetString("123456789012345678901234567890", 10)
+	y := U128FromUint64(9876543210987)
+	var z U128
+
+	b.Run("CheckedAdd", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			z.CheckedAdd(x, y)
+		}
+	})
+	b.Run("CheckedMul", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			z.CheckedMul(x, y)
+		}
+	})
+	b.Run("CheckedDiv", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			z.CheckedDiv(x, y)
+		}
+	})
+	b.Run("CheckedMod", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			z.CheckedMod(x, y)
+		}
+	})
+	b.Run("CheckedPow", func(b *testing.B) {
+		b.ReportAllocs()
+		base := U128FromUint64(7)
+		for i := 0; i < b.N; i++ {
+			z.CheckedPow(base, 45)
+		}
+	})
+	b.Run("Cmp", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			x.Cmp(U128FromUint64(uint64(i)))
+		}
+	})
+	b.Run("SetString", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			z.SetString("340282366920938463463374607431768211455", 10)
+		}
+	})
+	b.Run("AppendDecimal", func(b *testing.B) {
+		b.ReportAllocs()
+		var buf [40]byte
+		for i := 0; i < b.N; i++ {
+			x.appendDecimal(buf[:0])
+		}
+	})
+}
@@ -12,6 +12,7 @@ const (
 	lexerStateTemplate lexerState = iota
 	lexerStateInVariable
 	lexerStateInBlock
+	lexerStateInLineStatement
 )
 
 type startMarker int
@@ -20,6 +21,8 @@ const (
 	startMarkerVariable startMarker = iota
 	startMarkerBlock
 	startMarkerComment
+	startMarkerLineStatement
+	startMarkerLineComment
 )
 
 type tokenizerState struct {
@@ -226,6 +229,9 @@ type tokenizeIterator struct {
 	blockStart            string
 	blockEnd              string
 	commentEnd            string
+
+	// onComment is called with the text and the span of each comment if set.
+	onComment func(text string, spn *span)
 }
 
 type lexerStateStack struct {
@@ -293,6 +299,23 @@ func (i *tokenizeIterator) Next() (token, *span, error) {
 		}
 		switch i.state.stack.peek() {
 		case lexerStateTemplate:
+			if i.state.currentCol == 0 {
+				if startMarker, skip, matched := matchLineMarker(i.state.rest, i.syntaxConfig); matched {
+					if startMarker == startMarkerLineComment {
+						// The span of the comment does not include the newline.
+						comment := i.state.advance(uint(len(strings.TrimSuffix(i.state.rest[:skip], "\n"))))
+						if i.onComment != nil {
+							text := strings.TrimPrefix(strings.TrimSpace(comment), i.syntaxConfig.Syntax.LineCommentPrefix)
+							i.onComment(text, i.state.span(oldLoc))
+						}
+						i.state.advance(skip - uint(len(comment)))
+						continue
+					}
+					i.state.advance(skip)
+					i.state.stack.push(lexerStateInLineStatement)
+					return blockStartToken{}, i.state.span(oldLoc), nil
+				}
+			}
 			if startMarker, skip, matched := matchStartMarker(i.state.rest, i.syntaxConfig); matched {
 				switch startMarker {
 				case startMarkerComment:
@@ -300,7 +323,12 @@ func (i *tokenizeIterator) Next() (token, *span, error) {
 						if i.state.rest[skip+uint(end)-1] == '-' {
 							i.trimLeadingWhitespace = true
 						}
-						i.state.advance(uint(end) + skip + uint(len(i.commentEnd)))
+						comment := i.state.advance(uint(end) + skip + uint(len(i.commentEnd)))
+						if i.onComment != nil {
+							text := comment[skip : skip+uint(end)]
+							text = strings.TrimSuffix(strings.TrimPrefix(text, "-"), "-")
+							i.onComment(text, i.state.span(oldLoc))
+						}
 						continue
 					} else {
 						return nil, nil, i.state.syntaxError("unexpected end of comment")
@@ -356,8 +384,10 @@ func (i *tokenizeIterator) Next() (token, *span, error) {
 			if i.trimLeadingWhitespace {
 				i.trimLeadingWhitespace = false
 				i.state.skipWhitespace()
+				// look for markers again at the new position which might
+				// be at the start of a line.
+				continue
 			}
-			oldLoc = i.state.loc()
 
 			var lead string
 			var spn *span
@@ -382,9 +412,20 @@ func (i *tokenizeIterator) Next() (token, *span, error) {
 			}
 			return templateDataToken{s: lead}, spn, nil
 
-		case lexerStateInBlock, lexerStateInVariable:
-			// in blocks whitespace is generally ignored, skip it.
-			if trimLen := prefixLenFunc(i.state.rest, isASCIIWhitespace); trimLen > 0 {
+		case lexerStateInBlock, lexerStateInVariable, lexerStateInLineStatement:
+			if i.state.stack.peek() == lexerStateInLineStatement {
+				// line statements end at the end of the line.
+				if end, ok := lineStatementEnd(i.state.rest); ok {
+					i.state.stack.pop()
+					i.state.advance(end)
+					return blockEndToken{}, i.state.span(oldLoc), nil
+				}
+				if trimLen := prefixLenFunc(i.state.rest, isLineWhitespace); trimLen > 0 {
+					i.state.advance(trimLen)
+					continue
+				}
+			} else if trimLen := prefixLenFunc(i.state.rest, isASCIIWhitespace); trimLen > 0 {
+				// in blocks whitespace is generally ignored, skip it.
 				i.state.advance(trimLen)
 				continue
 			}
@@ -402,7 +443,7 @@ func (i *tokenizeIterator) Next() (token, *span, error) {
 					i.state.advance(uint(len(i.blockEnd)))
 					return blockEndToken{}, i.state.span(oldLoc), nil
 				}
-			} else {
+			} else if i.state.stack.peek() == lexerStateInVariable {
 				if strings.HasPrefix(i.state.rest, "-") && strings.HasPrefix(i.state.rest[1:], i.variableEnd) {
 					i.state.stack.pop()
 					i.trimLeadingWhitespace = true
@@ -500,6 +541,11 @@ func (i *tokenizeIterator) Next() (token, *span, error) {
 			return i.state.eatIdentifier()
 		}
 	}
+	// a line statement on the last line ends with the input.
+	if !i.state.failed && !i.state.stack.empty() && i.state.stack.peek() == lexerStateInLineStatement {
+		i.state.stack.pop()
+		return blockEndToken{}, i.state.span(i.state.loc()), nil
+	}
 	return nil, nil, nil
 }
 
@@ -531,7 +577,83 @@ func isASCIIDigit(r rune) bool {
 }
 
 func matchStartMarker(rest string, syntaxCfg *syntaxConfig) (startMarker, uint, bool) {
-	return matchStartMarkerDefault(rest)
+	if syntaxCfg == nil || syntaxCfg.Syntax == DefaultSyntax {
+		return matchStartMarkerDefault(rest)
+	}
+	return matchStartMarkerCustom(rest, &syntaxCfg.Syntax)
+}
+
+func matchStartMarkerCustom(rest string, syntax *Syntax) (startMarker, uint, bool) {
+	var marker startMarker
+	var skip uint
+	for _, m := range []struct {
+		marker startMarker
+		str    string
+	}{
+		{marker: startMarkerVariable, str: syntax.VariableStart},
+		{marker: startMarkerBlock, str: syntax.BlockStart},
+		{marker: startMarkerComment, str: syntax.CommentStart},
+	} {
+		// prefer the longest match
+		if strings.HasPrefix(rest, m.str) && uint(len(m.str)) > skip {
+			marker, skip = m.marker, uint(len(m.str))
+		}
+	}
+	return marker, skip, skip > 0
+}
+
+// matchLineMarker matches a line statement or a line comment at rest which
+// must be at the start of a line.  For line statements skip is the length up
+// to the end of the prefix, for line comments it is the length of the whole
+// line including the newline.
+func matchLineMarker(rest string, syntaxCfg *syntaxConfig) (marker startMarker, skip uint, matched bool) {
+	if syntaxCfg == nil || !syntaxCfg.Syntax.hasLinePrefixes() {
+		return 0, 0, false
+	}
+	stmtPrefix := syntaxCfg.Syntax.LineStatementPrefix
+	commentPrefix := syntaxCfg.Syntax.LineCommentPrefix
+	ws := prefixLenFunc(rest, isLineWhitespace)
+	line := rest[ws:]
+	// when one prefix is a prefix of the other, the longer one wins.
+	isComment := commentPrefix != "" && strings.HasPrefix(line, commentPrefix)
+	isStmt := stmtPrefix != "" && strings.HasPrefix(line, stmtPrefix)
+	if isComment && isStmt {
+		isComment = len(commentPrefix) >= len(stmtPrefix)
+		isStmt = !isComment
+	}
+	switch {
+	case isComment:
+		if end := strings.IndexByte(line, '\n'); end != -1 {
+			return startMarkerLineComment, ws + uint(end) + 1, true
+		}
+		return startMarkerLineComment, uint(len(rest)), true
+	case isStmt:
+		return startMarkerLineStatement, ws + uint(len(stmtPrefix)), true
+	}
+	return 0, 0, false
+}
+
+// lineStatementEnd returns the length up to and including the newline if rest
+// is at the end of a line statement.  An optional trailing colon is skipped.
+func lineStatementEnd(rest string) (uint, bool) {
+	ptr := strings.TrimLeftFunc(rest, isLineWhitespace)
+	if strings.HasPrefix(ptr, ":") {
+		ptr = strings.TrimLeftFunc(ptr[1:], isLineWhitespace)
+	}
+	switch {
+	case ptr == "":
+	case strings.HasPrefix(ptr, "\r\n"):
+		ptr = ptr[2:]
+	case strings.HasPrefix(ptr, "\n"):
+		ptr = ptr[1:]
+	default:
+		return 0, false
+	}
+	return uint(len(rest) - len(ptr)), true
+}
+
+func isLineWhitespace(r rune) bool {
+	return r == ' ' || r == '\t'
 }
 
 func matchStartMarkerDefault(rest string) (startMarker, uint, bool) {
@@ -548,7 +670,45 @@ func matchStartMarkerDefault(rest string) (startMarker, uint, bool) {
 }
 
 func findStartMarker(s string, syntaxCfg *syntaxConfig) (pos uint, hyphen bool, found bool) {
-	return findStartMarkerIndexRune(s)
+	if syntaxCfg == nil || syntaxCfg.Syntax == DefaultSyntax {
+		return findStartMarkerIndexRune(s)
+	}
+	return findStartMarkerCustom(s, syntaxCfg)
+}
+
+func findStartMarkerCustom(s string, syntaxCfg *syntaxConfig) (pos uint, hyphen bool, found bool) {
+	syntax := &syntaxCfg.Syntax
+	best, bestLen := -1, 0
+	for _, m := range []string{syntax.VariableStart, syntax.BlockStart, syntax.CommentStart} {
+		if idx := strings.Index(s, m); idx != -1 &&
+			(best == -1 || idx < best || idx == best && len(m) > bestLen) {
+			best, bestLen = idx, len(m)
+		}
+	}
+
+	// a line statement or a line comment before the marker found above
+	// ends the template data at the start of its line.
+	if syntax.hasLinePrefixes() {
+		for offset := 0; ; {
+			nl := strings.IndexByte(s[offset:], '\n')
+			if nl == -1 {
+				break
+			}
+			lineStart := offset + nl + 1
+			if best != -1 && lineStart > best {
+				break
+			}
+			if _, _, matched := matchLineMarker(s[lineStart:], syntaxCfg); matched {
+				return uint(lineStart), false, true
+			}
+			offset = lineStart
+		}
+	}
+
+	if best == -1 {
+		return 0, false, false
+	}
+	return uint(best), strings.HasPrefix(s[best+bestLen:], "-"), true
 }
 
 func findStartMarkerIndexRune(s string) (pos uint, hyphen bool, found bool) {
@@ -9,7 +9,9 @@ import (
 )
 
 type loopObject struct {
-	len              uint
+	// len is None if the number of items is not known up front like for
+	// an iterable object.
+	len              option.Option[uint]
 	idx              uint
 	depth            uint
 	valueTriple      [3]option.Option[Value]
@@ -70,21 +72,36 @@ func (l *loopObject) GetField(name string) option.Option[Value] {
 	if idx == ^uint(0) {
 		return option.Some[Value](Undefined)
 	}
+	var n uint
+	lenKnown := l.len.UnwrapTo(&n)
 	switch name {
 	case "index0":
 		return option.Some[Value](valueFromI64(int64(idx)))
 	case "index":
 		return option.Some[Value](valueFromI64(int64(idx + 1)))
 	case "length":
-		return option.Some[Value](valueFromI64(int64(l.len)))
+		if !lenKnown {
+			return option.Some(Undefined)
+		}
+		return option.Some[Value](valueFromI64(int64(n)))
 	case "revindex":
-		return option.Some[Value](valueFromI64(int64(uintSaturatingSub(l.len, idx))))
+		if !lenKnown {
+			return option.Some(Undefined)
+		}
+		return option.Some[Value](valueFromI64(int64(uintSaturatingSub(n, idx))))
 	case "revindex0":
-		return option.Some[Value](valueFromI64(int64(uintSaturatingSub(uintSaturatingSub(l.len, idx), 1))))
+		if !lenKnown {
+			return option.Some(Undefined)
+		}
+		return option.Some[Value](valueFromI64(int64(uintSaturatingSub(uintSaturatingSub(n, idx), 1))))
 	case "first":
 		return option.Some[Value](valueFromBool(idx == 0))
 	case "last":
-		return option.Some[Value](valueFromBool(l.len == 0 || idx == l.len-1))
+		if !lenKnown {
+			// the next item is fetched ahead of time.
+			return option.Some[Value](valueFromBool(l.valueTriple[2].IsNone()))
+		}
+		return option.Some[Value](valueFromBool(n == 0 || idx == n-1))
 	case "depth":
 		return option.Some[Value](valueFromI64(int64(l.depth + 1)))
 	case "depth0":
@@ -104,7 +121,11 @@ func (*loopObject) SupportsCustomVerb(verb rune) bool {
 func (l *loopObject) Format(f fmt.State, verb rune) {
 	switch verb {
 	case rustfmt.DisplayVerb:
-		fmt.Fprintf(f, "<loop %d/%d>", l.idx, l.len)
+		if n := uint(0); l.len.UnwrapTo(&n) {
+			fmt.Fprintf(f, "<loop %d/%d>", l.idx, n)
+		} else {
+			fmt.Fprintf(f, "<loop %d/?>", l.idx)
+		}
 	case rustfmt.DebugVerb:
 		s := rustfmt.NewDebugStruct("Loop")
 		for _, attr := range l.StaticFields().Unwrap() {
@@ -238,5 +238,10 @@ func trackWalk(node statement, state *assignmentTracker) {
 	case doStmt:
 		trackVisitExpr(st.call.data.expr, state)
 		trackVisitExpressions(st.call.data.args, state)
+	case customTagStmt:
+		trackVisitExpressions(st.args, state)
+		state.push()
+		trackWalkStatements(st.body, state)
+		state.pop()
 	}
 }
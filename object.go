@@ -19,8 +19,8 @@ import "github.com/hnakamur/mjingo/option"
 // [Kind] of the object.  By default an object can just be
 // stringified and methods can be called.
 //
-// For examples of how to implement objects refer to [SeqObject] and
-// [StructObject].
+// For examples of how to implement objects refer to [SeqObject],
+// [StructObject], [MapObject] and [IterableObject].
 type Object interface {
 	// Kind describes the kind of an object.
 	Kind() ObjectKind
@@ -31,11 +31,11 @@ type Object interface {
 // When a dynamic [Object] is implemented, it can be of one of the kinds
 // here.  The default behavior will be a [ObjectKindPlain] object which
 // doesn't do much other than that it can be printed.  For an object to turn
-// into a [StructObject] or [SeqObject] the necessary kind
-// has to be returned with a pointer to itself.
+// into a [StructObject], [SeqObject], [MapObject] or [IterableObject] the
+// necessary kind has to be returned with a pointer to itself.
 //
-// Today object's can have the behavior of structs and sequences but this
-// might expand in the future.  It does mean that not all types of values can
+// Today object's can have the behavior of structs, sequences, maps and
+// iterables but this might expand in the future.  It does mean that not all types of values can
 // be represented by objects.
 type ObjectKind uint
 
@@ -56,6 +56,17 @@ const (
 	//
 	// Requires that the object implements [StructObject].
 	ObjectKindStruct
+
+	// ObjectKindMap is a kind for a map with arbitrary keys.
+	//
+	// Requires that the object implements [MapObject].
+	ObjectKindMap
+
+	// ObjectKindIterable is a kind for a sequence which can only be
+	// iterated over.
+	//
+	// Requires that the object implements [IterableObject].
+	ObjectKindIterable
 )
 
 // SeqObject provides the behavior of an [Object] holding sequence of values.
@@ -158,3 +169,111 @@ func staticOrDynamicFields(s StructObject) []string {
 	}
 	return s.Fields()
 }
+
+// MapObject provides the behavior of an [Object] holding a map.
+//
+// Unlike a [StructObject] the keys of a map can be any values, like
+// integers or sequences.  The engine treats such an object like a map
+// value, so it can be iterated over, looked up with the subscript and
+// attribute syntax, tested with the `in` operator and serialized.
+//
+// This can be used to expose large lookup tables which are loaded lazily
+// as values are only requested with [MapObject.GetValue].
+type MapObject interface {
+	// GetValue looks up the value for a key.
+	//
+	// If the key does not exist, `None` shall be returned.  Like
+	// [StructObject.GetField] this is not supposed to have side effects.
+	GetValue(key Value) option.Option[Value]
+
+	// Keys returns the keys of the map in the order of iteration.
+	Keys() []Value
+
+	// Len returns the number of entries in the map.
+	Len() uint
+}
+
+func valueMapFromMapObject(m MapObject) *valueMap {
+	keys := m.Keys()
+	rv := valueMapWithCapacity(uint(len(keys)))
+	for _, key := range keys {
+		rv.Set(keyRefFromValue(key), m.GetValue(key).UnwrapOr(Undefined))
+	}
+	return rv
+}
+
+// IterableObject provides the behavior of an [Object] holding a sequence of
+// values which can only be iterated over.
+//
+// Unlike a [SeqObject] the number of items does not have to be known up
+// front and the items cannot be looked up by index.  This can be used to
+// expose database cursors or streams to templates.  The engine pulls the
+// items one at a time, so a `for` loop and filters like `first` and `join`
+// do not need to load all of them first.  The `map`, `select`, `reject`,
+// `selectattr` and `rejectattr` filters return iterable objects as well
+// which convert the items when they are pulled.  If converting an item
+// fails, rendering fails with that error when the item is pulled.  In a
+// `for` loop over such an object `loop.length`, `loop.revindex` and
+// `loop.revindex0` are undefined.
+//
+// An iterable object is always true in a boolean context like
+// `{% if rows %}`, even if it has no items, since checking for items would
+// pull one of them.  Use `{% for %}` with `{% else %}` to handle an empty
+// iterable.
+type IterableObject interface {
+	// Iterate starts a new iteration over the items.
+	//
+	// The returned function is called to get the next item and shall
+	// return `None` once all items were returned.  An object which can be
+	// iterated over only once may return a function which returns `None`
+	// right away on later calls.
+	Iterate() func() option.Option[Value]
+}
+
+// valueAsMapObject returns the map object if val holds an object of the
+// map kind.
+func valueAsMapObject(val Value) (MapObject, bool) {
+	if d, ok := val.data.(dynamicValue); ok && d.Dy.Kind() == ObjectKindMap {
+		return d.Dy.(MapObject), true
+	}
+	return nil, false
+}
+
+// valueAsIterableObject returns the iterable object if val holds an object
+// of the iterable kind.
+func valueAsIterableObject(val Value) (IterableObject, bool) {
+	if d, ok := val.data.(dynamicValue); ok && d.Dy.Kind() == ObjectKindIterable {
+		return d.Dy.(IterableObject), true
+	}
+	return nil, false
+}
+
+// collectIterableObject returns the items of obj.  If an item is an
+// invalid value, collecting stops with that item as the last one and its
+// error is returned.
+func collectIterableObject(obj IterableObject) ([]Value, error) {
+	var rv []Value
+	next := obj.Iterate()
+	for item := (Value{}); next().UnwrapTo(&item); {
+		rv = append(rv, item)
+		if err := invalidValueErr(item); err != nil {
+			return rv, err
+		}
+	}
+	return rv, nil
+}
+
+// collectIterableValue returns a sequence of the items if val holds an
+// iterable object and val itself otherwise.  It is used before formatting a
+// value so that an error while iterating is not hidden in the output.
+func collectIterableValue(val Value) (Value, error) {
+	obj, ok := valueAsIterableObject(val)
+	if !ok {
+		return val, nil
+	}
+	items, err := collectIterableObject(obj)
+	if err != nil {
+		return Value{}, err
+	}
+	return valueFromSlice(items), nil
+}
@@ -114,7 +114,7 @@ func opNeg(val Value) (Value, error) {
 
 	// special case for the largest i128 that can still be
 	// represented.
-	if v, ok := val.data.(u128Value); ok && v.N.n.Cmp(i128MinAbs) == 0 {
+	if v, ok := val.data.(u128Value); ok && v.N == i128MinAbs {
 		return val, nil
 	}
 
@@ -122,52 +122,52 @@ func opNeg(val Value) (Value, error) {
 	if err != nil {
 		return Value{}, NewError(InvalidOperation, "")
 	}
-	if x.CheckedNeg(x) == nil {
+	if x.CheckedNeg(&x) == nil {
 		return Value{}, failedOpUnary("-", val)
 	}
-	return i128AsValue(x), nil
+	return i128AsValue(&x), nil
 }
 
 func opAdd(lhs, rhs Value) (Value, error) {
-	switch c := coerce(lhs, rhs).(type) {
-	case i128CoerceResult:
+	switch c := coerce(lhs, rhs); c.typ {
+	case coerceResultTypeI128:
 		var n I128
-		if n.CheckedAdd(&c.lhs, &c.rhs) == nil {
+		if n.CheckedAdd(&c.i128.lhs, &c.i128.rhs) == nil {
 			return Value{}, failedOp("+", lhs, rhs)
 		}
 		return i128AsValue(&n), nil
-	case f64CoerceResult:
-		return valueFromF64(c.lhs + c.rhs), nil
-	case strCoerceResult:
-		return valueFromString(c.lhs + c.rhs), nil
+	case coerceResultTypeF64:
+		return valueFromF64(c.f64.lhs + c.f64.rhs), nil
+	case coerceResultTypeStr:
+		return valueFromString(c.str.lhs + c.str.rhs), nil
 	}
 	return Value{}, impossibleOp("+", lhs, rhs)
 }
 
 func opSub(lhs, rhs Value) (Value, error) {
-	switch c := coerce(lhs, rhs).(type) {
-	case i128CoerceResult:
+	switch c := coerce(lhs, rhs); c.typ {
+	case coerceResultTypeI128:
 		var n I128
-		if n.CheckedSub(&c.lhs, &c.rhs) == nil {
+		if n.CheckedSub(&c.i128.lhs, &c.i128.rhs) == nil {
 			return Value{}, failedOp("-", lhs, rhs)
 		}
 		return i128AsValue(&n), nil
-	case f64CoerceResult:
-		return valueFromF64(c.lhs - c.rhs), nil
+	case coerceResultTypeF64:
+		return valueFromF64(c.f64.lhs - c.f64.rhs), nil
 	}
 	return Value{}, impossibleOp("-", lhs, rhs)
 }
 
 func opMul(lhs, rhs Value) (Value, error) {
-	switch c := coerce(lhs, rhs).(type) {
-	case i128CoerceResult:
+	switch c := coerce(lhs, rhs); c.typ {
+	case coerceResultTypeI128:
 		var n I128
-		if n.CheckedMul(&c.lhs, &c.rhs) == nil {
+		if n.CheckedMul(&c.i128.lhs, &c.i128.rhs) == nil {
 			return Value{}, failedOp("*", lhs, rhs)
 		}
 		return i128AsValue(&n), nil
-	case f64CoerceResult:
-		return valueFromF64(c.lhs * c.rhs), nil
+	case coerceResultTypeF64:
+		return valueFromF64(c.f64.lhs * c.f64.rhs), nil
 	}
 	return Value{}, impossibleOp("*", lhs, rhs)
 }
@@ -183,48 +183,55 @@ func opDiv(lhs, rhs Value) (Value, error) {
 }
 
 func opIntDiv(lhs, rhs Value) (Value, error) {
-	switch c := coerce(lhs, rhs).(type) {
-	case i128CoerceResult:
+	switch c := coerce(lhs, rhs); c.typ {
+	case coerceResultTypeI128:
 		var div I128
-		if div.CheckedDiv(&c.lhs, &c.rhs) == nil {
+		if div.CheckedDiv(&c.i128.lhs, &c.i128.rhs) == nil {
 			return Value{}, failedOp("//", lhs, rhs)
 		}
 		return i128AsValue(&div), nil
-	case f64CoerceResult:
-		return valueFromF64(math.Floor(c.lhs / c.rhs)), nil
+	case coerceResultTypeF64:
+		return valueFromF64(math.Floor(c.f64.lhs / c.f64.rhs)), nil
 	}
 	return Value{}, impossibleOp("//", lhs, rhs)
 }
 
 func opRem(lhs, rhs Value) (Value, error) {
-	switch c := coerce(lhs, rhs).(type) {
-	case i128CoerceResult:
+	if s, ok := valueAsGoString(lhs); ok {
+		rv, err := printfFormat(s, newPrintfArgsFromValue(rhs))
+		if err != nil {
+			return Value{}, err
+		}
+		return valueFromString(rv), nil
+	}
+	switch c := coerce(lhs, rhs); c.typ {
+	case coerceResultTypeI128:
 		var mod I128
-		if mod.CheckedMod(&c.lhs, &c.rhs) == nil {
+		if mod.CheckedMod(&c.i128.lhs, &c.i128.rhs) == nil {
 			return Value{}, failedOp("%", lhs, rhs)
 		}
 		return i128AsValue(&mod), nil
-	case f64CoerceResult:
-		return valueFromF64(math.Remainder(c.lhs, c.rhs)), nil
+	case coerceResultTypeF64:
+		return valueFromF64(math.Remainder(c.f64.lhs, c.f64.rhs)), nil
 	}
 	return Value{}, impossibleOp("%", lhs, rhs)
 }
 
 func opPow(lhs, rhs Value) (Value, error) {
-	switch c := coerce(lhs, rhs).(type) {
-	case i128CoerceResult:
+	switch c := coerce(lhs, rhs); c.typ {
+	case coerceResultTypeI128:
 		var exp uint32
-		if !c.rhs.IsUint64() || c.rhs.Uint64() > math.MaxUint32 {
+		if !c.i128.rhs.IsUint64() || c.i128.rhs.Uint64() > math.MaxUint32 {
 			return Value{}, failedOp("**", lhs, rhs)
 		}
-		exp = uint32(c.rhs.Uint64())
+		exp = uint32(c.i128.rhs.Uint64())
 		var n I128
-		if i128CheckedPow(&n, &c.lhs, exp) == nil {
+		if n.CheckedPow(&c.i128.lhs, exp) == nil {
 			return Value{}, failedOp("**", lhs, rhs)
 		}
 		return i128AsValue(&n), nil
-	case f64CoerceResult:
-		return valueFromF64(math.Pow(c.lhs, c.rhs)), nil
+	case coerceResultTypeF64:
+		return valueFromF64(math.Pow(c.f64.lhs, c.f64.rhs)), nil
 	}
 	return Value{}, impossibleOp("**", lhs, rhs)
 }
@@ -259,6 +266,19 @@ func opContains(container, val Value) (Value, error) {
 	} else if mapVal, ok := container.data.(mapValue); ok {
 		_, ok := mapVal.Map.Get(keyRefFromValue(val.clone()))
 		rv = ok
+	} else if mapObj, ok := valueAsMapObject(container); ok {
+		rv = mapObj.GetValue(val).IsSome()
+	} else if obj, ok := valueAsIterableObject(container); ok {
+		next := obj.Iterate()
+		for elem := (Value{}); next().UnwrapTo(&elem); {
+			if err := invalidValueErr(elem); err != nil {
+				return Value{}, err
+			}
+			if elem.Equal(val) {
+				rv = true
+				break
+			}
+		}
 	} else {
 		return Value{}, NewError(InvalidOperation,
 			"cannot perform a containment check on this value")
@@ -266,8 +286,14 @@ func opContains(container, val Value) (Value, error) {
 	return valueFromBool(rv), nil
 }
 
-type coerceResult interface {
-	typ() coerceResultType
+// coerceResult holds two values converted to a common type.  The field
+// matching typ is set and typ is zero if the values cannot be coerced.  It
+// is a struct instead of an interface so coercing does not allocate.
+type coerceResult struct {
+	typ  coerceResultType
+	i128 i128CoerceResult
+	f64  f64CoerceResult
+	str  strCoerceResult
 }
 
 type i128CoerceResult struct {
@@ -285,10 +311,6 @@ type strCoerceResult struct {
 	rhs string
 }
 
-func (i128CoerceResult) typ() coerceResultType { return coerceResultTypeI128 }
-func (f64CoerceResult) typ() coerceResultType  { return coerceResultTypeF64 }
-func (strCoerceResult) typ() coerceResultType  { return coerceResultTypeStr }
-
 type coerceResultType int
 
 const (
@@ -305,36 +327,36 @@ func coerceData(a, b valueData) coerceResult {
 	case a.typ() == valueTypeU64 && b.typ() == valueTypeU64:
 		aVal := a.(u64Value).N
 		bVal := b.(u64Value).N
-		var rv i128CoerceResult
-		rv.lhs.SetUint64(aVal)
-		rv.rhs.SetUint64(bVal)
+		rv := coerceResult{typ: coerceResultTypeI128}
+		rv.i128.lhs.SetUint64(aVal)
+		rv.i128.rhs.SetUint64(bVal)
 		return rv
 	case a.typ() == valueTypeU128 && b.typ() == valueTypeU128:
 		aVal := a.(u128Value)
 		bVal := b.(u128Value)
-		var rv i128CoerceResult
-		castU128AsI128(&rv.lhs, &aVal.N)
-		castU128AsI128(&rv.rhs, &bVal.N)
+		rv := coerceResult{typ: coerceResultTypeI128}
+		castU128AsI128(&rv.i128.lhs, &aVal.N)
+		castU128AsI128(&rv.i128.rhs, &bVal.N)
 		return rv
 	case a.typ() == valueTypeString && b.typ() == valueTypeString:
 		aVal := a.(stringValue).Str
 		bVal := b.(stringValue).Str
-		return strCoerceResult{lhs: aVal, rhs: bVal}
+		return coerceResult{typ: coerceResultTypeStr, str: strCoerceResult{lhs: aVal, rhs: bVal}}
 	case a.typ() == valueTypeI64 && b.typ() == valueTypeI64:
 		aVal := a.(i64Value).N
 		bVal := b.(i64Value).N
-		var rv i128CoerceResult
-		rv.lhs.SetInt64(aVal)
-		rv.rhs.SetInt64(bVal)
+		rv := coerceResult{typ: coerceResultTypeI128}
+		rv.i128.lhs.SetInt64(aVal)
+		rv.i128.rhs.SetInt64(bVal)
 		return rv
 	case a.typ() == valueTypeI128 && b.typ() == valueTypeI128:
 		aVal := a.(i128Value).N
 		bVal := b.(i128Value).N
-		return i128CoerceResult{lhs: aVal, rhs: bVal}
+		return coerceResult{typ: coerceResultTypeI128, i128: i128CoerceResult{lhs: aVal, rhs: bVal}}
 	case a.typ() == valueTypeF64 && b.typ() == valueTypeF64:
 		aVal := a.(f64Value).F
 		bVal := b.(f64Value).F
-		return f64CoerceResult{lhs: aVal, rhs: bVal}
+		return coerceResult{typ: coerceResultTypeF64, f64: f64CoerceResult{lhs: aVal, rhs: bVal}}
 	case a.typ() == valueTypeF64 || b.typ() == valueTypeF64:
 		var aVal, bVal float64
 		if af, ok := a.(f64Value); ok {
@@ -342,36 +364,33 @@ func coerceData(a, b valueData) coerceResult {
 			if bMayVal := b.asF64(); bMayVal.IsSome() {
 				bVal = bMayVal.Unwrap()
 			} else {
-				return nil
+				return coerceResult{}
 			}
 		} else if bf, ok := b.(f64Value); ok {
 			bVal = bf.F
 			if aMayVal := a.asF64(); aMayVal.IsSome() {
 				aVal = aMayVal.Unwrap()
 			} else {
-				return nil
+				return coerceResult{}
 			}
 		}
-		return f64CoerceResult{lhs: aVal, rhs: bVal}
+		return coerceResult{typ: coerceResultTypeF64, f64: f64CoerceResult{lhs: aVal, rhs: bVal}}
 	default:
 		// everything else goes up to i64 (different from i128 in MiniJinja)
 		aVal, err := a.tryToI128()
 		if err != nil {
-			return nil
+			return coerceResult{}
 		}
 		bVal, err := b.tryToI128()
 		if err != nil {
-			return nil
+			return coerceResult{}
 		}
-		return i128CoerceResult{lhs: *aVal, rhs: *bVal}
+		return coerceResult{typ: coerceResultTypeI128, i128: i128CoerceResult{lhs: aVal, rhs: bVal}}
 	}
 }
 
 func castU128AsI128(ret *I128, input *U128) *I128 {
-	ret.n.Set(&input.n)
-	if input.n.Cmp(i128Max) > 0 {
-		ret.n.Sub(&ret.n, getTwoPow128())
-	}
+	*ret = I128(*input)
 	return ret
 }
 
@@ -382,37 +401,6 @@ func i128AsValue(val *I128) Value {
 	return valueFromI128(*val)
 }
 
-func i128CheckedMul(ret, lhs, rhs *I128) *I128 {
-	ret.n.Mul(&lhs.n, &rhs.n)
-	if isI128(&ret.n) {
-		return ret
-	}
-	return nil
-}
-
-func i128CheckedPow(ret, base *I128, exp uint32) *I128 {
-	// ported from https://github.com/rust-lang/rust/blob/1.72.0/library/core/src/num/int_macros.rs#L875-L899
-	ret.n.SetUint64(1)
-	if exp == 0 {
-		return ret
-	}
-	base2, _ := I128TryFromBigInt(&base.n)
-	for exp > 1 {
-		if exp&1 == 1 {
-			ret = i128CheckedMul(ret, ret, base2)
-			if ret == nil {
-				return nil
-			}
-		}
-		exp /= 2
-		base2 = i128CheckedMul(base2, base2, base2)
-		if base2 == nil {
-			return nil
-		}
-	}
-	return i128CheckedMul(ret, ret, base2)
-}
-
 func failedOpUnary(op string, v Value) error {
 	return NewError(InvalidOperation,
 		fmt.Sprintf("unable to calculate %s"+rustfmt.DisplayString, op, v))
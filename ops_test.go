@@ -1,7 +1,6 @@
 package mjingo
 
 import (
-	"math/big"
 	"testing"
 )
 
@@ -67,7 +66,9 @@ func TestCaseU128AsI128(t *testing.T) {
 	for _, tc := range testCases {
 		var u U128
 		var rv I128
-		setBigIntString(t, &u.n, tc.input)
+		if _, ok := u.SetString(tc.input, 10); !ok {
+			t.Fatalf("invalid U128 string: %s", tc.input)
+		}
 		castU128AsI128(&rv, &u)
 		if got, want := rv.String(), tc.want; got != want {
 			t.Errorf("result mismatch, got=%s, want=%s, input=%s", got, want, tc.input)
@@ -75,8 +76,34 @@ func TestCaseU128AsI128(t *testing.T) {
 	}
 }
 
-func setBigIntString(t *testing.T, n *big.Int, s string) {
-	if _, ok := n.SetString(s, 10); !ok {
-		t.Fatalf("invalid BigInt string: %s", s)
-	}
+// BenchmarkOps measures operations on numbers which do not fit in 64 bits.
+// Coercing and comparing do not allocate, so the only allocation of
+// AddI128I64 and MulI64Overflow is the resulting 128-bit integer Value.
+func BenchmarkOps(b *testing.B) {
+	big := valueFromI128(*new(I128).mustSetString("-123456789012345678901234567890", 10))
+	small := valueFromI64(9876543210987)
+	large := valueFromI64(1 << 62)
+
+	b.Run("AddI128I64", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := opAdd(big, small); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("MulI64Overflow", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := opMul(large, large); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("CmpI128I64", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			valueCmp(big, small)
+		}
+	})
 }
@@ -90,6 +90,11 @@ func writeWithHTMLEscaping(o *output, val Value) error {
 }
 
 func writeEscaped(o *output, autoEscape AutoEscape, val Value) error {
+	val, err := collectIterableValue(val)
+	if err != nil {
+		return err
+	}
+
 	// common case of safe strings or strings without auto escaping
 	if val.isSafe() || autoEscape.isNone() {
 		return writeString(o, val.String())
@@ -101,7 +106,11 @@ func writeEscaped(o *output, autoEscape AutoEscape, val Value) error {
 	case autoEscapeHTML:
 		return writeWithHTMLEscaping(o, val)
 	case autoEscapeJSON:
-		panic("not implemented")
+		s, err := marshalJSON(val, "", false)
+		if err != nil {
+			return NewError(BadSerialization, "unable to format to JSON").withSource(err)
+		}
+		return writeString(o, s)
 	case autoEscapeCustom:
 		panic(fmt.Sprintf("not implemented for custom auto escape name=%s", esc.name))
 	}
@@ -3,6 +3,7 @@ package mjingo
 import (
 	"errors"
 	"fmt"
+	"regexp"
 	"slices"
 	"strings"
 
@@ -62,16 +63,22 @@ func (s *tokenStream) currentSpan() span {
 }
 
 type parser struct {
-	stream  *tokenStream
-	inMacro bool
-	blocks  *hashset.StrHashSet
-	depth   uint
+	stream     *tokenStream
+	inMacro    bool
+	blocks     *hashset.StrHashSet
+	depth      uint
+	customTags map[string]string
 }
 
 func newParser(source string, inExpr bool, syntax *syntaxConfig) *parser {
+	var customTags map[string]string
+	if syntax != nil {
+		customTags = syntax.customTags
+	}
 	return &parser{
-		stream: newTokenStream(source, inExpr, syntax),
-		blocks: hashset.NewStrHashSet(),
+		stream:     newTokenStream(source, inExpr, syntax),
+		blocks:     hashset.NewStrHashSet(),
+		customTags: customTags,
 	}
 }
 
@@ -1101,7 +1108,22 @@ func (p *parser) parseStmtUnprotected() (statement, error) {
 		}
 		st.span = p.stream.expandSpan(spn)
 		return st, nil
+	case "trans":
+		st, err := p.parseTrans(spn)
+		if err != nil {
+			return nil, err
+		}
+		st.span = p.stream.expandSpan(spn)
+		return st, nil
 	default:
+		if endName, ok := p.customTags[ident]; ok {
+			st, err := p.parseCustomTag(ident, endName)
+			if err != nil {
+				return nil, err
+			}
+			st.span = p.stream.expandSpan(spn)
+			return st, nil
+		}
 		return nil, syntaxError(fmt.Sprintf("unknown statement %s", ident))
 	}
 }
@@ -1538,6 +1560,303 @@ func (p *parser) parseFilterBlock() (filterBlockStmt, error) {
 	}, nil
 }
 
+func (p *parser) parseCustomTag(name, endName string) (customTagStmt, error) {
+	args, err := p.parseCustomTagArgs()
+	if err != nil {
+		return customTagStmt{}, err
+	}
+	if endName == "" {
+		return customTagStmt{name: name, args: args}, nil
+	}
+	if _, _, err := p.expectToken(isTokenOfType[blockEndToken], "end of block"); err != nil {
+		return customTagStmt{}, err
+	}
+	body, err := p.subparse(isIdentTokenWithName(endName))
+	if err != nil {
+		return customTagStmt{}, err
+	}
+	if _, _, err := p.stream.next(); err != nil {
+		return customTagStmt{}, err
+	}
+	return customTagStmt{name: name, args: args, body: body}, nil
+}
+
+// parseCustomTagArgs parses comma separated arguments of a custom tag up to
+// the end of the block.  Like in calls, keyword arguments are collected into
+// a trailing kwargs expression.
+func (p *parser) parseCustomTagArgs() ([]astExpr, error) {
+	args := []astExpr{}
+	firstSpan := option.None[span]()
+	kwargs := []kwargExpr{}
+	for {
+		if matched, err := p.matchesToken(isTokenOfType[blockEndToken]); err != nil {
+			return nil, err
+		} else if matched {
+			break
+		}
+		if len(args) != 0 || len(kwargs) != 0 {
+			if _, _, err := p.expectToken(isTokenOfType[commaToken], "`,`"); err != nil {
+				return nil, err
+			}
+		}
+
+		expr, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if varExp, ok := expr.(varExpr); ok {
+			if matched, err := p.skipToken(isTokenOfType[assignToken]); err != nil {
+				return nil, err
+			} else if matched {
+				if firstSpan.IsNone() {
+					firstSpan = option.Some(varExp.span)
+				}
+				arg, err := p.parseExprNoIf()
+				if err != nil {
+					return nil, err
+				}
+				kwargs = append(kwargs, kwargExpr{key: varExp.id, arg: arg})
+				continue
+			}
+		}
+		if len(kwargs) != 0 {
+			return nil, syntaxError("non-keyword arg after keyword arg")
+		}
+		args = append(args, expr)
+	}
+
+	if len(kwargs) != 0 {
+		args = append(args, kwargsExpr{
+			pairs: kwargs,
+			span:  p.stream.expandSpan(firstSpan.Unwrap()),
+		})
+	}
+	return args, nil
+}
+
+// parseTrans parses a `{% trans %}` block into a call of one of the gettext
+// functions like the i18n extension of Jinja2.
+//
+// The block may start with a string literal for the message context which is
+// followed by the variables available in the body.  The first variable is the
+// plural count unless `{% pluralize %}` names one.  Only `{{ name }}`
+// placeholders are allowed in the body.
+func (p *parser) parseTrans(spn span) (emitExprStmt, error) {
+	msgctxt := option.None[string]()
+	if tkn, _, err := p.stream.current(); err != nil {
+		return emitExprStmt{}, err
+	} else if strTkn, ok := tkn.(strToken); ok {
+		msgctxt = option.Some(strTkn.s)
+		p.stream.next()
+	}
+
+	var vars []kwargExpr
+	findVar := func(name string) option.Option[astExpr] {
+		for _, v := range vars {
+			if v.key == name {
+				return option.Some(v.arg)
+			}
+		}
+		return option.None[astExpr]()
+	}
+	pluralExpr := option.None[astExpr]()
+	numCalledNum := false
+	trimmed := option.None[bool]()
+	for {
+		if matched, err := p.matchesToken(isTokenOfType[blockEndToken]); err != nil {
+			return emitExprStmt{}, err
+		} else if matched {
+			break
+		}
+		if len(vars) != 0 {
+			if _, _, err := p.expectToken(isTokenOfType[commaToken], "`,`"); err != nil {
+				return emitExprStmt{}, err
+			}
+		}
+		tkn, nameSpan, err := p.expectToken(isTokenOfType[identToken], "identifier")
+		if err != nil {
+			return emitExprStmt{}, err
+		}
+		name := tkn.(identToken).ident
+		if findVar(name).IsSome() {
+			return emitExprStmt{}, syntaxError(
+				fmt.Sprintf("translatable variable %s defined twice", name))
+		}
+		var expr astExpr
+		if matched, err := p.skipToken(isTokenOfType[assignToken]); err != nil {
+			return emitExprStmt{}, err
+		} else if matched {
+			if expr, err = p.parseExpr(); err != nil {
+				return emitExprStmt{}, err
+			}
+		} else if trimmed.IsNone() && (name == "trimmed" || name == "notrimmed") {
+			trimmed = option.Some(name == "trimmed")
+			continue
+		} else {
+			expr = varExpr{id: name, span: nameSpan}
+		}
+		vars = append(vars, kwargExpr{key: name, arg: expr})
+		if pluralExpr.IsNone() {
+			pluralExpr = option.Some(expr)
+			numCalledNum = name == "num"
+		}
+	}
+	if _, _, err := p.expectToken(isTokenOfType[blockEndToken], "end of block"); err != nil {
+		return emitExprStmt{}, err
+	}
+
+	singularNames, singular, hasPlural, err := p.parseTransBody(true)
+	if err != nil {
+		return emitExprStmt{}, err
+	}
+	var pluralNames []string
+	var plural string
+	if hasPlural {
+		if _, _, err := p.stream.next(); err != nil {
+			return emitExprStmt{}, err
+		}
+		if matched, err := p.matchesToken(isTokenOfType[blockEndToken]); err != nil {
+			return emitExprStmt{}, err
+		} else if !matched {
+			tkn, _, err := p.expectToken(isTokenOfType[identToken], "identifier")
+			if err != nil {
+				return emitExprStmt{}, err
+			}
+			name := tkn.(identToken).ident
+			var expr astExpr
+			if !findVar(name).UnwrapTo(&expr) {
+				return emitExprStmt{}, syntaxError(
+					fmt.Sprintf("unknown variable %s for pluralization", name))
+			}
+			pluralExpr = option.Some(expr)
+			numCalledNum = name == "num"
+		}
+		if _, _, err := p.expectToken(isTokenOfType[blockEndToken], "end of block"); err != nil {
+			return emitExprStmt{}, err
+		}
+		if pluralNames, plural, _, err = p.parseTransBody(false); err != nil {
+			return emitExprStmt{}, err
+		}
+	}
+	if _, _, err := p.stream.next(); err != nil {
+		return emitExprStmt{}, err
+	}
+
+	for _, name := range append(slices.Clip(singularNames), pluralNames...) {
+		if findVar(name).IsNone() {
+			vars = append(vars, kwargExpr{key: name, arg: varExpr{id: name, span: spn}})
+		}
+	}
+	if hasPlural && pluralExpr.IsNone() {
+		if len(singularNames) == 0 {
+			return emitExprStmt{}, syntaxError("pluralize without variables")
+		}
+		pluralExpr = findVar(singularNames[0])
+	}
+	if trimmed.UnwrapOr(false) {
+		singular = trimTransMessage(singular)
+		plural = trimTransMessage(plural)
+	}
+
+	var funcName string
+	switch {
+	case msgctxt.IsSome() && hasPlural:
+		funcName = "npgettext"
+	case msgctxt.IsSome():
+		funcName = "pgettext"
+	case hasPlural:
+		funcName = "ngettext"
+	default:
+		funcName = "gettext"
+	}
+	var args []astExpr
+	if msgctxt.IsSome() {
+		args = append(args, makeConst(valueFromString(msgctxt.Unwrap()), spn))
+	}
+	args = append(args, makeConst(valueFromString(singular), spn))
+	if hasPlural {
+		args = append(args, makeConst(valueFromString(plural), spn), pluralExpr.Unwrap())
+	}
+	kwargs := make([]kwargExpr, 0, len(vars))
+	for _, v := range vars {
+		// The count is passed as `num` by the plural functions.
+		if hasPlural && numCalledNum && v.key == "num" {
+			continue
+		}
+		kwargs = append(kwargs, v)
+	}
+	// Keyword arguments are always passed so that the message is formatted
+	// and `%%` is unescaped even if the body has no placeholders.
+	args = append(args, kwargsExpr{pairs: kwargs, span: spn})
+
+	return emitExprStmt{
+		expr: callExpr{call: spanned[call]{
+			data: call{expr: varExpr{id: funcName, span: spn}, args: args, trans: true},
+			span: spn,
+		}},
+	}, nil
+}
+
+// parseTransBody parses the body of a trans block up to `{% pluralize %}` or
+// `{% endtrans %}` and returns the names of the referenced variables and the
+// message in which they are replaced with `%(name)s` placeholders.  The
+// current token is the ident of the end tag on return.
+func (p *parser) parseTransBody(allowPluralize bool) (names []string, msg string, pluralize bool, err error) {
+	var b strings.Builder
+	for {
+		tkn, _, err := p.stream.next()
+		if err != nil {
+			return nil, "", false, err
+		}
+		switch tk := tkn.(type) {
+		case nil:
+			return nil, "", false, syntaxError("unclosed translation block")
+		case templateDataToken:
+			b.WriteString(strings.ReplaceAll(tk.s, "%", "%%"))
+		case variableStartToken:
+			tkn, _, err := p.expectToken(isTokenOfType[identToken], "identifier")
+			if err != nil {
+				return nil, "", false, err
+			}
+			name := tkn.(identToken).ident
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+			b.WriteString("%(" + name + ")s")
+			if _, _, err := p.expectToken(isTokenOfType[variableEndToken], "end of variable block"); err != nil {
+				return nil, "", false, err
+			}
+		case blockStartToken:
+			tkn, _, err := p.stream.current()
+			if err != nil {
+				return nil, "", false, err
+			}
+			if isIdentTokenWithName("endtrans")(tkn) {
+				return names, b.String(), false, nil
+			}
+			if isIdentTokenWithName("pluralize")(tkn) {
+				if allowPluralize {
+					return names, b.String(), true, nil
+				}
+				return nil, "", false, syntaxError(
+					"a translatable section can have only one pluralize section")
+			}
+			return nil, "", false, syntaxError(
+				"control structures in translatable sections are not allowed")
+		default:
+			panic("lexer produced garbage")
+		}
+	}
+}
+
+var transWhitespaceRe = regexp.MustCompile(`\s*\n\s*`)
+
+// trimTransMessage joins the lines of a message with single spaces and
+// strips the surrounding whitespace like the `trimmed` modifier of Jinja2.
+func trimTransMessage(msg string) string {
+	return transWhitespaceRe.ReplaceAllString(strings.TrimSpace(msg), " ")
+}
+
 func (p *parser) parseExtends() (extendsStmt, error) {
 	name, err := p.parseExpr()
 	if err != nil {
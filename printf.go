@@ -0,0 +1,315 @@
+package mjingo
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hnakamur/mjingo/internal/rustfmt"
+	"github.com/hnakamur/mjingo/option"
+)
+
+// printfArgs holds the arguments for printf-style formatting.
+//
+// This follows the rules of Python's `%` operator: a sequence provides
+// positional arguments, a map provides named arguments for `%(key)s` and
+// any other value is used as a single positional argument.
+type printfArgs struct {
+	positional []Value
+	mapping    option.Option[Value]
+	next       int
+}
+
+func newPrintfArgsFromValue(val Value) printfArgs {
+	switch v := val.data.(type) {
+	case seqValue:
+		return printfArgs{positional: v.Items}
+	case mapValue:
+		return printfArgs{positional: []Value{val}, mapping: option.Some(val)}
+	}
+	return printfArgs{positional: []Value{val}}
+}
+
+func (a *printfArgs) nextArg() (Value, error) {
+	if a.next >= len(a.positional) {
+		return Value{}, NewError(InvalidOperation, "not enough arguments for format string")
+	}
+	v := a.positional[a.next]
+	a.next++
+	return v, nil
+}
+
+func (a *printfArgs) namedArg(key string) (Value, error) {
+	var m Value
+	if !a.mapping.UnwrapTo(&m) {
+		return Value{}, NewError(InvalidOperation, "format requires a mapping")
+	}
+	var v Value
+	if !m.getItemOpt(valueFromString(key)).UnwrapTo(&v) {
+		return Value{}, NewError(InvalidOperation, fmt.Sprintf("missing key %q for format string", key))
+	}
+	return v, nil
+}
+
+func (a *printfArgs) checkAllUsed() error {
+	if a.mapping.IsNone() && a.next < len(a.positional) {
+		return NewError(InvalidOperation, "not all arguments converted during string formatting")
+	}
+	return nil
+}
+
+type printfSpec struct {
+	flags     string
+	width     int
+	hasWidth  bool
+	prec      int
+	hasPrec   bool
+	conv      byte
+	leftAlign bool
+}
+
+// goVerb builds a format string for Go's fmt package from the spec.
+func (s *printfSpec) goVerb(verb byte) string {
+	var b strings.Builder
+	b.WriteByte('%')
+	b.WriteString(s.flags)
+	if s.hasWidth {
+		fmt.Fprintf(&b, "%d", s.width)
+	}
+	if s.hasPrec {
+		fmt.Fprintf(&b, ".%d", s.prec)
+	}
+	b.WriteByte(verb)
+	return b.String()
+}
+
+// printfFormat formats args according to the printf-style format string
+// following the semantics of Python's `%` operator.
+func printfFormat(format string, args printfArgs) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(format); {
+		c := format[i]
+		if c != '%' {
+			j := strings.IndexByte(format[i:], '%')
+			if j == -1 {
+				b.WriteString(format[i:])
+				break
+			}
+			b.WriteString(format[i : i+j])
+			i += j
+			continue
+		}
+		i++
+		if i >= len(format) {
+			return "", NewError(InvalidOperation, "incomplete format")
+		}
+
+		var arg option.Option[Value]
+		if format[i] == '(' {
+			end := strings.IndexByte(format[i:], ')')
+			if end == -1 {
+				return "", NewError(InvalidOperation, "incomplete format key")
+			}
+			v, err := args.namedArg(format[i+1 : i+end])
+			if err != nil {
+				return "", err
+			}
+			arg = option.Some(v)
+			i += end + 1
+		}
+
+		var spec printfSpec
+		for ; i < len(format) && strings.IndexByte("-+ #0", format[i]) != -1; i++ {
+			if format[i] == '-' {
+				spec.leftAlign = true
+			}
+			if strings.IndexByte(spec.flags, format[i]) == -1 {
+				spec.flags += string(format[i])
+			}
+		}
+		if spec.leftAlign {
+			// '-' overrides '0' like in Python.
+			spec.flags = strings.ReplaceAll(spec.flags, "0", "")
+		}
+
+		width, hasWidth, n, err := parsePrintfNum(format[i:], &args)
+		if err != nil {
+			return "", err
+		}
+		spec.width, spec.hasWidth = width, hasWidth
+		i += n
+		if spec.hasWidth && spec.width < 0 {
+			spec.width = -spec.width
+			spec.leftAlign = true
+			if !strings.Contains(spec.flags, "-") {
+				spec.flags += "-"
+			}
+		}
+		if i < len(format) && format[i] == '.' {
+			i++
+			prec, _, n, err := parsePrintfNum(format[i:], &args)
+			if err != nil {
+				return "", err
+			}
+			spec.prec, spec.hasPrec = max(prec, 0), true
+			i += n
+		}
+		// Length modifiers are accepted and ignored like in Python.
+		for ; i < len(format) && strings.IndexByte("hlL", format[i]) != -1; i++ {
+		}
+		if i >= len(format) {
+			return "", NewError(InvalidOperation, "incomplete format")
+		}
+		spec.conv = format[i]
+		i++
+
+		if spec.conv == '%' {
+			b.WriteByte('%')
+			continue
+		}
+		var val Value
+		if !arg.UnwrapTo(&val) {
+			if val, err = args.nextArg(); err != nil {
+				return "", err
+			}
+		}
+		s, err := formatPrintfValue(&spec, val)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(s)
+	}
+	if err := args.checkAllUsed(); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// parsePrintfNum parses a width or precision which is either a decimal
+// number or `*` to take the value from the arguments.
+func parsePrintfNum(s string, args *printfArgs) (num int, found bool, consumed int, err error) {
+	if s != "" && s[0] == '*' {
+		v, err := args.nextArg()
+		if err != nil {
+			return 0, false, 0, err
+		}
+		n, err := v.tryToI64()
+		if err != nil {
+			return 0, false, 0, NewError(InvalidOperation, "* wants int")
+		}
+		return int(n), true, 1, nil
+	}
+	for consumed < len(s) && s[consumed] >= '0' && s[consumed] <= '9' {
+		num = num*10 + int(s[consumed]-'0')
+		consumed++
+	}
+	return num, consumed > 0, consumed, nil
+}
+
+func formatPrintfValue(spec *printfSpec, val Value) (string, error) {
+	if strings.IndexByte("srac", spec.conv) != -1 {
+		// Only the '-' flag has an effect on string conversions.
+		if spec.leftAlign {
+			spec.flags = "-"
+		} else {
+			spec.flags = ""
+		}
+	}
+	switch spec.conv {
+	case 's':
+		return fmt.Sprintf(spec.goVerb('s'), fmt.Sprintf(rustfmt.DisplayString, val)), nil
+	case 'r', 'a':
+		return fmt.Sprintf(spec.goVerb('s'), fmt.Sprintf(rustfmt.DebugString, val)), nil
+	case 'd', 'i', 'u':
+		n, err := printfIntArg(spec.conv, val)
+		if err != nil {
+			return "", err
+		}
+		// Python ignores the precision for integers.
+		spec.hasPrec = false
+		return fmt.Sprintf(spec.goVerb('d'), n), nil
+	case 'x', 'X', 'o':
+		n, err := printfIntArg(spec.conv, val)
+		if err != nil {
+			return "", err
+		}
+		spec.hasPrec = false
+		verb := spec.conv
+		if verb == 'o' && strings.Contains(spec.flags, "#") {
+			// Python uses the `0o` prefix for the alternate form.
+			spec.flags = strings.ReplaceAll(spec.flags, "#", "")
+			verb = 'O'
+		}
+		return fmt.Sprintf(spec.goVerb(verb), n), nil
+	case 'e', 'E', 'f', 'F', 'g', 'G':
+		var f float64
+		if !val.asF64().UnwrapTo(&f) {
+			return "", printfTypeError(spec.conv, val)
+		}
+		if !spec.hasPrec {
+			spec.prec, spec.hasPrec = 6, true
+		}
+		verb := spec.conv
+		if verb == 'F' {
+			verb = 'f'
+		}
+		rv := fmt.Sprintf(spec.goVerb(verb), f)
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			rv = strings.NewReplacer("+Inf", "inf", "-Inf", "-inf", "Inf", "inf", "NaN", "nan").Replace(rv)
+			if spec.conv >= 'A' && spec.conv <= 'Z' {
+				rv = strings.ToUpper(rv)
+			}
+		}
+		return rv, nil
+	case 'c':
+		var r rune
+		if s, ok := valueAsGoString(val); ok {
+			if utf8.RuneCountInString(s) != 1 {
+				return "", NewError(InvalidOperation, "%c requires int or char")
+			}
+			r, _ = utf8.DecodeRuneInString(s)
+		} else if n, err := val.tryToI64(); err == nil && n >= 0 && n <= utf8.MaxRune {
+			r = rune(n)
+		} else {
+			return "", NewError(InvalidOperation, "%c requires int or char")
+		}
+		spec.hasPrec = false
+		return fmt.Sprintf(spec.goVerb('c'), r), nil
+	}
+	return "", NewError(InvalidOperation,
+		fmt.Sprintf("unsupported format character %q", spec.conv))
+}
+
+func printfIntArg(conv byte, val Value) (*big.Int, error) {
+	switch v := val.data.(type) {
+	case boolValue:
+		if v.B {
+			return big.NewInt(1), nil
+		}
+		return big.NewInt(0), nil
+	case i64Value:
+		return big.NewInt(v.N), nil
+	case u64Value:
+		return new(big.Int).SetUint64(v.N), nil
+	case i128Value:
+		n := v.N.BigInt()
+		return &n, nil
+	case u128Value:
+		n := v.N.BigInt()
+		return &n, nil
+	case f64Value:
+		if math.IsInf(v.F, 0) || math.IsNaN(v.F) {
+			return nil, NewError(InvalidOperation, "cannot convert float to integer")
+		}
+		n, _ := big.NewFloat(v.F).Int(nil)
+		return n, nil
+	}
+	return nil, printfTypeError(conv, val)
+}
+
+func printfTypeError(conv byte, val Value) error {
+	return NewError(InvalidOperation,
+		fmt.Sprintf("%%%c format: a number is required, not %s", conv, val.Kind()))
+}
@@ -0,0 +1,53 @@
+package mjingo
+
+import "testing"
+
+func TestPrintfFormat(t *testing.T) {
+	testCases := []struct {
+		format string
+		args   Value
+		want   string
+	}{
+		{format: "%s", args: valueFromString("a"), want: "a"},
+		{format: "%s and %s", args: valueFromSlice([]Value{valueFromI64(1), valueFromBool(true)}), want: "1 and true"},
+		{format: "%5.1s|", args: valueFromString("abc"), want: "    a|"},
+		{format: "%*d", args: valueFromSlice([]Value{valueFromI64(4), valueFromI64(7)}), want: "   7"},
+		{format: "%+d % d", args: valueFromSlice([]Value{valueFromI64(3), valueFromI64(3)}), want: "+3  3"},
+		{format: "%d", args: valueFromF64(3.9), want: "3"},
+		{format: "%d", args: valueFromU128(*U128FromUint64(18446744073709551615)), want: "18446744073709551615"},
+		{format: "%#o", args: valueFromI64(8), want: "0o10"},
+		{format: "%e", args: valueFromF64(12345.678), want: "1.234568e+04"},
+		{format: "%g", args: valueFromF64(0.5), want: "0.5"},
+		{format: "%f", args: valueFromI64(2), want: "2.000000"},
+		{format: "%c%c", args: valueFromSlice([]Value{valueFromI64(65), valueFromString("b")}), want: "Ab"},
+		{format: "%s", args: valueFromSlice([]Value{valueFromSlice([]Value{valueFromI64(1)})}), want: "[1]"},
+	}
+	for _, tc := range testCases {
+		got, err := printfFormat(tc.format, newPrintfArgsFromValue(tc.args))
+		if err != nil {
+			t.Errorf("format=%q: unexpected error: %s", tc.format, err)
+		} else if got != tc.want {
+			t.Errorf("format=%q: got=%q, want=%q", tc.format, got, tc.want)
+		}
+	}
+}
+
+func TestPrintfFormatError(t *testing.T) {
+	testCases := []struct {
+		format string
+		args   Value
+	}{
+		{format: "%s %s", args: valueFromString("a")},
+		{format: "%s", args: valueFromSlice([]Value{valueFromI64(1), valueFromI64(2)})},
+		{format: "%d", args: valueFromString("a")},
+		{format: "%(a)s", args: valueFromI64(1)},
+		{format: "%(b)s", args: valueFromIndexMap(newValueMap())},
+		{format: "%", args: valueFromI64(1)},
+		{format: "%y", args: valueFromI64(1)},
+	}
+	for _, tc := range testCases {
+		if _, err := printfFormat(tc.format, newPrintfArgsFromValue(tc.args)); err == nil {
+			t.Errorf("format=%q: expected an error", tc.format)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package mjingo
+
+import (
+	"container/list"
+	"regexp"
+	"sync"
+
+	"github.com/hnakamur/mjingo/option"
+)
+
+// DefaultRegexCacheSize is the default maximum number of compiled regular
+// expressions cached by an [Environment].
+const DefaultRegexCacheSize = 128
+
+// regexCache is a least recently used cache of compiled regular expressions
+// which is safe for concurrent use.
+type regexCache struct {
+	mu      sync.Mutex
+	maxSize int
+	entries map[string]*list.Element
+	order   list.List // of *regexCacheEntry, most recently used first
+}
+
+type regexCacheEntry struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+func newRegexCache(maxSize int) *regexCache {
+	return &regexCache{maxSize: maxSize, entries: make(map[string]*list.Element)}
+}
+
+// get returns the compiled pattern, compiling and caching it if needed.
+func (c *regexCache) get(pattern string) (*regexp.Regexp, error) {
+	c.mu.Lock()
+	if elem, ok := c.entries[pattern]; ok {
+		c.order.MoveToFront(elem)
+		c.mu.Unlock()
+		return elem.Value.(*regexCacheEntry).re, nil
+	}
+	c.mu.Unlock()
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, NewError(InvalidOperation, "invalid regular expression").withSource(err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.maxSize <= 0 {
+		return re, nil
+	}
+	if elem, ok := c.entries[pattern]; ok {
+		c.order.MoveToFront(elem)
+		return elem.Value.(*regexCacheEntry).re, nil
+	}
+	c.entries[pattern] = c.order.PushFront(&regexCacheEntry{pattern: pattern, re: re})
+	c.evict()
+	return re, nil
+}
+
+func (c *regexCache) setMaxSize(maxSize int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maxSize = maxSize
+	c.evict()
+}
+
+func (c *regexCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *regexCache) evict() {
+	for c.order.Len() > max(c.maxSize, 0) {
+		elem := c.order.Back()
+		c.order.Remove(elem)
+		delete(c.entries, elem.Value.(*regexCacheEntry).pattern)
+	}
+}
+
+// compileRegex compiles pattern with the flags given as the optional
+// `ignorecase` and `multiline` arguments using the cache of the environment.
+func compileRegex(state *State, pattern string, ignoreCase, multiline option.Option[Value]) (*regexp.Regexp, error) {
+	i, err := argOr(ignoreCase, false, valueTryToGoBool)
+	if err != nil {
+		return nil, err
+	}
+	m, err := argOr(multiline, false, valueTryToGoBool)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case i && m:
+		pattern = "(?im)" + pattern
+	case i:
+		pattern = "(?i)" + pattern
+	case m:
+		pattern = "(?m)" + pattern
+	}
+	return state.env.regexCache.get(pattern)
+}
@@ -0,0 +1,31 @@
+package mjingo
+
+import "testing"
+
+func TestRegexCache(t *testing.T) {
+	c := newRegexCache(2)
+	for _, pattern := range []string{"a", "b", "a", "c"} {
+		if _, err := c.get(pattern); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := c.len(), 2; got != want {
+		t.Errorf("len mismatch, got=%d, want=%d", got, want)
+	}
+	// "b" is the least recently used pattern.
+	if _, ok := c.entries["b"]; ok {
+		t.Error(`"b" should have been evicted`)
+	}
+
+	c.setMaxSize(0)
+	if _, err := c.get("d"); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.len(); got != 0 {
+		t.Errorf("len mismatch, got=%d, want=0", got)
+	}
+
+	if _, err := c.get("("); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
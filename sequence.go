@@ -0,0 +1,412 @@
+package mjingo
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/hnakamur/mjingo/option"
+)
+
+// lockedRand is a random number generator which is safe for concurrent use.
+type lockedRand struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+func newLockedRand(seed int64) *lockedRand {
+	return &lockedRand{r: rand.New(rand.NewSource(seed))}
+}
+
+func newLockedRandFromTime() *lockedRand {
+	return newLockedRand(time.Now().UnixNano())
+}
+
+func (r *lockedRand) seed(seed int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.r.Seed(seed)
+}
+
+func (r *lockedRand) intn(n int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.r.Intn(n)
+}
+
+func (r *lockedRand) shuffle(n int, swap func(i, j int)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.r.Shuffle(n, swap)
+}
+
+// seqObjectsOf returns the sequences of values if all of them are
+// sequences.
+func seqObjectsOf(values []Value) ([]SeqObject, bool) {
+	seqs := make([]SeqObject, 0, len(values))
+	for _, val := range values {
+		var seq SeqObject
+		if !val.asSeq().UnwrapTo(&seq) {
+			return nil, false
+		}
+		seqs = append(seqs, seq)
+	}
+	return seqs, true
+}
+
+// chainSeqObject is a sequence of the items of multiple sequences which
+// are looked up when accessed.
+type chainSeqObject struct {
+	seqs []SeqObject
+}
+
+var _ = SeqObject((*chainSeqObject)(nil))
+
+func (*chainSeqObject) Kind() ObjectKind { return ObjectKindSeq }
+
+func (c *chainSeqObject) GetItem(idx uint) option.Option[Value] {
+	for _, seq := range c.seqs {
+		n := seq.ItemCount()
+		if idx < n {
+			return seq.GetItem(idx)
+		}
+		idx -= n
+	}
+	return option.None[Value]()
+}
+
+func (c *chainSeqObject) ItemCount() uint {
+	var n uint
+	for _, seq := range c.seqs {
+		n += seq.ItemCount()
+	}
+	return n
+}
+
+// zipSeqObject is a sequence of lists of the items at the same index in
+// multiple sequences.  It is as long as the shortest sequence.
+type zipSeqObject struct {
+	seqs []SeqObject
+}
+
+var _ = SeqObject((*zipSeqObject)(nil))
+
+func (*zipSeqObject) Kind() ObjectKind { return ObjectKindSeq }
+
+func (z *zipSeqObject) GetItem(idx uint) option.Option[Value] {
+	if idx >= z.ItemCount() {
+		return option.None[Value]()
+	}
+	items := make([]Value, 0, len(z.seqs))
+	for _, seq := range z.seqs {
+		items = append(items, seq.GetItem(idx).UnwrapOr(Undefined))
+	}
+	return option.Some(valueFromSlice(items))
+}
+
+func (z *zipSeqObject) ItemCount() uint {
+	if len(z.seqs) == 0 {
+		return 0
+	}
+	n := z.seqs[0].ItemCount()
+	for _, seq := range z.seqs[1:] {
+		n = min(n, seq.ItemCount())
+	}
+	return n
+}
+
+// subSeqObject is a view of at most count items of a sequence starting
+// at offset.
+type subSeqObject struct {
+	seq    SeqObject
+	offset uint
+	count  uint
+}
+
+var _ = SeqObject((*subSeqObject)(nil))
+
+func (*subSeqObject) Kind() ObjectKind { return ObjectKindSeq }
+
+func (s *subSeqObject) GetItem(idx uint) option.Option[Value] {
+	if idx >= s.ItemCount() {
+		return option.None[Value]()
+	}
+	return s.seq.GetItem(s.offset + idx)
+}
+
+func (s *subSeqObject) ItemCount() uint {
+	n := s.seq.ItemCount()
+	if n <= s.offset {
+		return 0
+	}
+	return min(n-s.offset, s.count)
+}
+
+// mappedIterableObject is an iterable of the items of another iterable
+// which are converted by f when they are pulled.  f returns None to skip an
+// item.  If f fails or an item is already an invalid value, the iteration
+// ends with an invalid value holding the error, which makes the consumers
+// of the iterable fail.
+type mappedIterableObject struct {
+	obj IterableObject
+	f   func(item Value) (option.Option[Value], error)
+}
+
+var _ = IterableObject((*mappedIterableObject)(nil))
+
+func (*mappedIterableObject) Kind() ObjectKind { return ObjectKindIterable }
+
+func (m *mappedIterableObject) Iterate() func() option.Option[Value] {
+	next := m.obj.Iterate()
+	done := false
+	return func() option.Option[Value] {
+		for item := (Value{}); !done && next().UnwrapTo(&item); {
+			if invalidValueErr(item) != nil {
+				done = true
+				return option.Some(item)
+			}
+			rv, err := m.f(item)
+			if err != nil {
+				done = true
+				return option.Some(mapErrToInvalidValue(Value{}, err))
+			}
+			if rv.IsSome() {
+				return rv
+			}
+		}
+		done = true
+		return option.None[Value]()
+	}
+}
+
+// Chains multiple iterables into a single sequence.
+//
+// If all values are sequences, the items are looked up when they are
+// accessed instead of being copied.  Other iterables like strings and maps
+// are iterated right away.
+//
+// ```jinja
+// {{ [1, 2]|chain([3], "ab")|join(",") }}
+//
+//	-> 1,2,3,a,b
+//
+// ```
+func chainFilter(val Value, others ...Value) (Value, error) {
+	values := append([]Value{val}, others...)
+	if seqs, ok := seqObjectsOf(values); ok {
+		return ValueFromObject(&chainSeqObject{seqs: seqs}), nil
+	}
+	iter, err := val.tryIter()
+	if err != nil {
+		return Value{}, err
+	}
+	for _, other := range others {
+		otherIter, err := other.tryIter()
+		if err != nil {
+			return Value{}, err
+		}
+		iter = iter.Chain(otherIter)
+	}
+	items, err := iter.Collect()
+	if err != nil {
+		return Value{}, err
+	}
+	return valueFromSlice(items), nil
+}
+
+// Zips multiple iterables into a sequence of lists.
+//
+// The n-th list contains the n-th items of all iterables and the result
+// is as long as the shortest iterable.
+//
+// ```jinja
+// {% for name, age in ["alice", "bob"]|zip([31, 42]) %}
+//
+//	{{ name }} is {{ age }}
+//
+// {% endfor %}
+// ```
+func zipFilter(val Value, others ...Value) (Value, error) {
+	values := append([]Value{val}, others...)
+	if seqs, ok := seqObjectsOf(values); ok {
+		return ValueFromObject(&zipSeqObject{seqs: seqs}), nil
+	}
+	iters := make([]iterator, 0, len(values))
+	for _, v := range values {
+		iter, err := v.tryIter()
+		if err != nil {
+			return Value{}, err
+		}
+		iters = append(iters, iter)
+	}
+	var rv []Value
+	for {
+		items := make([]Value, 0, len(iters))
+		for i := range iters {
+			var item Value
+			if !iters[i].Next().UnwrapTo(&item) {
+				if err := iters[i].Err(); err != nil {
+					return Value{}, err
+				}
+				return valueFromSlice(rv), nil
+			}
+			items = append(items, item)
+		}
+		rv = append(rv, valueFromSlice(items))
+	}
+}
+
+// Flattens nested sequences into a single list.
+//
+// By default all levels are flattened.  If levels is given, only that many
+// levels of nesting are removed.  Strings and maps are not flattened.
+//
+// ```jinja
+// {{ [1, [2, [3, [4]]]]|flatten(1) }}
+//
+//	-> [1, 2, [3, [4]]]
+//
+// ```
+func flattenFilter(val Value, levels option.Option[uint]) (Value, error) {
+	depth := -1
+	var n uint
+	if levels.UnwrapTo(&n) {
+		depth = int(n)
+	}
+	rv, err := flattenValues(nil, val, depth)
+	if err != nil {
+		return Value{}, err
+	}
+	return valueFromSlice(rv), nil
+}
+
+func flattenValues(dest []Value, val Value, depth int) ([]Value, error) {
+	iter, err := val.tryIter()
+	if err != nil {
+		return nil, err
+	}
+	for item := (Value{}); iter.Next().UnwrapTo(&item); {
+		if item.Kind() == ValueKindSeq && depth != 0 {
+			if dest, err = flattenValues(dest, item, depth-1); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		dest = append(dest, item)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	return dest, nil
+}
+
+// Returns the first n items of an iterable.
+//
+// ```jinja
+// {{ [1, 2, 3, 4]|take(2) }}
+//
+//	-> [1, 2]
+//
+// ```
+func takeFilter(val Value, n uint) (Value, error) {
+	var seq SeqObject
+	if val.asSeq().UnwrapTo(&seq) {
+		return ValueFromObject(&subSeqObject{seq: seq, count: n}), nil
+	}
+	iter, err := val.tryIter()
+	if err != nil {
+		return Value{}, err
+	}
+	rv := make([]Value, 0, min(n, iter.Len()))
+	for item := (Value{}); uint(len(rv)) < n && iter.Next().UnwrapTo(&item); {
+		rv = append(rv, item)
+	}
+	if err := iter.Err(); err != nil {
+		return Value{}, err
+	}
+	return valueFromSlice(rv), nil
+}
+
+// Returns the items of an iterable after skipping the first n items.
+//
+// ```jinja
+// {{ [1, 2, 3, 4]|skip(2) }}
+//
+//	-> [3, 4]
+//
+// ```
+func skipFilter(val Value, n uint) (Value, error) {
+	var seq SeqObject
+	if val.asSeq().UnwrapTo(&seq) {
+		return ValueFromObject(&subSeqObject{seq: seq, offset: n, count: ^uint(0)}), nil
+	}
+	iter, err := val.tryIter()
+	if err != nil {
+		return Value{}, err
+	}
+	for i := uint(0); i < n && iter.Next().IsSome(); i++ {
+	}
+	items, err := iter.Collect()
+	if err != nil {
+		return Value{}, err
+	}
+	return valueFromSlice(items), nil
+}
+
+// Returns a random item of an iterable.
+//
+// Undefined is returned for an empty iterable.  The random number
+// generator of the environment can be seeded with
+// [Environment.SetRandomSeed].
+//
+// ```jinja
+// {{ ["red", "green", "blue"]|random }}
+// ```
+func randomFilter(state *State, val Value) (Value, error) {
+	var seq SeqObject
+	if val.asSeq().UnwrapTo(&seq) {
+		n := seq.ItemCount()
+		if n == 0 {
+			return Undefined, nil
+		}
+		return seq.GetItem(uint(state.env.rand.intn(int(n)))).UnwrapOr(Undefined), nil
+	}
+	iter, err := val.tryIter()
+	if err != nil {
+		return Value{}, err
+	}
+	items, err := iter.Collect()
+	if err != nil {
+		return Value{}, err
+	}
+	if len(items) == 0 {
+		return Undefined, nil
+	}
+	return items[state.env.rand.intn(len(items))], nil
+}
+
+// Returns the items of an iterable in random order.
+//
+// The random number generator of the environment can be seeded with
+// [Environment.SetRandomSeed].
+//
+// ```jinja
+// {{ [1, 2, 3]|shuffle }}
+//
+//	-> [3, 1, 2]
+//
+// ```
+func shuffleFilter(state *State, val Value) (Value, error) {
+	iter, err := val.tryIter()
+	if err != nil {
+		return Value{}, err
+	}
+	items, err := iter.Collect()
+	if err != nil {
+		return Value{}, err
+	}
+	state.env.rand.shuffle(len(items), func(i, j int) {
+		items[i], items[j] = items[j], items[i]
+	})
+	return valueFromSlice(items), nil
+}
@@ -2,12 +2,14 @@ package mjingo
 
 import (
 	"cmp"
+	"encoding"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/hnakamur/mjingo/internal/rustfmt"
 	"github.com/hnakamur/mjingo/option"
@@ -94,7 +96,12 @@ func serializeNone() (Value, error) {
 type ValueFromGoValueOption func(*valueFromGoValueConfig)
 
 type valueFromGoValueConfig struct {
-	structTag string
+	structTag      string
+	marshalers     bool
+	converters     map[reflect.Type]func(any) Value
+	methods        bool
+	allowedMethods []string
+	deniedMethods  []string
 }
 
 // WithStructTag sets the struct tag which is used to reference a struct field.
@@ -106,12 +113,98 @@ func WithStructTag(tag string) ValueFromGoValueOption {
 	}
 }
 
+// WithMarshalers enables conversion of types which implement the
+// marshaling interfaces.
+//
+// A type which implements json.Marshaler is converted by parsing the JSON
+// it generates, a type which implements encoding.TextMarshaler is
+// converted to a string and a type which implements fmt.Stringer is
+// converted to a string as well.  The interfaces are checked in that order
+// and only for types which are not supported natively.
+func WithMarshalers() ValueFromGoValueOption {
+	return func(cfg *valueFromGoValueConfig) {
+		cfg.marshalers = true
+	}
+}
+
+// WithConverter registers a function which converts a Go value of the type
+// ty to a value.
+//
+// Converters take precedence over the built-in conversions and the
+// marshaling interfaces enabled with [WithMarshalers].
+func WithConverter(ty reflect.Type, f func(any) Value) ValueFromGoValueOption {
+	return func(cfg *valueFromGoValueConfig) {
+		if cfg.converters == nil {
+			cfg.converters = make(map[reflect.Type]func(any) Value)
+		}
+		cfg.converters[ty] = f
+	}
+}
+
+// WithMethods enables calling the exported methods of structs from
+// templates.
+//
+// Methods cannot be called by default since they may have side effects.
+// Use [WithAllowedMethods] instead to enable only some of the methods.
+func WithMethods() ValueFromGoValueOption {
+	return func(cfg *valueFromGoValueConfig) {
+		cfg.methods = true
+	}
+}
+
+// WithAllowedMethods enables calling the exported methods of structs with
+// the specified names from templates.
+//
+// The other methods cannot be called even if [WithMethods] is specified.
+func WithAllowedMethods(names ...string) ValueFromGoValueOption {
+	return func(cfg *valueFromGoValueConfig) {
+		cfg.allowedMethods = append(cfg.allowedMethods, names...)
+	}
+}
+
+// WithDeniedMethods prevents the methods of structs with the specified
+// names from being called from templates.
+//
+// This takes precedence over [WithAllowedMethods].
+func WithDeniedMethods(names ...string) ValueFromGoValueOption {
+	return func(cfg *valueFromGoValueConfig) {
+		cfg.deniedMethods = append(cfg.deniedMethods, names...)
+	}
+}
+
+func (c *valueFromGoValueConfig) isMethodAllowed(name string) bool {
+	if slices.Contains(c.deniedMethods, name) {
+		return false
+	}
+	if len(c.allowedMethods) > 0 {
+		return slices.Contains(c.allowedMethods, name)
+	}
+	return c.methods
+}
+
 // ValueFromGoValue creates a value from a Go value.
 //
 // Supported scalar types are bool, uint8, uint16, uint32, uint64, uint, int8, int16,
-// int32, int64, int, json.Number, I128, U128, float32, float64, string, nil, Value.
+// int32, int64, int, json.Number, I128, U128, float32, float64, string, []byte,
+// nil, Value.  A []byte becomes a bytes value which has a length and
+// yields its bytes as numbers when iterated or indexed.
+//
+// A time.Time is converted to a date object with the attributes year,
+// month, day, hour, minute, second, microsecond, nanosecond, weekday
+// (Monday is 0), yearday, timestamp, timezone and offset (in seconds east
+// of UTC).  A time.Duration is converted to an object with the attributes
+// days, hours, minutes, seconds, nanoseconds and total_seconds.  Both can
+// be formatted with the date filters like `datetimeformat`.
 //
 // And struct, slice, pointer, and map of these types are supported.
+//
+// If [WithMethods] or [WithAllowedMethods] is specified, the exported
+// methods of a struct can be called from templates like
+// `{{ user.FullName() }}`.  Methods cannot be called without these options.
+// The methods with a pointer receiver can be called only if a pointer to
+// the struct is converted.  The arguments are converted like the ones of
+// [BoxedFuncFromFuncReflect] and a method may return an error as its second
+// return value.  Use [WithDeniedMethods] to exclude some of the methods.
 func ValueFromGoValue(val any, opts ...ValueFromGoValueOption) Value {
 	var config valueFromGoValueConfig
 	for _, opt := range opts {
@@ -140,6 +233,11 @@ func valueFromGoValueHelper(val any, config *valueFromGoValueConfig, level uint)
 	if level >= maxNestLevelForValueFromGoValue {
 		return Value{data: invalidValue{Detail: "nested level too deep"}}
 	}
+	if len(config.converters) > 0 {
+		if f, ok := config.converters[reflect.TypeOf(val)]; ok {
+			return f(val)
+		}
+	}
 	switch v := val.(type) {
 	case bool:
 		return mapErrToInvalidValue(serializeBool(v))
@@ -189,6 +287,8 @@ func valueFromGoValueHelper(val any, config *valueFromGoValueConfig, level uint)
 	// 	return mapErrToInvalidValue(serializeRune(v))
 	case string:
 		return mapErrToInvalidValue(serializeStr(v))
+	case []byte:
+		return mapErrToInvalidValue(serializeBytes(v))
 	case nil:
 		return mapErrToInvalidValue(serializeNone())
 	case Value:
@@ -196,10 +296,19 @@ func valueFromGoValueHelper(val any, config *valueFromGoValueConfig, level uint)
 	case Object:
 		return ValueFromObject(v)
 	case map[string]any:
-		return valueFromStrKeyGoMap(v)
+		return valueFromStrKeyGoMap(v, config, level)
 	case []Value:
 		return valueFromSlice(v)
+	case time.Time:
+		return valueFromTime(v)
+	case time.Duration:
+		return ValueFromObject(durationObject{d: v})
 	default:
+		if config.marshalers {
+			if rv, ok := valueFromGoMarshaler(v); ok {
+				return rv
+			}
+		}
 		ty := reflect.TypeOf(v)
 		k := ty.Kind()
 		switch k {
@@ -210,13 +319,61 @@ func valueFromGoValueHelper(val any, config *valueFromGoValueConfig, level uint)
 		case reflect.Map:
 			return valueFromGoMapReflect(reflect.ValueOf(v), config, level)
 		case reflect.Ptr:
-			return valueFromGoValueHelper(reflect.ValueOf(v).Elem().Interface(), config, level+1)
+			ptr := reflect.ValueOf(v)
+			if ptr.IsNil() {
+				return none
+			}
+			elem := ptr.Elem()
+			rv := valueFromGoValueHelper(elem.Interface(), config, level+1)
+			// Keep the addressable struct so that the methods with a pointer
+			// receiver can be called.
+			if dy, ok := rv.data.(dynamicValue); ok {
+				if obj, ok := dy.Dy.(*reflectStructObject); ok && obj.val.Type() == elem.Type() {
+					obj.val = elem
+				}
+			}
+			return rv
 		}
 		return mapErrToInvalidValue(Value{}, fmt.Errorf("unsupported type: %T, ty=%+v, kind=%s", val, ty, k))
 	}
 }
 
-func valueFromStrKeyGoMap[V any](m map[string]V) Value {
+// valueFromGoMarshaler converts val with the marshaling interface it
+// implements.  The methods with a pointer receiver are used for a
+// non-pointer val as well.
+func valueFromGoMarshaler(val any) (Value, bool) {
+	rv := reflect.ValueOf(val)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return Value{}, false
+		}
+	} else if ptrTy := reflect.PointerTo(rv.Type()); ptrTy.Implements(reflectType[json.Marshaler]()) ||
+		ptrTy.Implements(reflectType[encoding.TextMarshaler]()) ||
+		ptrTy.Implements(reflectType[fmt.Stringer]()) {
+		ptr := reflect.New(rv.Type())
+		ptr.Elem().Set(rv)
+		val = ptr.Interface()
+	}
+	switch m := val.(type) {
+	case json.Marshaler:
+		data, err := m.MarshalJSON()
+		if err != nil {
+			return mapErrToInvalidValue(Value{}, err), true
+		}
+		return mapErrToInvalidValue(unmarshalJSON(string(data))), true
+	case encoding.TextMarshaler:
+		text, err := m.MarshalText()
+		if err != nil {
+			return mapErrToInvalidValue(Value{}, err), true
+		}
+		return valueFromString(string(text)), true
+	case fmt.Stringer:
+		return valueFromString(m.String()), true
+	}
+	return Value{}, false
+}
+
+func valueFromStrKeyGoMap[V any](m map[string]V, config *valueFromGoValueConfig, level uint) Value {
 	keys := make([]string, 0, len(m))
 	for key := range m {
 		keys = append(keys, key)
@@ -224,7 +381,7 @@ func valueFromStrKeyGoMap[V any](m map[string]V) Value {
 	slices.Sort(keys)
 	vm := newValueMap()
 	for _, key := range keys {
-		valVal := ValueFromGoValue(m[key])
+		valVal := valueFromGoValueHelper(m[key], config, level+1)
 		vm.Set(keyRefFromString(key), valVal)
 	}
 	return valueFromIndexMap(vm)
@@ -255,6 +412,14 @@ func mapErrToInvalidValue(val Value, err error) Value {
 	return val
 }
 
+// invalidValueErr returns the error held by v if it is an invalid value.
+func invalidValueErr(v Value) error {
+	if vInvalid, ok := v.data.(invalidValue); ok {
+		return NewError(BadSerialization, vInvalid.Detail)
+	}
+	return nil
+}
+
 func valueFromGoMapReflect(val reflect.Value, config *valueFromGoValueConfig, level uint) Value {
 	entries := make([]valueMapEntry, 0, val.Len())
 	for iter := val.MapRange(); iter.Next(); {
@@ -279,6 +444,7 @@ type reflectStructObject struct {
 
 var _ = (Object)((*reflectStructObject)(nil))
 var _ = (StructObject)((*reflectStructObject)(nil))
+var _ = (CallMethoder)((*reflectStructObject)(nil))
 
 func structObjectWithReflect(val reflect.Value, config *valueFromGoValueConfig, level uint) *reflectStructObject {
 	return &reflectStructObject{val: val, config: config, level: level}
@@ -319,8 +485,12 @@ func (o *reflectStructObject) collectFieldNames() {
 }
 
 func (s *reflectStructObject) keyNameForField(f reflect.StructField) string {
-	if s.config.structTag != "" {
-		if tagVal, ok := f.Tag.Lookup(s.config.structTag); ok {
+	return s.config.keyNameForField(f)
+}
+
+func (c *valueFromGoValueConfig) keyNameForField(f reflect.StructField) string {
+	if c.structTag != "" {
+		if tagVal, ok := f.Tag.Lookup(c.structTag); ok {
 			nameInTag, _, _ := strings.Cut(tagVal, ",")
 			if nameInTag != "" {
 				return nameInTag
@@ -332,6 +502,56 @@ func (s *reflectStructObject) keyNameForField(f reflect.StructField) string {
 
 func (*reflectStructObject) Fields() []string { return nil }
 
+func (s *reflectStructObject) CallMethod(state *State, name string, args []Value) (Value, error) {
+	var method reflect.Value
+	if s.config.isMethodAllowed(name) {
+		if s.val.CanAddr() {
+			method = s.val.Addr().MethodByName(name)
+		} else {
+			method = s.val.MethodByName(name)
+		}
+	}
+	if !method.IsValid() {
+		return Value{}, NewError(UnknownMethod,
+			fmt.Sprintf("%s has no method named %s", s.val.Type(), name))
+	}
+
+	fnType := method.Type()
+	numOut := fnType.NumOut()
+	if numOut != 1 && numOut != 2 || !canConvertibleToValue(fnType.Out(0)) ||
+		numOut == 2 && fnType.Out(1) != reflectType[error]() {
+		return Value{}, NewError(InvalidOperation,
+			fmt.Sprintf("method %s of %s has unsupported return values", name, s.val.Type()))
+	}
+	variadic := fnType.IsVariadic()
+	argTypes := make([]reflect.Type, fnType.NumIn())
+	for i := range argTypes {
+		argTypes[i] = fnType.In(i)
+	}
+	if err := checkArgTypes(argTypes, variadic); err != nil {
+		return Value{}, err
+	}
+
+	goVals, err := argsToGoValuesReflect(state, args, argTypes, variadic)
+	if err != nil {
+		return Value{}, err
+	}
+	reflectVals := make([]reflect.Value, len(goVals))
+	for i, goVal := range goVals {
+		reflectVals[i] = reflect.ValueOf(goVal)
+	}
+	var retVals []reflect.Value
+	if variadic {
+		retVals = method.CallSlice(reflectVals)
+	} else {
+		retVals = method.Call(reflectVals)
+	}
+	if len(retVals) == 2 && !retVals[1].IsNil() {
+		return Value{}, retVals[1].Interface().(error)
+	}
+	return valueFromGoValueHelper(retVals[0].Interface(), s.config, s.level+1), nil
+}
+
 type reflectSeqObject struct {
 	val    reflect.Value
 	config *valueFromGoValueConfig
@@ -81,6 +81,32 @@ func (s *State) lookup(name string) option.Option[Value] {
 	return s.ctx.load(s.env, name)
 }
 
+// Lookup looks up a variable by name in the current scope.
+//
+// Local variables are looked up first, then the template context and
+// finally the globals of the environment.  None is returned if the
+// variable is not defined.
+func (s *State) Lookup(name string) option.Option[Value] {
+	return s.lookup(name)
+}
+
+// KnownVariables returns the sorted names of all variables visible in the
+// current scope.
+//
+// This includes the template context, local variables like loop variables,
+// macro arguments or variables assigned with `set`, and the globals of the
+// environment.  Each name can be resolved with [State.Lookup].
+func (s *State) KnownVariables() []string {
+	ctxVars, localVars := s.ctx.visibleVariables()
+	names := make(map[string]struct{}, len(ctxVars)+len(localVars)+len(s.env.globals))
+	for _, vars := range []map[string]Value{ctxVars, localVars, s.env.globals} {
+		for name := range vars {
+			names[name] = struct{}{}
+		}
+	}
+	return mapSortedKeys(names)
+}
+
 // RenderBlock renders a block with the given name into a string.
 //
 // This method works like [Template.Render] but
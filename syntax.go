@@ -7,13 +7,23 @@ package mjingo
 // be shared, but the start markers need to be distinct.  It would
 // thus not be valid to configure `{{` to be the marker for both
 // variables and blocks.
+//
+// LineStatementPrefix and LineCommentPrefix are optional and disabled when
+// empty.  When LineStatementPrefix is set, a line starting with the prefix
+// (after optional whitespace) is treated as a block tag which ends at the
+// end of the line, so `# for item in items` works like
+// `{% for item in items %}`.  A trailing colon is ignored.  When
+// LineCommentPrefix is set, a line starting with the prefix (after optional
+// whitespace) is removed from the output.
 type Syntax struct {
-	BlockStart    string
-	BlockEnd      string
-	VariableStart string
-	VariableEnd   string
-	CommentStart  string
-	CommentEnd    string
+	BlockStart          string
+	BlockEnd            string
+	VariableStart       string
+	VariableEnd         string
+	CommentStart        string
+	CommentEnd          string
+	LineStatementPrefix string
+	LineCommentPrefix   string
 }
 
 // DefaultSyntax is the default delimiter configuration for the environment and the parser.
@@ -28,8 +38,31 @@ var DefaultSyntax = Syntax{
 
 type syntaxConfig struct {
 	Syntax Syntax
+
+	// customTags maps the names of custom statement tags to the names of
+	// their end tags.  An empty end tag name means the tag has no body.
+	customTags map[string]string
 }
 
 var defaultSyntaxConfig = syntaxConfig{
 	Syntax: DefaultSyntax,
 }
+
+func (s *Syntax) validate() error {
+	if s.BlockStart == "" || s.BlockEnd == "" || s.VariableStart == "" ||
+		s.VariableEnd == "" || s.CommentStart == "" || s.CommentEnd == "" {
+		return NewError(InvalidDelimiter, "delimiters must not be empty")
+	}
+	if s.BlockStart == s.VariableStart || s.BlockStart == s.CommentStart ||
+		s.VariableStart == s.CommentStart {
+		return NewError(InvalidDelimiter, "start delimiters must be distinct")
+	}
+	if s.LineStatementPrefix != "" && s.LineStatementPrefix == s.LineCommentPrefix {
+		return NewError(InvalidDelimiter, "line statement and line comment prefixes must be distinct")
+	}
+	return nil
+}
+
+func (s *Syntax) hasLinePrefixes() bool {
+	return s.LineStatementPrefix != "" || s.LineCommentPrefix != ""
+}
@@ -44,3 +44,29 @@ func TestSkipBasicTag(t *testing.T) {
 		}
 	}
 }
+
+func TestMatchLineMarker(t *testing.T) {
+	cfg := &syntaxConfig{Syntax: DefaultSyntax}
+	cfg.Syntax.LineStatementPrefix = "#"
+	cfg.Syntax.LineCommentPrefix = "##"
+	testCases := []struct {
+		input   string
+		marker  startMarker
+		skip    uint
+		matched bool
+	}{
+		{input: "# for x in y", marker: startMarkerLineStatement, skip: 1, matched: true},
+		{input: "  # if x", marker: startMarkerLineStatement, skip: 3, matched: true},
+		{input: "## comment\nfoo", marker: startMarkerLineComment, skip: 11, matched: true},
+		{input: "\t## comment", marker: startMarkerLineComment, skip: 11, matched: true},
+		{input: "foo # bar", matched: false},
+		{input: "", matched: false},
+	}
+	for _, tc := range testCases {
+		marker, skip, matched := matchLineMarker(tc.input, cfg)
+		if matched != tc.matched || (matched && (marker != tc.marker || skip != tc.skip)) {
+			t.Errorf("got marker=%d skip=%d matched=%t, want marker=%d skip=%d matched=%t for input=%q",
+				marker, skip, matched, tc.marker, tc.skip, tc.matched, tc.input)
+		}
+	}
+}
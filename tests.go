@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"unicode"
 
 	"github.com/hnakamur/mjingo/internal/rustfmt"
 )
@@ -571,6 +572,188 @@ func isSequence(val Value) bool { return val.Kind() == ValueKindSeq }
 // ```
 func isMapping(val Value) bool { return val.Kind() == ValueKindMap }
 
+// Checks if a value is divisible by another number.
+//
+// ```jinja
+// {{ 21 is divisibleby(7) }} -> true
+// {{ 22 is divisibleby(7) }} -> false
+// ```
+func isDivisibleBy(val, other Value) bool {
+	if val.Kind() != ValueKindNumber || other.Kind() != ValueKindNumber {
+		return false
+	}
+	rv, err := opRem(val, other)
+	return err == nil && !rv.isTrue()
+}
+
+// Checks if this value is an integer.
+//
+// ```jinja
+// {{ 42 is integer }} -> true
+// {{ 42.0 is integer }} -> false
+// ```
+func isInteger(val Value) bool {
+	switch val.data.(type) {
+	case i64Value, u64Value, i128Value, u128Value:
+		return true
+	}
+	return false
+}
+
+// Checks if this value is a float.
+//
+// ```jinja
+// {{ 42.0 is float }} -> true
+// {{ 42 is float }} -> false
+// ```
+func isFloat(val Value) bool {
+	_, ok := val.data.(f64Value)
+	return ok
+}
+
+// Checks if this value is a boolean.
+//
+// ```jinja
+// {{ true is boolean }} -> true
+// {{ 1 is boolean }} -> false
+// ```
+func isBoolean(val Value) bool {
+	_, ok := val.data.(boolValue)
+	return ok
+}
+
+// Checks if this value can be called like a function or a macro.
+//
+// ```jinja
+// {{ range is callable }} -> true
+// {{ 42 is callable }} -> false
+// ```
+func isCallable(val Value) bool {
+	if dyVal, ok := val.data.(dynamicValue); ok {
+		_, ok := dyVal.Dy.(Caller)
+		return ok
+	}
+	return false
+}
+
+// Checks if this value can be iterated over.
+//
+// ```jinja
+// {{ [1, 2, 3] is iterable }} -> true
+// {{ 42 is iterable }} -> false
+// ```
+func isIterable(val Value) bool {
+	_, err := val.tryIter()
+	return err == nil
+}
+
+// Checks if a string is all lowercase.
+//
+// Like Python's `str.islower` the string must contain at least one
+// cased character.
+//
+// ```jinja
+// {{ "foo" is lower }} -> true
+// {{ "Foo" is lower }} -> false
+// ```
+func isLower(s string) bool {
+	cased := false
+	for _, r := range s {
+		if unicode.IsUpper(r) || unicode.IsTitle(r) {
+			return false
+		}
+		cased = cased || unicode.IsLower(r)
+	}
+	return cased
+}
+
+// Checks if a string is all uppercase.
+//
+// Like Python's `str.isupper` the string must contain at least one
+// cased character.
+//
+// ```jinja
+// {{ "FOO" is upper }} -> true
+// {{ "Foo" is upper }} -> false
+// ```
+func isUpper(s string) bool {
+	cased := false
+	for _, r := range s {
+		if unicode.IsLower(r) || unicode.IsTitle(r) {
+			return false
+		}
+		cased = cased || unicode.IsUpper(r)
+	}
+	return cased
+}
+
+// Checks if a value is the same object as another value.
+//
+// Sequences, maps and objects are the same only if they are the identical
+// instance.  Objects which cannot be compared with == like functions are
+// compared by their pointers.  Other values are the same if they have the
+// same type and are equal.
+//
+// ```jinja
+// {% set a = [1] %}
+// {{ a is sameas(a) }} -> true
+// {{ a is sameas([1]) }} -> false
+// {{ none is sameas(none) }} -> true
+// ```
+func isSameAs(val, other Value) bool {
+	switch v := val.data.(type) {
+	case seqValue:
+		o, ok := other.data.(seqValue)
+		return ok && len(v.Items) == len(o.Items) &&
+			(len(v.Items) == 0 || &v.Items[0] == &o.Items[0])
+	case mapValue:
+		o, ok := other.data.(mapValue)
+		return ok && v.Map == o.Map
+	case dynamicValue:
+		o, ok := other.data.(dynamicValue)
+		if !ok {
+			return false
+		}
+		if reflect.TypeOf(v.Dy) != reflect.TypeOf(o.Dy) {
+			return false
+		}
+		return reflectIdentical(reflect.ValueOf(v.Dy), reflect.ValueOf(o.Dy))
+	}
+	return reflect.TypeOf(val.data) == reflect.TypeOf(other.data) && valueEqual(val, other)
+}
+
+// reflectIdentical reports whether a and b of the same type are identical.
+// Unlike ==, it also supports types which are not comparable by comparing
+// the pointers of funcs, maps and slices.
+func reflectIdentical(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Func, reflect.Map, reflect.Chan, reflect.Pointer, reflect.UnsafePointer:
+		return a.Pointer() == b.Pointer()
+	case reflect.Slice:
+		return a.Pointer() == b.Pointer() && a.Len() == b.Len()
+	case reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		return a.Elem().Type() == b.Elem().Type() && reflectIdentical(a.Elem(), b.Elem())
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if !reflectIdentical(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Array:
+		for i := 0; i < a.Len(); i++ {
+			if !reflectIdentical(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	}
+	return a.Equal(b)
+}
+
 // Checks if the value is starting with a string.
 //
 // ```jinja
@@ -587,6 +770,30 @@ func isStartingWith(v, other string) bool { return strings.HasPrefix(v, other) }
 // ```
 func isEndingWith(v, other string) bool { return strings.HasSuffix(v, other) }
 
+// Checks if the value matches a regular expression.
+//
+// The pattern uses the syntax of Go's `regexp` package and matches
+// anywhere in the string unless it is anchored with `^` or `$`.  The
+// `ignorecase` and `multiline` arguments enable the corresponding flags.
+// This test is also available as `regex`.
+//
+// ```jinja
+// {{ "PROJ-123" is matching("^[A-Z]+-\\d+$") }} -> true
+// {{ "proj-123" is matching("^[A-Z]+-\\d+$") }} -> false
+// {{ "proj-123" is matching("^[A-Z]+-\\d+$", ignorecase=true) }} -> true
+// ```
+func isMatching(state *State, v, pattern string, args ...Value) (bool, error) {
+	bound, err := bindArgs(args, "ignorecase", "multiline")
+	if err != nil {
+		return false, err
+	}
+	re, err := compileRegex(state, pattern, bound[0], bound[1])
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(v), nil
+}
+
 func isEq(val, other Value) bool { return valueEqual(val, other) }
 func isNe(val, other Value) bool { return !valueEqual(val, other) }
 func isLt(val, other Value) bool { return valueCmp(val, other) < 0 }
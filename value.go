@@ -61,7 +61,7 @@ func (v Value) getAttrFast(key string) option.Option[Value] {
 func (v Value) getItemOpt(key Value) option.Option[Value] {
 	return v.data.getItemOpt(key)
 }
-func (v Value) tryToI128() (*I128, error)       { return v.data.tryToI128() }
+func (v Value) tryToI128() (I128, error)        { return v.data.tryToI128() }
 func (v Value) tryToI64() (int64, error)        { return v.data.tryToI64() }
 func (v Value) tryToUint() (uint, error)        { return v.data.tryToUint() }
 func (v Value) asF64() option.Option[float64]   { return v.data.asF64() }
@@ -77,6 +77,95 @@ func (v Value) Equal(other any) bool {
 	return false
 }
 
+// IsUndefined returns true if the value is undefined.
+func (v Value) IsUndefined() bool { return v.isUndefined() }
+
+// IsTrue returns true if the value is considered true in a boolean
+// context, like in an `if` block.
+func (v Value) IsTrue() bool { return v.isTrue() }
+
+// AsString returns the string if the value is a string.
+//
+// Safe strings are returned as well.  Other values are not converted.
+func (v Value) AsString() option.Option[string] {
+	if s, ok := v.data.(stringValue); ok {
+		return option.Some(s.Str)
+	}
+	return option.None[string]()
+}
+
+// AsInt64 returns the value as an int64 if it is a number which can be
+// represented as an int64 without loss, or a bool.
+func (v Value) AsInt64() option.Option[int64] {
+	if v.Kind() != ValueKindNumber && v.Kind() != ValueKindBool {
+		return option.None[int64]()
+	}
+	n, err := v.tryToI64()
+	if err != nil {
+		return option.None[int64]()
+	}
+	return option.Some(n)
+}
+
+// AsFloat64 returns the value as a float64 if it is a number or a bool.
+func (v Value) AsFloat64() option.Option[float64] { return v.asF64() }
+
+// Len returns the length of a string, a sequence or a map.
+//
+// The length of a string is the number of characters.
+func (v Value) Len() option.Option[uint] { return v.len() }
+
+// Iter returns an iterator over the value.
+//
+// Sequences are iterated over their items, maps over their keys and
+// strings over their characters.  Undefined and none values yield an empty
+// iterator.
+func (v Value) Iter() (ValueIterator, error) {
+	iter, err := v.tryIter()
+	if err != nil {
+		return ValueIterator{}, err
+	}
+	return ValueIterator{iter: iter}, nil
+}
+
+// GetAttr looks up an attribute by name.
+//
+// This works on maps and struct objects.  Undefined is returned if the
+// attribute does not exist and an error is returned if the value itself is
+// undefined.
+func (v Value) GetAttr(name string) (Value, error) { return getAttr(v, name) }
+
+// GetItem looks up an item by key.
+//
+// This works on maps, sequences, strings and objects.  Sequences and
+// strings accept negative indexes.  Undefined is returned if the item does
+// not exist and an error is returned if the value itself is undefined.
+func (v Value) GetItem(key Value) (Value, error) { return getItem(v, key) }
+
+// ValueIterator iterates over the items of a [Value].
+//
+// It is created with [Value.Iter].
+type ValueIterator struct {
+	iter iterator
+}
+
+// Next returns the next item or None if the iterator is exhausted.
+func (i *ValueIterator) Next() option.Option[Value] {
+	if i.iter.iterState == nil {
+		return option.None[Value]()
+	}
+	return i.iter.Next()
+}
+
+// Len returns the number of remaining items.  It is 0 if the number is
+// not known like for an iterable object.
+func (i *ValueIterator) Len() uint { return i.iter.Len() }
+
+// Err returns the error which ended the iteration early.  This happens
+// when an item is an invalid value, for example when a lazily mapped
+// iterable fails.
+func (i *ValueIterator) Err() error { return i.iter.Err() }
+
 type valueData interface {
 	fmt.Stringer
 	debugString() string
@@ -89,7 +178,7 @@ type valueData interface {
 	isTrue() bool
 	getAttrFast(key string) option.Option[Value]
 	getItemOpt(key Value) option.Option[Value]
-	tryToI128() (*I128, error)
+	tryToI128() (I128, error)
 	tryToI64() (int64, error)
 	tryToUint() (uint, error)
 	asF64() option.Option[float64]
@@ -357,8 +446,13 @@ func (v dynamicValue) String() string {
 		b.WriteString("]")
 		return b.String()
 	case ObjectKindStruct:
-		if m, ok := v.Dy.(*macro); ok {
-			return m.String()
+		switch o := v.Dy.(type) {
+		case *macro:
+			return o.String()
+		case dateTimeObject:
+			return o.String()
+		case durationObject:
+			return o.String()
 		}
 		obj := v.Dy.(StructObject)
 		fields := staticOrDynamicFields(obj)
@@ -374,11 +468,36 @@ func (v dynamicValue) String() string {
 		}
 		b.WriteString("}")
 		return b.String()
+	case ObjectKindMap:
+		if s, ok := v.Dy.(fmt.Stringer); ok {
+			return s.String()
+		}
+		return valueFromIndexMap(valueMapFromMapObject(v.Dy.(MapObject))).String()
+	case ObjectKindIterable:
+		items, _ := collectIterableObject(v.Dy.(IterableObject))
+		return valueFromSlice(items).String()
 	default:
 		panic("unreachable")
 	}
 }
 
+// formatDynamicValue formats a map object like a map value and an iterable
+// object like a sequence unless it implements its own formatting.
+func formatDynamicValue(f fmt.State, verb rune, obj Object) {
+	if _, ok := obj.(rustfmt.Formatter); !ok {
+		switch obj.Kind() {
+		case ObjectKindMap:
+			rustfmt.NewDebugMap(*valueMapFromMapObject(obj.(MapObject))).Format(f, rustfmt.DebugVerb)
+			return
+		case ObjectKindIterable:
+			items, _ := collectIterableObject(obj.(IterableObject))
+			rustfmt.NewDebugList(slicex.Map(items, func(v Value) any { return v })).Format(f, rustfmt.DebugVerb)
+			return
+		}
+	}
+	rustfmt.FormatValue(f, verb, obj, "%v")
+}
+
 func (v undefinedValue) debugString() string { return "undefined" }
 func (v boolValue) debugString() string      { return strconv.FormatBool(v.B) }
 func (v u64Value) debugString() string       { return strconv.FormatUint(v.N, 10) }
@@ -474,9 +593,9 @@ func (v dynamicValue) kind() ValueKind {
 	case ObjectKindPlain:
 		// XXX: basic objects should probably not report as map
 		return ValueKindMap
-	case ObjectKindSeq:
+	case ObjectKindSeq, ObjectKindIterable:
 		return ValueKindSeq
-	case ObjectKindStruct:
+	case ObjectKindStruct, ObjectKindMap:
 		return ValueKindMap
 	default:
 		panic("unreachable")
@@ -555,6 +674,10 @@ func (v dynamicValue) isTrue() bool {
 		return v.Dy.(SeqObject).ItemCount() != 0
 	case ObjectKindStruct:
 		return fieldCount(v.Dy.(StructObject)) != 0
+	case ObjectKindMap:
+		return v.Dy.(MapObject).Len() != 0
+	case ObjectKindIterable:
+		return true
 	default:
 		panic("unreachable")
 	}
@@ -579,6 +702,9 @@ func (v mapValue) getAttrFast(key string) option.Option[Value] {
 	return option.None[Value]()
 }
 func (v dynamicValue) getAttrFast(key string) option.Option[Value] {
+	if v.Dy.Kind() == ObjectKindMap {
+		return v.Dy.(MapObject).GetValue(valueFromString(key))
+	}
 	if s, ok := v.Dy.(StructObject); ok {
 		return s.GetField(key)
 	}
@@ -611,7 +737,19 @@ func (v stringValue) getItemOpt(key Value) option.Option[Value] {
 	}
 	return option.Some(valueFromString(string(chars[idx])))
 }
-func (bytesValue) getItemOpt(_ Value) option.Option[Value] { return option.None[Value]() }
+func (v bytesValue) getItemOpt(key Value) option.Option[Value] {
+	idx, err := key.tryToI64()
+	if err != nil {
+		return option.None[Value]()
+	}
+	if idx < 0 {
+		idx += int64(len(v.B))
+	}
+	if idx < 0 || idx >= int64(len(v.B)) {
+		return option.None[Value]()
+	}
+	return option.Some(valueFromU64(uint64(v.B[idx])))
+}
 func (v seqValue) getItemOpt(key Value) option.Option[Value] {
 	return getItemOptFromSeq(newSliceSeqObject(v.Items), key)
 }
@@ -632,6 +770,10 @@ func (v dynamicValue) getItemOpt(key Value) option.Option[Value] {
 			return v.Dy.(StructObject).GetField(strKey)
 		}
 		return option.None[Value]()
+	case ObjectKindMap:
+		return v.Dy.(MapObject).GetValue(key)
+	case ObjectKindIterable:
+		return option.None[Value]()
 	default:
 		panic("unreachable")
 	}
@@ -659,51 +801,62 @@ func getItemOptFromSeq(seq SeqObject, key Value) option.Option[Value] {
 	return option.None[Value]()
 }
 
-func (v undefinedValue) tryToI128() (*I128, error) {
-	return nil, unsupportedConversion(v.typ(), "i128")
+func (v undefinedValue) tryToI128() (I128, error) {
+	return I128{}, unsupportedConversion(v.typ(), "i128")
 }
-func (v boolValue) tryToI128() (*I128, error) {
-	var n int64
+func (v boolValue) tryToI128() (I128, error) {
+	var rv I128
 	if v.B {
-		n = 1
+		rv.SetInt64(1)
 	}
-	return I128FromInt64(n), nil
+	return rv, nil
+}
+func (v u64Value) tryToI128() (I128, error) {
+	var rv I128
+	rv.SetUint64(v.N)
+	return rv, nil
 }
-func (v u64Value) tryToI128() (*I128, error) { return I128FromUint64(v.N), nil }
-func (v i64Value) tryToI128() (*I128, error) { return I128FromInt64(v.N), nil }
-func (v f64Value) tryToI128() (*I128, error) {
+func (v i64Value) tryToI128() (I128, error) {
+	var rv I128
+	rv.SetInt64(v.N)
+	return rv, nil
+}
+func (v f64Value) tryToI128() (I128, error) {
 	if float64(int64(v.F)) == v.F {
-		return I128FromInt64(int64(v.F)), nil
+		var rv I128
+		rv.SetInt64(int64(v.F))
+		return rv, nil
 	}
-	return nil, unsupportedConversion(v.typ(), "i128")
+	return I128{}, unsupportedConversion(v.typ(), "i128")
 }
-func (v noneValue) tryToI128() (*I128, error) {
-	return nil, unsupportedConversion(v.typ(), "i128")
+func (v noneValue) tryToI128() (I128, error) {
+	return I128{}, unsupportedConversion(v.typ(), "i128")
 }
-func (v invalidValue) tryToI128() (*I128, error) {
-	return nil, unsupportedConversion(v.typ(), "i128")
+func (v invalidValue) tryToI128() (I128, error) {
+	return I128{}, unsupportedConversion(v.typ(), "i128")
 }
-func (v u128Value) tryToI128() (*I128, error) {
-	if v.N.n.Cmp(i128Max) > 0 {
-		return nil, unsupportedConversion(v.typ(), "i128")
+func (v u128Value) tryToI128() (I128, error) {
+	rv, ok := i128FromMagnitude(false, v.N)
+	if !ok {
+		return I128{}, unsupportedConversion(v.typ(), "i128")
 	}
-	return I128TryFromBigInt(&v.N.n)
+	return rv, nil
 }
-func (v i128Value) tryToI128() (*I128, error) { return I128TryFromBigInt(&v.N.n) }
-func (v stringValue) tryToI128() (*I128, error) {
-	return nil, unsupportedConversion(v.typ(), "i128")
+func (v i128Value) tryToI128() (I128, error) { return v.N, nil }
+func (v stringValue) tryToI128() (I128, error) {
+	return I128{}, unsupportedConversion(v.typ(), "i128")
 }
-func (v bytesValue) tryToI128() (*I128, error) {
-	return nil, unsupportedConversion(v.typ(), "i128")
+func (v bytesValue) tryToI128() (I128, error) {
+	return I128{}, unsupportedConversion(v.typ(), "i128")
 }
-func (v seqValue) tryToI128() (*I128, error) {
-	return nil, unsupportedConversion(v.typ(), "i128")
+func (v seqValue) tryToI128() (I128, error) {
+	return I128{}, unsupportedConversion(v.typ(), "i128")
 }
-func (v mapValue) tryToI128() (*I128, error) {
-	return nil, unsupportedConversion(v.typ(), "i128")
+func (v mapValue) tryToI128() (I128, error) {
+	return I128{}, unsupportedConversion(v.typ(), "i128")
 }
-func (v dynamicValue) tryToI128() (*I128, error) {
-	return nil, unsupportedConversion(v.typ(), "i128")
+func (v dynamicValue) tryToI128() (I128, error) {
+	return I128{}, unsupportedConversion(v.typ(), "i128")
 }
 
 func (v undefinedValue) tryToI64() (int64, error) { return 0, unsupportedConversion(v.typ(), "i64") }
@@ -814,14 +967,8 @@ func (v i64Value) asF64() option.Option[float64]   { return option.Some(float64(
 func (v f64Value) asF64() option.Option[float64]   { return option.Some(v.F) }
 func (noneValue) asF64() option.Option[float64]    { return option.None[float64]() }
 func (invalidValue) asF64() option.Option[float64] { return option.None[float64]() }
-func (v u128Value) asF64() option.Option[float64] {
-	f, _ := v.N.n.Float64()
-	return option.Some(f)
-}
-func (v i128Value) asF64() option.Option[float64] {
-	f, _ := v.N.n.Float64()
-	return option.Some(f)
-}
+func (v u128Value) asF64() option.Option[float64]  { return option.Some(v.N.Float64()) }
+func (v i128Value) asF64() option.Option[float64]  { return option.Some(v.N.Float64()) }
 func (stringValue) asF64() option.Option[float64]  { return option.None[float64]() }
 func (bytesValue) asF64() option.Option[float64]   { return option.None[float64]() }
 func (seqValue) asF64() option.Option[float64]     { return option.None[float64]() }
@@ -920,7 +1067,7 @@ func (v stringValue) tryIter() (iterator, error) {
 	return iterator{iterState: &charsValueIteratorState{s: v.Str}, len: uint(utf8.RuneCountInString(v.Str))}, nil
 }
 func (v bytesValue) tryIter() (iterator, error) {
-	return iterator{}, NewError(InvalidOperation, fmt.Sprintf("%s is not iterable", v.kind()))
+	return iterator{iterState: &bytesValueIteratorState{b: v.B}, len: uint(len(v.B))}, nil
 }
 func (v seqValue) tryIter() (iterator, error) {
 	return iterator{iterState: &seqValueIteratorState{items: v.Items}, len: uint(len(v.Items))}, nil
@@ -939,6 +1086,11 @@ func (v dynamicValue) tryIter() (iterator, error) {
 		obj := v.Dy.(StructObject)
 		fields := staticOrDynamicFields(obj)
 		return iterator{iterState: &stringsValueIteratorState{items: fields}}, nil
+	case ObjectKindMap:
+		keys := v.Dy.(MapObject).Keys()
+		return iterator{iterState: &seqValueIteratorState{items: keys}, len: uint(len(keys))}, nil
+	case ObjectKindIterable:
+		return iterator{iterState: &dynIterableValueIteratorState{next: v.Dy.(IterableObject).Iterate()}}, nil
 	default:
 		panic("unreachable")
 	}
@@ -1016,25 +1168,18 @@ func valueTryToGoUint64(val Value) (uint64, error) {
 }
 
 func valueTryToGoI128(val Value) (I128, error) {
-	i, err := val.tryToI128()
-	if err != nil {
-		return I128{}, err
-	}
-	return *i, nil
+	return val.tryToI128()
 }
 
 func valueTryToGoU128(val Value) (U128, error) {
 	if u, ok := val.data.(u128Value); ok {
-		u2, _ := U128TryFromBigInt(&u.N.n)
-		return *u2, nil
+		return u.N, nil
 	}
 	n, err := val.tryToI128()
-	var zero I128
-	if err != nil || n.Cmp(&zero) < 0 {
+	if err != nil || n.isNeg() {
 		return U128{}, unsupportedConversion(val.typ(), "uint64")
 	}
-	u, _ := U128TryFromBigInt(&n.n)
-	return *u, nil
+	return n.bits(), nil
 }
 
 func valueTryToGoFloat32(val Value) (float32, error) {
@@ -1056,6 +1201,7 @@ func valueTryToGoFloat64(val Value) (float64, error) {
 type iterator struct {
 	iterState valueIteratorState
 	len       uint
+	err       error
 }
 
 func iteratorFromSeqObject(s SeqObject) *iterator {
@@ -1084,14 +1230,33 @@ func (i iterator) Cloned() iterator {
 	}
 }
 
+// Next returns the next item.  The iteration ends when an item is an
+// invalid value and the error is kept for Err.
 func (i *iterator) Next() option.Option[Value] {
+	if i.err != nil {
+		return option.None[Value]()
+	}
 	optVal := i.iterState.advanceState()
-	if optVal.IsSome() {
-		i.len--
+	if item := (Value{}); optVal.UnwrapTo(&item) {
+		if err := invalidValueErr(item); err != nil {
+			i.err = err
+			i.len = 0
+			return option.None[Value]()
+		}
+		if i.len > 0 {
+			i.len--
+		}
 	}
 	return optVal
 }
 
+// Err returns the error which ended the iteration early.
+func (i *iterator) Err() error {
+	return i.err
+}
+
+// Len returns the number of remaining items.  It is 0 if the number is
+// not known like for an iterable object.
 func (i *iterator) Len() uint {
 	return i.len
 }
@@ -1162,12 +1327,15 @@ func (i *iterator) maxBy(compare func(a, b Value) int) option.Option[Value] {
 	return rv
 }
 
-func (i *iterator) Collect() []Value {
+func (i *iterator) Collect() ([]Value, error) {
 	items := make([]Value, 0, i.Len())
 	for item := (Value{}); i.Next().UnwrapTo(&item); {
 		items = append(items, item)
 	}
-	return items
+	if i.err != nil {
+		return nil, i.err
+	}
+	return items, nil
 }
 
 type valueIteratorState interface {
@@ -1179,6 +1347,10 @@ type charsValueIteratorState struct {
 	offset uint
 	s      string
 }
+type bytesValueIteratorState struct {
+	idx uint
+	b   []byte
+}
 type seqValueIteratorState struct {
 	idx   uint
 	items []Value
@@ -1191,6 +1363,9 @@ type dynSeqValueIteratorState struct {
 	idx uint
 	obj SeqObject
 }
+type dynIterableValueIteratorState struct {
+	next func() option.Option[Value]
+}
 type mapValueIteratorState struct {
 	idx  uint
 	keys []keyRef
@@ -1230,6 +1405,14 @@ func (s *charsValueIteratorState) advanceState() option.Option[Value] {
 	}
 	return option.None[Value]()
 }
+func (s *bytesValueIteratorState) advanceState() option.Option[Value] {
+	if s.idx < uint(len(s.b)) {
+		b := s.b[s.idx]
+		s.idx++
+		return option.Some(valueFromU64(uint64(b)))
+	}
+	return option.None[Value]()
+}
 func (s *seqValueIteratorState) advanceState() option.Option[Value] {
 	if s.idx < uint(len(s.items)) {
 		item := s.items[s.idx]
@@ -1246,6 +1429,9 @@ func (s *stringsValueIteratorState) advanceState() option.Option[Value] {
 	}
 	return option.None[Value]()
 }
+func (s *dynIterableValueIteratorState) advanceState() option.Option[Value] {
+	return s.next()
+}
 func (s *dynSeqValueIteratorState) advanceState() option.Option[Value] {
 	val := s.obj.GetItem(s.idx)
 	s.idx++
@@ -1296,9 +1482,9 @@ func (i128Value) len() option.Option[uint]      { return option.None[uint]() }
 func (v stringValue) len() option.Option[uint] {
 	return option.Some(uint(utf8.RuneCountInString(v.Str)))
 }
-func (bytesValue) len() option.Option[uint] { return option.None[uint]() }
-func (v seqValue) len() option.Option[uint] { return option.Some(uint(len(v.Items))) }
-func (v mapValue) len() option.Option[uint] { return option.Some(v.Map.Len()) }
+func (v bytesValue) len() option.Option[uint] { return option.Some(uint(len(v.B))) }
+func (v seqValue) len() option.Option[uint]   { return option.Some(uint(len(v.Items))) }
+func (v mapValue) len() option.Option[uint]   { return option.Some(v.Map.Len()) }
 func (v dynamicValue) len() option.Option[uint] {
 	switch v.Dy.Kind() {
 	case ObjectKindPlain:
@@ -1307,6 +1493,10 @@ func (v dynamicValue) len() option.Option[uint] {
 		return option.Some(v.Dy.(SeqObject).ItemCount())
 	case ObjectKindStruct:
 		return option.Some(fieldCount(v.Dy.(StructObject)))
+	case ObjectKindMap:
+		return option.Some(v.Dy.(MapObject).Len())
+	case ObjectKindIterable:
+		return option.None[uint]()
 	default:
 		panic("unreachable")
 	}
@@ -1329,13 +1519,13 @@ func valueDataEqual(v, other valueData) bool {
 		b := other.(bytesValue).B
 		return bytes.Equal(a, b)
 	default:
-		switch c := coerceData(v, other).(type) {
-		case f64CoerceResult:
-			return c.lhs == c.rhs
-		case i128CoerceResult:
-			return c.lhs.Cmp(&c.rhs) == 0
-		case strCoerceResult:
-			return c.lhs == c.rhs
+		switch c := coerceData(v, other); c.typ {
+		case coerceResultTypeF64:
+			return c.f64.lhs == c.f64.rhs
+		case coerceResultTypeI128:
+			return c.i128.lhs.Cmp(&c.i128.rhs) == 0
+		case coerceResultTypeStr:
+			return c.str.lhs == c.str.rhs
 		default:
 			if optA, optB := v.asSeq(), other.asSeq(); optA.IsSome() && optB.IsSome() {
 				iterA, err := v.tryIter()
@@ -1402,13 +1592,13 @@ outer:
 		b := other.data.(bytesValue).B
 		rv = bytes.Compare(a, b)
 	default:
-		switch c := coerce(v, other).(type) {
-		case f64CoerceResult:
-			rv = f64TotalCmp(c.lhs, c.rhs)
-		case i128CoerceResult:
-			rv = c.lhs.Cmp(&c.rhs)
-		case strCoerceResult:
-			rv = strings.Compare(c.lhs, c.rhs)
+		switch c := coerce(v, other); c.typ {
+		case coerceResultTypeF64:
+			rv = f64TotalCmp(c.f64.lhs, c.f64.rhs)
+		case coerceResultTypeI128:
+			rv = c.i128.lhs.Cmp(&c.i128.rhs)
+		case coerceResultTypeStr:
+			rv = strings.Compare(c.str.lhs, c.str.rhs)
 		default:
 			if optA, optB := v.asSeq(), other.asSeq(); optA.IsSome() && optB.IsSome() {
 				iterA, err := v.tryIter()
@@ -1484,10 +1674,8 @@ func getAttr(val Value, key string) (Value, error) {
 			return v2.clone(), nil
 		}
 	case dynamicValue:
-		if obj, ok := v.Dy.(StructObject); ok {
-			if optField := obj.GetField(key); optField.IsSome() {
-				return optField.Unwrap(), nil
-			}
+		if optField := v.getAttrFast(key); optField.IsSome() {
+			return optField.Unwrap(), nil
 		}
 	}
 	return Undefined, nil
@@ -1579,6 +1767,17 @@ func valueDataHash(val valueData, h hash.Hash) {
 				io.WriteString(h, field)
 				structObj.GetField(field).Hash(h, valueHash)
 			}
+		case ObjectKindMap:
+			mapObj := v.Dy.(MapObject)
+			for _, key := range mapObj.Keys() {
+				valueDataHash(key.data, h)
+				mapObj.GetValue(key).Hash(h, valueHash)
+			}
+		case ObjectKindIterable:
+			items, _ := collectIterableObject(v.Dy.(IterableObject))
+			for _, item := range items {
+				valueDataHash(item.data, h)
+			}
 		}
 	case u64Value, i64Value, f64Value, u128Value, i128Value:
 		n, err := val.clone().tryToI64()
@@ -1659,7 +1858,7 @@ func (v Value) Format(f fmt.State, verb rune) {
 		case mapValue:
 			rustfmt.NewDebugMap(*d.Map).Format(f, rustfmt.DebugVerb)
 		case dynamicValue:
-			rustfmt.FormatValue(f, verb, d.Dy, "%v")
+			formatDynamicValue(f, verb, d.Dy)
 		default:
 			panic("not implemented yet")
 			// fmt.Fprintf(f, fmt.FormatString(f, verb), v.data)
@@ -1693,7 +1892,7 @@ func (v Value) Format(f fmt.State, verb rune) {
 		case mapValue:
 			rustfmt.NewDebugMap(*d.Map).Format(f, verb)
 		case dynamicValue:
-			rustfmt.FormatValue(f, verb, d.Dy, "%v")
+			formatDynamicValue(f, verb, d.Dy)
 		default:
 			fmt.Fprintf(f, fmt.FormatString(f, verb), d)
 		}
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/hnakamur/mjingo/internal/rustfmt"
+	"github.com/hnakamur/mjingo/option"
 )
 
 func TestValueString(t *testing.T) {
@@ -106,3 +107,121 @@ func TestValueCmp(t *testing.T) {
 		t.Errorf("result mismatch, got=%v, want=%v", got, want)
 	}
 }
+
+func TestValue_PublicAPI(t *testing.T) {
+	t.Run("accessors", func(t *testing.T) {
+		if got, want := ValueFromString("foo").AsString(), option.Some("foo"); got != want {
+			t.Errorf("AsString mismatch, got=%v, want=%v", got, want)
+		}
+		if got := ValueFromInt(1).AsString(); got.IsSome() {
+			t.Errorf("AsString of int should be none, got=%v", got)
+		}
+		if got, want := ValueFromInt(-3).AsInt64(), option.Some(int64(-3)); got != want {
+			t.Errorf("AsInt64 mismatch, got=%v, want=%v", got, want)
+		}
+		if got, want := ValueFromFloat(2).AsInt64(), option.Some(int64(2)); got != want {
+			t.Errorf("AsInt64 of whole float mismatch, got=%v, want=%v", got, want)
+		}
+		if got := ValueFromFloat(2.5).AsInt64(); got.IsSome() {
+			t.Errorf("AsInt64 of fractional float should be none, got=%v", got)
+		}
+		if got := ValueFromString("1").AsInt64(); got.IsSome() {
+			t.Errorf("AsInt64 of string should be none, got=%v", got)
+		}
+		if got, want := ValueFromInt(3).AsFloat64(), option.Some(3.0); got != want {
+			t.Errorf("AsFloat64 mismatch, got=%v, want=%v", got, want)
+		}
+		if got, want := ValueFromString("äb").Len(), option.Some(uint(2)); got != want {
+			t.Errorf("Len mismatch, got=%v, want=%v", got, want)
+		}
+		if got := ValueFromBool(true).Len(); got.IsSome() {
+			t.Errorf("Len of bool should be none, got=%v", got)
+		}
+		if !ValueFromBool(true).IsTrue() || ValueFromSlice(nil).IsTrue() {
+			t.Error("IsTrue mismatch")
+		}
+		if !Undefined.IsUndefined() || ValueFromBool(false).IsUndefined() {
+			t.Error("IsUndefined mismatch")
+		}
+		if got, want := ValueFromBytes([]byte("ab")).Kind(), ValueKindBytes; got != want {
+			t.Errorf("kind mismatch, got=%v, want=%v", got, want)
+		}
+	})
+	t.Run("map", func(t *testing.T) {
+		m := ValueFromMap(map[string]Value{
+			"b": ValueFromInt(2),
+			"a": ValueFromString("x"),
+		})
+		if got, want := m.String(), `{"a": "x", "b": 2}`; got != want {
+			t.Errorf("String mismatch, got=%s, want=%s", got, want)
+		}
+		got, err := m.GetAttr("b")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := ValueFromInt(2); !got.Equal(want) {
+			t.Errorf("GetAttr mismatch, got=%v, want=%v", got, want)
+		}
+		if got, err := m.GetAttr("missing"); err != nil || !got.IsUndefined() {
+			t.Errorf("GetAttr of missing key mismatch, got=%v, err=%v", got, err)
+		}
+		got, err = m.GetItem(ValueFromString("a"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := ValueFromString("x"); !got.Equal(want) {
+			t.Errorf("GetItem mismatch, got=%v, want=%v", got, want)
+		}
+		if _, err := Undefined.GetAttr("a"); err == nil {
+			t.Error("GetAttr of undefined should fail")
+		}
+	})
+	t.Run("iter", func(t *testing.T) {
+		seq := ValueFromSlice([]Value{ValueFromInt(1), ValueFromInt(2), ValueFromInt(3)})
+		got, err := seq.GetItem(ValueFromInt(-1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := ValueFromInt(3); !got.Equal(want) {
+			t.Errorf("GetItem mismatch, got=%v, want=%v", got, want)
+		}
+		iter, err := seq.Iter()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := iter.Len(), uint(3); got != want {
+			t.Errorf("Len mismatch, got=%d, want=%d", got, want)
+		}
+		var sum int64
+		for item := (Value{}); iter.Next().UnwrapTo(&item); {
+			sum += item.AsInt64().Unwrap()
+		}
+		if got, want := sum, int64(6); got != want {
+			t.Errorf("sum mismatch, got=%d, want=%d", got, want)
+		}
+		if err := iter.Err(); err != nil {
+			t.Errorf("Err should be nil, got=%v", err)
+		}
+		invalid := mapErrToInvalidValue(Value{}, NewError(InvalidOperation, "boom"))
+		iter, err = ValueFromSlice([]Value{ValueFromInt(1), invalid, ValueFromInt(3)}).Iter()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := iter.Next(); !got.IsSome() || !got.Unwrap().Equal(ValueFromInt(1)) {
+			t.Errorf("first item mismatch, got=%v", got)
+		}
+		if iter.Next().IsSome() {
+			t.Error("iteration should end at an invalid value")
+		}
+		if err := iter.Err(); err == nil {
+			t.Error("Err should be set after an invalid value")
+		}
+		if _, err := ValueFromInt(1).Iter(); err == nil {
+			t.Error("Iter of int should fail")
+		}
+		var zero ValueIterator
+		if zero.Next().IsSome() {
+			t.Error("zero iterator should be exhausted")
+		}
+	})
+}
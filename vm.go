@@ -392,6 +392,9 @@ loop:
 					stack.Push(v)
 				}
 			} else {
+				if err := l.iterator.Err(); err != nil {
+					return option.None[Value](), processErr(err, pc, state)
+				}
 				pc = inst.JumpTarget
 				// fmt.Printf("Iterate pc=%d\n", pc)
 				continue
@@ -468,12 +471,15 @@ loop:
 				return option.None[Value](), processErr(err, pc, state)
 			}
 			args := stack.SliceTop(inst.ArgCount)
-			if rv, err := tf(state, args); err != nil {
+			rv, err := tf(state, args)
+			if err != nil {
 				return option.None[Value](), processErr(err, pc, state)
-			} else {
-				stack.DropTop(inst.ArgCount)
-				stack.Push(rv)
 			}
+			if rv, err = assertValid(rv, pc, state); err != nil {
+				return option.None[Value](), err
+			}
+			stack.DropTop(inst.ArgCount)
+			stack.Push(rv)
 		case performTestInstruction:
 			f := func() option.Option[BoxedTest] { return state.env.getTest(inst.Name) }
 			var tf BoxedTest
@@ -533,7 +539,13 @@ loop:
 			stack.DropTop(inst.ArgCount)
 			stack.Push(a)
 		case callObjectInstruction:
-			panic("not implemented for CallObjectInstruction")
+			args := stack.SliceTop(inst.ArgCount)
+			a, err := valueCall(args[0], state, args[1:])
+			if err != nil {
+				return option.None[Value](), processErr(err, pc, state)
+			}
+			stack.DropTop(inst.ArgCount)
+			stack.Push(a)
 		case dupTopInstruction:
 			if val, ok := stack.Peek(); ok {
 				stack.Push(val.clone())
@@ -608,6 +620,36 @@ loop:
 		case getClosureInstruction:
 			closure := state.ctx.closure()
 			stack.Push(ValueFromObject(&closure))
+		case callCustomTagInstruction:
+			var f CustomTagFunc
+			if !state.env.getCustomTag(inst.Name).UnwrapTo(&f) {
+				err := NewError(InvalidOperation, fmt.Sprintf("tag %s is unknown", inst.Name))
+				return option.None[Value](), processErr(err, pc, state)
+			}
+			args := slices.Clone(stack.SliceTop(inst.ArgCount))
+			stack.DropTop(inst.ArgCount)
+			bodyPC := pc + 1
+			body := func() (Value, error) {
+				var bodyStack stackpkg.Stack[Value]
+				out.beginCapture(captureModeCapture)
+				_, err := m.evalImpl(state, out, &bodyStack, bodyPC)
+				captured := out.endCapture(state.autoEscape)
+				if err != nil {
+					return Value{}, err
+				}
+				return captured, nil
+			}
+			rv, err := f(state, args, body)
+			if err != nil {
+				return option.None[Value](), processErr(err, pc, state)
+			}
+			if rv.data != nil && !rv.isUndefined() {
+				if err := m.env.format(rv, state, out); err != nil {
+					return option.None[Value](), processErr(err, pc, state)
+				}
+			}
+			pc = inst.JumpTarget
+			continue
 		default:
 			panic("unreachable")
 		}
@@ -808,7 +850,10 @@ func (m *virtualMachine) pushLoop(state *State, iterable Value,
 	if err != nil {
 		return err
 	}
-	l := it.Len()
+	l := option.Some(it.Len())
+	if _, ok := valueAsIterableObject(iterable); ok {
+		l = option.None[uint]()
+	}
 	depth := uint(0)
 	if optLoopState := state.ctx.currentLoop(); optLoopState.IsSome() {
 		loopState := optLoopState.Unwrap()
@@ -912,9 +957,7 @@ func getOrLookupLocal[T any](vec []option.Option[T], localID uint8, f func() opt
 }
 
 func assertValid(v Value, pc uint, st *State) (Value, error) {
-	if vInvalid, ok := v.data.(invalidValue); ok {
-		detail := vInvalid.Detail
-		err := NewError(BadSerialization, detail)
+	if err := invalidValueErr(v); err != nil {
 		processErr(err, pc, st)
 		return Value{}, err
 	}
@@ -0,0 +1,687 @@
+package mjingo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// yamlParser parses a YAML document in block and flow style using the
+// YAML 1.2 core schema.  Anchors, aliases, tags, complex keys and
+// multiple documents are not supported.
+type yamlParser struct {
+	lines []string
+	pos   int
+}
+
+// errYAMLIncomplete is returned when a quoted scalar or a flow collection
+// continues on the next line.
+var errYAMLIncomplete = errors.New("unexpected end of line")
+
+var (
+	yamlIntRe   = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	yamlOctRe   = regexp.MustCompile(`^0o[0-7]+$`)
+	yamlHexRe   = regexp.MustCompile(`^0x[0-9a-fA-F]+$`)
+	yamlFloatRe = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+)
+
+// unmarshalYAML parses s as a single YAML document.
+func unmarshalYAML(s string) (Value, error) {
+	p := &yamlParser{lines: strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")}
+	val, err := p.parseDocument()
+	if err != nil {
+		return Value{}, NewError(InvalidOperation, "cannot parse YAML").withSource(err)
+	}
+	return val, nil
+}
+
+func (p *yamlParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", p.pos+1, fmt.Sprintf(format, args...))
+}
+
+func (p *yamlParser) skipBlankLines() {
+	for p.pos < len(p.lines) {
+		t := strings.TrimSpace(p.lines[p.pos])
+		if t != "" && t[0] != '#' {
+			return
+		}
+		p.pos++
+	}
+}
+
+func isYAMLDocumentMarker(line string) bool {
+	return line == "---" || line == "..." || strings.HasPrefix(line, "--- ")
+}
+
+func (p *yamlParser) parseDocument() (Value, error) {
+	p.skipBlankLines()
+	if p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		switch {
+		case strings.HasPrefix(line, "%"):
+			return Value{}, p.errorf("directives are not supported")
+		case line == "---":
+			p.pos++
+		case strings.HasPrefix(line, "--- "):
+			p.lines[p.pos] = strings.TrimLeft(line[4:], " ")
+		}
+	}
+	val, err := p.parseNode(-1)
+	if err != nil {
+		return Value{}, err
+	}
+	p.skipBlankLines()
+	if p.pos < len(p.lines) && p.lines[p.pos] == "..." {
+		p.pos++
+		p.skipBlankLines()
+	}
+	if p.pos < len(p.lines) {
+		if isYAMLDocumentMarker(p.lines[p.pos]) {
+			return Value{}, p.errorf("multiple documents are not supported")
+		}
+		return Value{}, p.errorf("unexpected content")
+	}
+	return val, nil
+}
+
+// nextContent skips blank and comment lines and returns the indentation
+// of the next content line.  It returns false at the end of the document.
+func (p *yamlParser) nextContent() (int, bool, error) {
+	p.skipBlankLines()
+	if p.pos >= len(p.lines) || isYAMLDocumentMarker(p.lines[p.pos]) {
+		return 0, false, nil
+	}
+	line := p.lines[p.pos]
+	t := strings.TrimLeft(line, " ")
+	if t[0] == '\t' {
+		return 0, false, p.errorf("tabs are not allowed for indentation")
+	}
+	return len(line) - len(t), true, nil
+}
+
+// parseNode parses the node starting at the next content line which must
+// be indented more than parent.  A missing node is none.
+func (p *yamlParser) parseNode(parent int) (Value, error) {
+	indent, ok, err := p.nextContent()
+	if err != nil || !ok || indent <= parent {
+		return none, err
+	}
+	text := stripYAMLComment(p.lines[p.pos][indent:])
+	if isYAMLSeqItem(text) {
+		return p.parseSeq(indent)
+	}
+	if _, _, isKey, err := splitYAMLKey(text); err != nil {
+		return Value{}, p.errorf("%s", err)
+	} else if isKey {
+		return p.parseMap(indent)
+	}
+	return p.parseInlineValue(text, parent)
+}
+
+func isYAMLSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+func (p *yamlParser) parseMap(indent int) (Value, error) {
+	m := newValueMap()
+	for {
+		ind, ok, err := p.nextContent()
+		if err != nil {
+			return Value{}, err
+		}
+		if !ok || ind < indent {
+			break
+		}
+		if ind > indent {
+			return Value{}, p.errorf("unexpected indentation")
+		}
+		text := stripYAMLComment(p.lines[p.pos][ind:])
+		key, rest, isKey, err := splitYAMLKey(text)
+		if err != nil {
+			return Value{}, p.errorf("%s", err)
+		}
+		if !isKey {
+			return Value{}, p.errorf("expected a mapping key")
+		}
+
+		var val Value
+		if rest == "" {
+			p.pos++
+			next, ok, err := p.nextContent()
+			if err != nil {
+				return Value{}, err
+			}
+			if ok && next == indent && isYAMLSeqItem(stripYAMLComment(p.lines[p.pos][next:])) {
+				val, err = p.parseSeq(indent)
+			} else {
+				val, err = p.parseNode(indent)
+			}
+			if err != nil {
+				return Value{}, err
+			}
+		} else if val, err = p.parseInlineValue(rest, indent); err != nil {
+			return Value{}, err
+		}
+		if _, isStr := key.data.(stringValue); isStr {
+			m.Set(keyRefFromString(key.String()), val)
+		} else {
+			m.Set(keyRefFromValue(key), val)
+		}
+	}
+	return valueFromIndexMap(m), nil
+}
+
+func (p *yamlParser) parseSeq(indent int) (Value, error) {
+	var items []Value
+	for {
+		ind, ok, err := p.nextContent()
+		if err != nil {
+			return Value{}, err
+		}
+		if !ok || ind < indent {
+			break
+		}
+		if ind > indent {
+			return Value{}, p.errorf("unexpected indentation")
+		}
+		line := p.lines[p.pos]
+		if !isYAMLSeqItem(stripYAMLComment(line[ind:])) {
+			break
+		}
+
+		raw := line[ind+1:]
+		rest := strings.TrimLeft(raw, " ")
+		text := stripYAMLComment(rest)
+		var item Value
+		switch {
+		case text == "":
+			p.pos++
+			item, err = p.parseNode(indent)
+		case text[0] == '|' || text[0] == '>':
+			item, err = p.parseInlineValue(text, indent)
+		default:
+			_, _, isKey, keyErr := splitYAMLKey(text)
+			if keyErr != nil {
+				return Value{}, p.errorf("%s", keyErr)
+			}
+			if isKey || isYAMLSeqItem(text) {
+				// A compact nested collection like `- a: 1` continues at
+				// the column of its first entry.
+				p.lines[p.pos] = strings.Repeat(" ", len(line)-len(rest)) + rest
+				item, err = p.parseNode(indent)
+			} else {
+				item, err = p.parseInlineValue(text, indent)
+			}
+		}
+		if err != nil {
+			return Value{}, err
+		}
+		items = append(items, item)
+	}
+	return valueFromSlice(items), nil
+}
+
+// parseInlineValue parses text which is the comment stripped rest of the
+// current line as a scalar, a flow collection or the header of a block
+// scalar.  Continuation lines must be indented more than parent.
+func (p *yamlParser) parseInlineValue(text string, parent int) (Value, error) {
+	switch text[0] {
+	case '|', '>':
+		p.pos++
+		return p.parseBlockScalar(text, parent)
+	case '&', '*', '!':
+		return Value{}, p.errorf("anchors, aliases and tags are not supported")
+	case '%', '@', '`':
+		return Value{}, p.errorf("plain scalar cannot start with %q", text[0])
+	case '"', '\'', '[', '{':
+		for {
+			var val Value
+			var n int
+			var err error
+			if text[0] == '"' || text[0] == '\'' {
+				var s string
+				s, n, err = parseYAMLQuoted(text)
+				val = valueFromString(s)
+			} else {
+				fp := yamlFlowParser{s: text}
+				val, err = fp.parseValue()
+				n = fp.i
+			}
+			if err == errYAMLIncomplete && p.pos+1 < len(p.lines) {
+				p.pos++
+				next := strings.TrimSpace(p.lines[p.pos])
+				if text[0] == '[' || text[0] == '{' {
+					next = stripYAMLComment(next)
+				}
+				text += " " + next
+				continue
+			}
+			if err != nil {
+				return Value{}, p.errorf("%s", err)
+			}
+			if rest := strings.TrimSpace(text[n:]); rest != "" {
+				return Value{}, p.errorf("unexpected %q after value", rest)
+			}
+			p.pos++
+			return val, nil
+		}
+	}
+
+	// A plain scalar which can continue on more indented lines.
+	if isYAMLMappingValue(text) {
+		return Value{}, p.errorf("mapping values are not allowed here")
+	}
+	p.pos++
+	var b strings.Builder
+	b.WriteString(text)
+	for {
+		j, blanks := p.pos, 0
+		for j < len(p.lines) && strings.TrimSpace(p.lines[j]) == "" {
+			j++
+			blanks++
+		}
+		if j >= len(p.lines) || isYAMLDocumentMarker(p.lines[j]) {
+			break
+		}
+		line := p.lines[j]
+		t := strings.TrimSpace(line)
+		if len(line)-len(strings.TrimLeft(line, " ")) <= parent || t[0] == '#' {
+			break
+		}
+		t = stripYAMLComment(t)
+		if isYAMLMappingValue(t) {
+			p.pos = j
+			return Value{}, p.errorf("mapping values are not allowed here")
+		}
+		if blanks > 0 {
+			b.WriteString(strings.Repeat("\n", blanks))
+		} else {
+			b.WriteByte(' ')
+		}
+		b.WriteString(t)
+		p.pos = j + 1
+	}
+	return resolveYAMLPlainScalar(b.String()), nil
+}
+
+// parseBlockScalar parses the lines of a literal or folded block scalar
+// with the given header which are indented more than parent.
+func (p *yamlParser) parseBlockScalar(header string, parent int) (Value, error) {
+	folded := header[0] == '>'
+	chomp := byte(0)
+	contentIndent := -1
+	for _, c := range []byte(header[1:]) {
+		switch {
+		case (c == '+' || c == '-') && chomp == 0:
+			chomp = c
+		case '1' <= c && c <= '9' && contentIndent == -1:
+			contentIndent = max(parent, 0) + int(c-'0')
+		default:
+			p.pos--
+			return Value{}, p.errorf("invalid block scalar header %q", header)
+		}
+	}
+
+	var lines []string
+	for ; p.pos < len(p.lines); p.pos++ {
+		line := p.lines[p.pos]
+		if strings.TrimSpace(line) == "" {
+			lines = append(lines, "")
+			continue
+		}
+		ind := len(line) - len(strings.TrimLeft(line, " "))
+		if contentIndent == -1 {
+			if ind <= parent {
+				break
+			}
+			contentIndent = ind
+		}
+		if ind < contentIndent {
+			break
+		}
+		lines = append(lines, line[contentIndent:])
+	}
+	trailing := 0
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+		trailing++
+	}
+
+	var b strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			prev := lines[i-1]
+			switch {
+			case !folded, line == "":
+				b.WriteByte('\n')
+			case prev == "":
+				// The line break before empty lines is folded away.
+			case line[0] == ' ' || prev[0] == ' ':
+				// Line breaks around more indented lines are kept.
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(line)
+	}
+	switch {
+	case chomp == '-':
+	case chomp == '+':
+		if len(lines) > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(strings.Repeat("\n", trailing))
+	case len(lines) > 0:
+		b.WriteByte('\n')
+	}
+	return valueFromString(b.String()), nil
+}
+
+// isYAMLMappingValue reports whether plain scalar text contains a `:`
+// indicator, which is only allowed after a mapping key.
+func isYAMLMappingValue(text string) bool {
+	return strings.Contains(text, ": ") || strings.HasSuffix(text, ":")
+}
+
+// splitYAMLKey splits text like `key: value` into the key and the value
+// text.  It returns false if text does not start with a mapping key.
+func splitYAMLKey(text string) (Value, string, bool, error) {
+	if text == "?" || strings.HasPrefix(text, "? ") {
+		return Value{}, "", false, errors.New("complex keys are not supported")
+	}
+	isValueStart := func(rest string) bool {
+		return rest == ":" || strings.HasPrefix(rest, ": ")
+	}
+	switch text[0] {
+	case '"', '\'':
+		key, n, err := parseYAMLQuoted(text)
+		if err != nil || !isValueStart(text[n:]) {
+			return Value{}, "", false, nil
+		}
+		return valueFromString(key), strings.TrimSpace(text[n+1:]), true, nil
+	case '[', '{':
+		return Value{}, "", false, nil
+	}
+	i := strings.Index(text, ": ")
+	if i == -1 {
+		if !strings.HasSuffix(text, ":") {
+			return Value{}, "", false, nil
+		}
+		i = len(text) - 1
+	}
+	key := strings.TrimRight(text[:i], " ")
+	if key == "" {
+		return Value{}, "", false, nil
+	}
+	if strings.ContainsRune("&*!", rune(key[0])) {
+		return Value{}, "", false, errors.New("anchors, aliases and tags are not supported")
+	}
+	return resolveYAMLPlainScalar(key), strings.TrimSpace(text[i+1:]), true, nil
+}
+
+// stripYAMLComment removes a trailing comment and whitespace from s.
+func stripYAMLComment(s string) string {
+	var quote byte
+	prev := byte(' ')      // previous byte
+	prevToken := byte(':') // previous non-space byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			if quote == '\'' && i+1 < len(s) && s[i+1] == '\'' {
+				i++
+			} else {
+				quote = 0
+			}
+		case quote != 0:
+		case (c == '"' || c == '\'') && strings.IndexByte(":-,[{?", prevToken) != -1 && (prev == ' ' || prev == prevToken):
+			quote = c
+		case c == '#' && (prev == ' ' || prev == '\t'):
+			return strings.TrimRight(s[:i], " \t")
+		}
+		prev = c
+		if c != ' ' && c != '\t' {
+			prevToken = c
+		}
+	}
+	return strings.TrimRight(s, " \t")
+}
+
+// parseYAMLQuoted parses the quoted scalar at the start of s and returns
+// it with the number of bytes consumed.
+func parseYAMLQuoted(s string) (string, int, error) {
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == quote:
+			if quote == '\'' && i+1 < len(s) && s[i+1] == '\'' {
+				b.WriteByte('\'')
+				i++
+				continue
+			}
+			return b.String(), i + 1, nil
+		case c == '\\' && quote == '"':
+			i++
+			if i >= len(s) {
+				return "", 0, errYAMLIncomplete
+			}
+			switch e := s[i]; e {
+			case '0':
+				b.WriteByte(0)
+			case 'a':
+				b.WriteByte('\a')
+			case 'b':
+				b.WriteByte('\b')
+			case 't', '\t':
+				b.WriteByte('\t')
+			case 'n':
+				b.WriteByte('\n')
+			case 'v':
+				b.WriteByte('\v')
+			case 'f':
+				b.WriteByte('\f')
+			case 'r':
+				b.WriteByte('\r')
+			case 'e':
+				b.WriteByte(0x1b)
+			case ' ', '"', '/', '\\':
+				b.WriteByte(e)
+			case 'N':
+				b.WriteRune('\u0085')
+			case '_':
+				b.WriteRune('\u00a0')
+			case 'L':
+				b.WriteRune('\u2028')
+			case 'P':
+				b.WriteRune('\u2029')
+			case 'x', 'u', 'U':
+				n := map[byte]int{'x': 2, 'u': 4, 'U': 8}[e]
+				if i+n >= len(s) {
+					return "", 0, fmt.Errorf("invalid escape sequence \\%c", e)
+				}
+				r, err := strconv.ParseUint(s[i+1:i+1+n], 16, 32)
+				if err != nil {
+					return "", 0, fmt.Errorf("invalid escape sequence \\%s", s[i:i+1+n])
+				}
+				b.WriteRune(rune(r))
+				i += n
+			default:
+				return "", 0, fmt.Errorf("invalid escape sequence \\%c", e)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, errYAMLIncomplete
+}
+
+// resolveYAMLPlainScalar converts a plain scalar to a value with the tag
+// resolution of the YAML 1.2 core schema.
+func resolveYAMLPlainScalar(s string) Value {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return none
+	case "true", "True", "TRUE":
+		return valueFromBool(true)
+	case "false", "False", "FALSE":
+		return valueFromBool(false)
+	case ".inf", ".Inf", ".INF", "+.inf", "+.Inf", "+.INF":
+		return valueFromF64(math.Inf(1))
+	case "-.inf", "-.Inf", "-.INF":
+		return valueFromF64(math.Inf(-1))
+	case ".nan", ".NaN", ".NAN":
+		return valueFromF64(math.NaN())
+	}
+	switch {
+	case yamlIntRe.MatchString(s):
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return valueFromI64(n)
+		}
+		if n, err := strconv.ParseUint(strings.TrimPrefix(s, "+"), 10, 64); err == nil {
+			return valueFromU64(n)
+		}
+	case yamlOctRe.MatchString(s):
+		if n, err := strconv.ParseInt(s[2:], 8, 64); err == nil {
+			return valueFromI64(n)
+		}
+	case yamlHexRe.MatchString(s):
+		if n, err := strconv.ParseInt(s[2:], 16, 64); err == nil {
+			return valueFromI64(n)
+		}
+	}
+	if yamlFloatRe.MatchString(s) || yamlIntRe.MatchString(s) {
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return valueFromF64(f)
+		}
+	}
+	return valueFromString(s)
+}
+
+// yamlFlowParser parses flow collections like `[a, {b: c}]`.
+type yamlFlowParser struct {
+	s string
+	i int
+}
+
+func (p *yamlFlowParser) skipSpaces() {
+	for p.i < len(p.s) && p.s[p.i] == ' ' {
+		p.i++
+	}
+}
+
+func (p *yamlFlowParser) parseValue() (Value, error) {
+	p.skipSpaces()
+	if p.i >= len(p.s) {
+		return Value{}, errYAMLIncomplete
+	}
+	switch p.s[p.i] {
+	case '[':
+		p.i++
+		var items []Value
+		for {
+			p.skipSpaces()
+			if p.i >= len(p.s) {
+				return Value{}, errYAMLIncomplete
+			}
+			if p.s[p.i] == ']' {
+				p.i++
+				return valueFromSlice(items), nil
+			}
+			item, err := p.parseValue()
+			if err != nil {
+				return Value{}, err
+			}
+			items = append(items, item)
+			if err := p.parseSeparator(']'); err != nil {
+				return Value{}, err
+			}
+		}
+	case '{':
+		p.i++
+		m := newValueMap()
+		for {
+			p.skipSpaces()
+			if p.i >= len(p.s) {
+				return Value{}, errYAMLIncomplete
+			}
+			if p.s[p.i] == '}' {
+				p.i++
+				return valueFromIndexMap(m), nil
+			}
+			key, err := p.parseScalar(true)
+			if err != nil {
+				return Value{}, err
+			}
+			p.skipSpaces()
+			val := none
+			if p.i < len(p.s) && p.s[p.i] == ':' {
+				p.i++
+				p.skipSpaces()
+				if p.i < len(p.s) && p.s[p.i] != ',' && p.s[p.i] != '}' {
+					if val, err = p.parseValue(); err != nil {
+						return Value{}, err
+					}
+				}
+			}
+			if _, isStr := key.data.(stringValue); isStr {
+				m.Set(keyRefFromString(key.String()), val)
+			} else {
+				m.Set(keyRefFromValue(key), val)
+			}
+			if err := p.parseSeparator('}'); err != nil {
+				return Value{}, err
+			}
+		}
+	}
+	return p.parseScalar(false)
+}
+
+// parseSeparator consumes a comma or peeks the closing bracket.
+func (p *yamlFlowParser) parseSeparator(closing byte) error {
+	p.skipSpaces()
+	switch {
+	case p.i >= len(p.s):
+		return errYAMLIncomplete
+	case p.s[p.i] == ',':
+		p.i++
+		return nil
+	case p.s[p.i] == closing:
+		return nil
+	}
+	return fmt.Errorf("expected ',' or '%c' in flow collection", closing)
+}
+
+// parseScalar parses a quoted or plain scalar in a flow collection.  A
+// plain key ends before a `:` followed by a space or a flow indicator.
+func (p *yamlFlowParser) parseScalar(isKey bool) (Value, error) {
+	if c := p.s[p.i]; c == '"' || c == '\'' {
+		s, n, err := parseYAMLQuoted(p.s[p.i:])
+		if err != nil {
+			return Value{}, err
+		}
+		p.i += n
+		return valueFromString(s), nil
+	}
+	if strings.IndexByte("&*!", p.s[p.i]) != -1 {
+		return Value{}, errors.New("anchors, aliases and tags are not supported")
+	}
+	start := p.i
+	for ; p.i < len(p.s); p.i++ {
+		c := p.s[p.i]
+		if c == ',' || c == ']' || c == '}' || c == '[' || c == '{' {
+			break
+		}
+		if isKey && c == ':' && (p.i+1 == len(p.s) || strings.IndexByte(" ,]}", p.s[p.i+1]) != -1) {
+			break
+		}
+	}
+	return resolveYAMLPlainScalar(strings.TrimSpace(p.s[start:p.i])), nil
+}
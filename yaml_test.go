@@ -0,0 +1,62 @@
+package mjingo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestUnmarshalYAML(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "scalars", input: "a: 1\nb: -2.5e3\nc: ~\nd: True\ne: 0x1F\nf: 0o17\ng: 1.2.3\nh: .inf\n", want: `{"a": 1, "b": -2500.0, "c": none, "d": true, "e": 31, "f": 15, "g": "1.2.3", "h": inf}`},
+		{name: "quoted", input: `a: "x\ty\u00e9 # no comment"` + "\nb: 'it''s' # comment\n'c d': \"\"", want: `{"a": "x\tyé # no comment", "b": "it's", "c d": ""}`},
+		{name: "nested", input: "a:\n  b:\n    - 1\n    - c: 2\n      d: 3\n  e: x\nf:\n- y\n- - z\n", want: `{"a": {"b": [1, {"c": 2, "d": 3}], "e": "x"}, "f": ["y", ["z"]]}`},
+		{name: "flow", input: "a: [1, 'x, y', {b: c, d: [e]}, ]\nb: {x: 1,\n  y: 2}\n", want: `{"a": [1, "x, y", {"b": "c", "d": ["e"]}], "b": {"x": 1, "y": 2}}`},
+		{name: "literal", input: "a: |\n  x\n   y\n\n  z\n\nb: 1\n", want: `{"a": "x\n y\n\nz\n", "b": 1}`},
+		{name: "literalChomping", input: "a: |-\n  x\nb: |+\n  y\n\nc: >\n  p\n  q\n\n  r\n", want: `{"a": "x", "b": "y\n\n", "c": "p q\nr\n"}`},
+		{name: "seqBlockScalar", input: "- |\n  x\n- >-\n  y\n  z\n", want: `["x\n", "y z"]`},
+		{name: "plainMultiLine", input: "a: this is\n  continued\nb: 2\n", want: `{"a": "this is continued", "b": 2}`},
+		{name: "document", input: "# comment\n---\n- a # c\n...\n", want: `["a"]`},
+		{name: "topScalar", input: "hello", want: `"hello"`},
+		{name: "empty", input: "", want: "none"},
+		{name: "nullValues", input: "a:\nb: 1\n", want: `{"a": none, "b": 1}`},
+		{name: "url", input: "a: http://example.com:8080/x#y\n", want: `{"a": "http://example.com:8080/x#y"}`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := unmarshalYAML(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.DebugString() != tc.want {
+				t.Errorf("result mismatch,\n got=%s,\nwant=%s", got.DebugString(), tc.want)
+			}
+		})
+	}
+
+	if got, err := unmarshalYAML("a: .nan"); err != nil || !math.IsNaN(got.getAttrFast("a").Unwrap().asF64().Unwrap()) {
+		t.Errorf("expected NaN, got=%v, err=%v", got, err)
+	}
+
+	for _, input := range []string{
+		"a: &x 1",
+		"a: *x",
+		"a: !!str 1",
+		"? a\n: b",
+		"a: 1\n---\nb: 2",
+		"a:\n\t- 1",
+		"a: [1, 2",
+		`a: "x`,
+		"a: 1\n b: 2",
+		"- a\nb: 1",
+		`a: "\q"`,
+		"%YAML 1.2\n---\na: 1",
+	} {
+		if _, err := unmarshalYAML(input); err == nil {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}